@@ -0,0 +1,289 @@
+package calendar
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+)
+
+// B3 moved the stock market settlement cycle from D+3 to D+2 on 2019-05-27
+var settlementD2Start = utils.NewB3Date(2019, time.May, 27)
+
+type B3Calendar struct {
+	closures map[string]string
+	openings map[string]string
+	years    map[int]bool
+}
+
+// GetB3Calendar returns the built-in calendar extended by the file set on
+// B3_CALENDAR_FILE, if any.
+func GetB3Calendar() (*B3Calendar, error) {
+	cal := &B3Calendar{
+		closures: make(map[string]string),
+		openings: make(map[string]string),
+		years:    make(map[int]bool),
+	}
+
+	fileName, ok := os.LookupEnv("B3_CALENDAR_FILE")
+
+	if !ok || fileName == "" {
+		return cal, nil
+	}
+
+	if err := cal.LoadFile(fileName); err != nil {
+		return nil, err
+	}
+
+	return cal, nil
+}
+
+// easterSunday uses the anonymous gregorian algorithm
+func easterSunday(year int) utils.B3Date {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := ((h + l - 7*m + 114) % 31) + 1
+
+	return utils.NewB3Date(year, time.Month(month), day)
+}
+
+func (cal *B3Calendar) addClosure(date utils.B3Date, description string) {
+	key := date.String()
+
+	if _, ok := cal.closures[key]; !ok {
+		cal.closures[key] = description
+	}
+}
+
+// loadYear adds national holidays, Sao Paulo holidays (until 2021) and B3
+// closures of the given year.
+func (cal *B3Calendar) loadYear(year int) {
+	if cal.years[year] {
+		return
+	}
+
+	cal.years[year] = true
+
+	fixed := []struct {
+		month       time.Month
+		day         int
+		description string
+	}{
+		{time.January, 1, "Confraternizacao Universal"},
+		{time.April, 21, "Tiradentes"},
+		{time.May, 1, "Dia do Trabalho"},
+		{time.September, 7, "Independencia"},
+		{time.October, 12, "Nossa Senhora Aparecida"},
+		{time.November, 2, "Finados"},
+		{time.November, 15, "Proclamacao da Republica"},
+		{time.December, 24, "Vespera de Natal (B3)"},
+		{time.December, 25, "Natal"},
+		{time.December, 31, "Ultimo dia util do ano (B3)"},
+	}
+
+	for _, holiday := range fixed {
+		cal.addClosure(utils.NewB3Date(year, holiday.month, holiday.day), holiday.description)
+	}
+
+	// B3 observed the Sao Paulo holidays until 2021, exceptions (such as the
+	// 2020 holidays moved by the city) go on B3_CALENDAR_FILE as OPEN
+	if year <= 2021 {
+		cal.addClosure(utils.NewB3Date(year, time.January, 25), "Aniversario de Sao Paulo")
+		cal.addClosure(utils.NewB3Date(year, time.July, 9), "Revolucao Constitucionalista")
+
+		if year >= 2004 {
+			cal.addClosure(utils.NewB3Date(year, time.November, 20), "Dia da Consciencia Negra (Sao Paulo)")
+		}
+	}
+
+	if year >= 2024 {
+		cal.addClosure(utils.NewB3Date(year, time.November, 20), "Dia Nacional de Zumbi e da Consciencia Negra")
+	}
+
+	easter := easterSunday(year)
+	cal.addClosure(easter.AddDays(-48), "Carnaval")
+	cal.addClosure(easter.AddDays(-47), "Carnaval")
+	cal.addClosure(easter.AddDays(-2), "Paixao de Cristo")
+	cal.addClosure(easter.AddDays(60), "Corpus Christi")
+}
+
+// LoadFile reads a csv with `date,type,description` lines, type is one of
+// HOLIDAY, CLOSED or OPEN (OPEN overrides a built-in closure).
+func (cal *B3Calendar) LoadFile(fileName string) error {
+	calendarFile, err := os.Open(fileName)
+
+	if err != nil {
+		log.Printf("calendar.B3Calendar.LoadFile: error opening file: %s", fileName)
+		return err
+	}
+
+	defer calendarFile.Close()
+
+	err = cal.Load(calendarFile)
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("calendar.B3Calendar.LoadFile: success loading: %s", fileName)
+
+	return nil
+}
+
+func (cal *B3Calendar) Load(source io.Reader) error {
+	reader := csv.NewReader(source)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+
+	if err != nil {
+		return err
+	}
+
+	for lineNum, record := range records {
+		if len(record) < 2 {
+			details := fmt.Sprintf("line %d: expected date,type[,description]", lineNum+1)
+			return utils.GetError("calendar.B3Calendar.Load", "ERR_SYS_001", details)
+		}
+
+		date, err := utils.ParseB3Date(strings.TrimSpace(record[0]))
+
+		if err != nil {
+			return err
+		}
+
+		description := ""
+
+		if len(record) > 2 {
+			description = strings.TrimSpace(record[2])
+		}
+
+		entryTypes := constants.CalendarEntryTypes
+
+		switch strings.ToUpper(strings.TrimSpace(record[1])) {
+		case entryTypes.HOLIDAY, entryTypes.CLOSED:
+			cal.closures[date.String()] = description
+			delete(cal.openings, date.String())
+		case entryTypes.OPEN:
+			cal.openings[date.String()] = description
+		default:
+			details := fmt.Sprintf("line %d: unknown entry type %s", lineNum+1, record[1])
+			return utils.GetError("calendar.B3Calendar.Load", "ERR_SYS_001", details)
+		}
+	}
+
+	return nil
+}
+
+func (cal *B3Calendar) IsTradingDay(date utils.B3Date) bool {
+	weekday := date.Weekday()
+
+	if weekday == time.Saturday || weekday == time.Sunday {
+		return false
+	}
+
+	key := date.String()
+
+	if _, ok := cal.openings[key]; ok {
+		return true
+	}
+
+	cal.loadYear(date.Year())
+
+	_, closed := cal.closures[key]
+
+	return !closed
+}
+
+// GetClosure returns the reason the market is closed on date, empty when open.
+func (cal *B3Calendar) GetClosure(date utils.B3Date) string {
+	if cal.IsTradingDay(date) {
+		return ""
+	}
+
+	if description, ok := cal.closures[date.String()]; ok {
+		return description
+	}
+
+	return date.Weekday().String()
+}
+
+func (cal *B3Calendar) NextTradingDay(date utils.B3Date) utils.B3Date {
+	next := date.AddDays(1)
+
+	for !cal.IsTradingDay(next) {
+		next = next.AddDays(1)
+	}
+
+	return next
+}
+
+func (cal *B3Calendar) PreviousTradingDay(date utils.B3Date) utils.B3Date {
+	prev := date.AddDays(-1)
+
+	for !cal.IsTradingDay(prev) {
+		prev = prev.AddDays(-1)
+	}
+
+	return prev
+}
+
+func (cal *B3Calendar) AddTradingDays(date utils.B3Date, days int) utils.B3Date {
+	res := date
+
+	for i := 0; i < days; i++ {
+		res = cal.NextTradingDay(res)
+	}
+
+	return res
+}
+
+// GetSettlementDays returns the settlement cycle of a market, option premiums
+// settle on D+1 and the other markets on D+2 (D+3 before 2019-05-27).
+func (cal *B3Calendar) GetSettlementDays(marketDate utils.B3Date, market string) int {
+	if market == constants.Markets.CALL || market == constants.Markets.PUT {
+		return 1
+	}
+
+	if marketDate.Before(settlementD2Start) {
+		return 3
+	}
+
+	return 2
+}
+
+// GetSettlementDate returns the settlement date of a trade on market.
+func (cal *B3Calendar) GetSettlementDate(marketDate utils.B3Date, market string) utils.B3Date {
+	return cal.AddTradingDays(marketDate, cal.GetSettlementDays(marketDate, market))
+}
+
+// GetDarfDueDate returns the due date of the DARF for results of the month of
+// date, the last business day of the following month.
+func (cal *B3Calendar) GetDarfDueDate(date utils.B3Date) utils.B3Date {
+	dueDate := date.FirstDayOfMonth().AddMonths(1).LastDayOfMonth()
+
+	for !cal.IsTradingDay(dueDate) {
+		dueDate = dueDate.AddDays(-1)
+	}
+
+	return dueDate
+}
@@ -0,0 +1,15 @@
+package constants
+
+type BrokerageModelsEnum struct {
+	FLAT_ORDER  string
+	PER_COMPANY string
+	TIERED      string
+	ZERO        string
+}
+
+var BrokerageModels = BrokerageModelsEnum{
+	FLAT_ORDER:  "FLAT_ORDER",
+	PER_COMPANY: "PER_COMPANY",
+	TIERED:      "TIERED",
+	ZERO:        "ZERO",
+}
@@ -0,0 +1,13 @@
+package constants
+
+type CalendarEntryTypesEnum struct {
+	HOLIDAY string
+	CLOSED  string
+	OPEN    string
+}
+
+var CalendarEntryTypes = CalendarEntryTypesEnum{
+	HOLIDAY: "HOLIDAY",
+	CLOSED:  "CLOSED",
+	OPEN:    "OPEN",
+}
@@ -0,0 +1,19 @@
+package constants
+
+type CorporateEventTypesEnum struct {
+	INCORPORATION string
+	SPIN_OFF      string
+	RIGHTS_GRANT  string
+	SUBSCRIPTION  string
+	UNIT_SPLIT    string
+	UNIT_MERGE    string
+}
+
+var CorporateEventTypes = CorporateEventTypesEnum{
+	INCORPORATION: "INCORPORATION",
+	SPIN_OFF:      "SPIN_OFF",
+	RIGHTS_GRANT:  "RIGHTS_GRANT",
+	SUBSCRIPTION:  "SUBSCRIPTION",
+	UNIT_SPLIT:    "UNIT_SPLIT",
+	UNIT_MERGE:    "UNIT_MERGE",
+}
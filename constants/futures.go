@@ -0,0 +1,19 @@
+package constants
+
+type FuturesContractsEnum struct {
+	WIN string
+	WDO string
+}
+
+// FuturesContracts BM&F mini contracts, the ticker adds the maturity month
+// letter and year (WINZ24, WDOF25).
+var FuturesContracts = FuturesContractsEnum{
+	WIN: "WIN",
+	WDO: "WDO",
+}
+
+// FuturesMultipliers value of one point of each contract in reais.
+var FuturesMultipliers = map[string]float64{
+	FuturesContracts.WIN: 0.20,
+	FuturesContracts.WDO: 10.0,
+}
@@ -0,0 +1,17 @@
+package constants
+
+type IngestionStatusesEnum struct {
+	PROCESSED     string
+	SKIPPED       string
+	FAILED        string
+	NOT_PROCESSED string
+}
+
+// IngestionStatuses outcome of each invoice of a batch run, NOT_PROCESSED
+// invoices come after a failed one.
+var IngestionStatuses = IngestionStatusesEnum{
+	PROCESSED:     "PROCESSED",
+	SKIPPED:       "SKIPPED",
+	FAILED:        "FAILED",
+	NOT_PROCESSED: "NOT_PROCESSED",
+}
@@ -0,0 +1,23 @@
+package constants
+
+type IrpfAssetCode struct {
+	Group       string
+	Code        string
+	Description string
+}
+
+type IrpfAssetCodesEnum struct {
+	SHR IrpfAssetCode
+	BDR IrpfAssetCode
+	ETF IrpfAssetCode
+	FII IrpfAssetCode
+}
+
+// Bens e Direitos group/code pairs by asset type, with the name of the
+// asset on the item description
+var IrpfAssetCodes = IrpfAssetCodesEnum{
+	SHR: IrpfAssetCode{Group: "03", Code: "01", Description: "ACOES"},
+	BDR: IrpfAssetCode{Group: "04", Code: "04", Description: "BDR"},
+	ETF: IrpfAssetCode{Group: "07", Code: "09", Description: "COTAS DE ETF"},
+	FII: IrpfAssetCode{Group: "07", Code: "03", Description: "COTAS DE FII"},
+}
@@ -0,0 +1,12 @@
+package constants
+
+type LendingSidesEnum struct {
+	LENDER   string
+	BORROWER string
+}
+
+// LendingSides sides of a stock lending (BTC) contract.
+var LendingSides = LendingSidesEnum{
+	LENDER:   "LENDER",
+	BORROWER: "BORROWER",
+}
@@ -0,0 +1,31 @@
+package constants
+
+type OptionTypesEnum struct {
+	CALL string
+	PUT  string
+}
+
+var OptionTypes = OptionTypesEnum{
+	CALL: "CALL",
+	PUT:  "PUT",
+}
+
+// MarketsEnum market names printed on SINACOR notes, an empty item market
+// means the invoice market.
+type MarketsEnum struct {
+	SPOT          string
+	CALL          string
+	PUT           string
+	CALL_EXERCISE string
+	PUT_EXERCISE  string
+	TERMO         string
+}
+
+var Markets = MarketsEnum{
+	SPOT:          "VISTA",
+	CALL:          "OPCAO DE COMPRA",
+	PUT:           "OPCAO DE VENDA",
+	CALL_EXERCISE: "EXERC OPC COMPRA",
+	PUT_EXERCISE:  "EXERC OPC VENDA",
+	TERMO:         "TERMO",
+}
@@ -0,0 +1,15 @@
+package constants
+
+type ReconcileStatusesEnum struct {
+	MISSING_ON_DB string
+	MISSING_ON_B3 string
+	QTY_MISMATCH  string
+}
+
+// ReconcileStatuses kinds of discrepancy between B3's custody statement and
+// the stored positions.
+var ReconcileStatuses = ReconcileStatusesEnum{
+	MISSING_ON_DB: "MISSING_ON_DB",
+	MISSING_ON_B3: "MISSING_ON_B3",
+	QTY_MISMATCH:  "QTY_MISMATCH",
+}
@@ -0,0 +1,13 @@
+package constants
+
+type ReportFormatsEnum struct {
+	CSV     string
+	JSON    string
+	CONSOLE string
+}
+
+var ReportFormats = ReportFormatsEnum{
+	CSV:     "csv",
+	JSON:    "json",
+	CONSOLE: "console",
+}
@@ -1,11 +1,13 @@
 package constants
 
 type TaxSourcesEnum struct {
-	EARNING     string
-	INVOICE     string
-	ITEM_BATCH  string
-	TRADE_BATCH string
-	TRADE       string
+	EARNING       string
+	INVOICE       string
+	ITEM_BATCH    string
+	TRADE_BATCH   string
+	TRADE         string
+	FUTURES_NOTE  string
+	FUTURES_BATCH string
 }
 
 type TaxRatesEnum struct {
@@ -15,6 +17,8 @@ type TaxRatesEnum struct {
 	IRRFFEE       float64
 	IR_EXPT_LIMIT float64
 	IRFEE         float64
+	IRDTFEE       float64
+	IRFIIFEE      float64
 	BRKFEE        float64
 }
 
@@ -24,23 +28,43 @@ type TaxTypesEnum struct {
 	ISSSPFEE string
 	IRRFFEE  string
 	IRFEE    string
+	IRFIIFEE string
+	IRDTFEE  string
 	BRKFEE   string
+	REGFEE   string
+	BTCFEE   string
+}
+
+type RateCodesEnum struct {
+	SETFEE        string
+	EMLFEE        string
+	ISSSPFEE      string
+	IRRFFEE       string
+	IR_EXPT_LIMIT string
+	IRFEE         string
+	IRDTFEE       string
+	IRFIIFEE      string
+	BRKFEE        string
 }
 
 type TaxGroupPrefixEnum struct {
-	EARNING     string
-	INVOICE     string
-	ITEM_BATCH  string
-	TRADE       string
-	TRADE_BATCH string
+	EARNING       string
+	INVOICE       string
+	ITEM_BATCH    string
+	TRADE         string
+	TRADE_BATCH   string
+	FUTURES_NOTE  string
+	FUTURES_BATCH string
 }
 
 var TaxSources = TaxSourcesEnum{
-	EARNING:     "EAR",
-	INVOICE:     "BIV",
-	ITEM_BATCH:  "ITB",
-	TRADE_BATCH: "TDB",
-	TRADE:       "TRD",
+	EARNING:       "EAR",
+	INVOICE:       "BIV",
+	ITEM_BATCH:    "ITB",
+	TRADE_BATCH:   "TDB",
+	TRADE:         "TRD",
+	FUTURES_NOTE:  "BMF",
+	FUTURES_BATCH: "FTB",
 }
 
 var TaxTypes = TaxTypesEnum{
@@ -49,7 +73,11 @@ var TaxTypes = TaxTypesEnum{
 	ISSSPFEE: "ISSSPFEE",
 	IRRFFEE:  "IRRFFEE",
 	IRFEE:    "IRFEEE",
+	IRFIIFEE: "IRFIIFEE",
+	IRDTFEE:  "IRDTFEE",
 	BRKFEE:   "BRKFEE",
+	REGFEE:   "REGFEE",
+	BTCFEE:   "BTCFEE",
 }
 
 var TaxRates = TaxRatesEnum{
@@ -59,13 +87,30 @@ var TaxRates = TaxRatesEnum{
 	IRRFFEE:       0.00005,
 	IR_EXPT_LIMIT: 20000,
 	IRFEE:         0.15,
+	IRDTFEE:       0.20,
+	IRFIIFEE:      0.20,
 	BRKFEE:        4.9,
 }
 
+// keys of the rate table, tax rates share the code of their tax type
+var RateCodes = RateCodesEnum{
+	SETFEE:        TaxTypes.SETFEE,
+	EMLFEE:        TaxTypes.EMLFEE,
+	ISSSPFEE:      TaxTypes.ISSSPFEE,
+	IRRFFEE:       TaxTypes.IRRFFEE,
+	IR_EXPT_LIMIT: "IR_EXPT_LIMIT",
+	IRFEE:         TaxTypes.IRFEE,
+	IRDTFEE:       TaxTypes.IRDTFEE,
+	IRFIIFEE:      TaxTypes.IRFIIFEE,
+	BRKFEE:        TaxTypes.BRKFEE,
+}
+
 var TaxGroupPrefix = TaxGroupPrefixEnum{
-	EARNING:     "1",
-	INVOICE:     "2",
-	ITEM_BATCH:  "3",
-	TRADE:       "4",
-	TRADE_BATCH: "5",
+	EARNING:       "1",
+	INVOICE:       "2",
+	ITEM_BATCH:    "3",
+	TRADE:         "4",
+	TRADE_BATCH:   "5",
+	FUTURES_NOTE:  "6",
+	FUTURES_BATCH: "7",
 }
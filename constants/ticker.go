@@ -0,0 +1,29 @@
+package constants
+
+type AssetClassesEnum struct {
+	STOCK  string
+	BDR    string
+	ETF    string
+	FII    string
+	UNIT   string
+	OPTION string
+}
+
+var AssetClasses = AssetClassesEnum{
+	STOCK:  "STOCK",
+	BDR:    "BDR",
+	ETF:    "ETF",
+	FII:    "FII",
+	UNIT:   "UNIT",
+	OPTION: "OPTION",
+}
+
+type MarketSegmentsEnum struct {
+	STANDARD   string
+	FRACTIONAL string
+}
+
+var MarketSegments = MarketSegmentsEnum{
+	STANDARD:   "STANDARD",
+	FRACTIONAL: "FRACTIONAL",
+}
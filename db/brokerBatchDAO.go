@@ -0,0 +1,417 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type BrokerBatchDAO struct {
+	tx          *sql.Tx
+	brokerBatch *model.BrokerBatch
+}
+
+func GetBrokerBatchDAO(tx *sql.Tx, brokerBatch *model.BrokerBatch) *BrokerBatchDAO {
+	return &BrokerBatchDAO{
+		tx:          tx,
+		brokerBatch: brokerBatch,
+	}
+}
+
+// GetBrokerBatch the broker batch of the user, company and agent, the unique
+// key of the table; its company batch may be an older (closed) one of the
+// company, to be moved by the caller.
+func (dao *BrokerBatchDAO) GetBrokerBatch() (*model.BrokerBatch, error) {
+	query := `SELECT
+		cbb_id,
+		cbt_id,
+		cbb_start_date,
+		cbb_qty,
+		cbb_avg_price,
+		cbb_total_price
+	FROM company_broker_batch
+	WHERE usr_id = ?
+		AND cmp_id = ?
+		AND cbb_agent_id = ?`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	brokerBatch := dao.brokerBatch
+	var brokerBatchRec model.BrokerBatch
+	var companyBatchId int64
+
+	err = stmt.QueryRow(
+		brokerBatch.User.Id,
+		brokerBatch.Company.Id,
+		brokerBatch.AgentId,
+	).Scan(
+		&brokerBatchRec.Id,
+		&companyBatchId,
+		&brokerBatchRec.StartDate,
+		&brokerBatchRec.Qty,
+		&brokerBatchRec.AvgPrice,
+		&brokerBatchRec.TotalPrice,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	brokerBatchRec.CompanyBatch = brokerBatch.CompanyBatch
+	brokerBatchRec.User = brokerBatch.User
+	brokerBatchRec.Company = brokerBatch.Company
+	brokerBatchRec.AgentId = brokerBatch.AgentId
+
+	if companyBatchId != brokerBatch.CompanyBatch.Id {
+		brokerBatchRec.CompanyBatch = &entity.CompanyBatch{
+			Id:      companyBatchId,
+			User:    brokerBatch.User,
+			Company: brokerBatch.Company,
+		}
+	}
+
+	log.Printf(
+		"BrokerBatchDAO.GetBrokerBatch: found broker batch [%d, %s, %s, %d]",
+		brokerBatchRec.Id,
+		brokerBatchRec.Company.Code,
+		brokerBatchRec.AgentId,
+		brokerBatchRec.Qty,
+	)
+
+	return &brokerBatchRec, nil
+}
+
+func (dao *BrokerBatchDAO) GetBrokerBatchesByUser() ([]*model.BrokerBatch, error) {
+	query := `SELECT
+		cbb.cbb_id,
+		cbb.cbt_id,
+		cbb.cbb_agent_id,
+		cbb.cbb_start_date,
+		cbb.cbb_qty,
+		cbb.cbb_avg_price,
+		cbb.cbb_total_price,
+		cmp.cmp_id,
+		cmp.cmp_code,
+		cmp.cmp_name,
+		cmp.cmp_bdr,
+		cmp.cmp_etf
+	FROM company_broker_batch cbb
+	INNER JOIN company cmp ON cbb.cmp_id = cmp.cmp_id
+	WHERE cbb.usr_id = ?
+		AND cbb.cbb_qty <> 0
+	ORDER BY cmp.cmp_code, cbb.cbb_agent_id`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	user := dao.brokerBatch.User
+
+	rows, err := stmt.Query(user.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	brokerBatchRecs := make([]*model.BrokerBatch, 0)
+
+	for rows.Next() {
+		var brokerBatchRec model.BrokerBatch
+		var companyBatchId int64
+		var companyRec entity.Company
+		var bdr []uint8
+		var etf []uint8
+
+		err := rows.Scan(
+			&brokerBatchRec.Id,
+			&companyBatchId,
+			&brokerBatchRec.AgentId,
+			&brokerBatchRec.StartDate,
+			&brokerBatchRec.Qty,
+			&brokerBatchRec.AvgPrice,
+			&brokerBatchRec.TotalPrice,
+			&companyRec.Id,
+			&companyRec.Code,
+			&companyRec.Name,
+			&bdr,
+			&etf,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		setCompanyFlags(&companyRec, bdr, etf)
+
+		brokerBatchRec.User = user
+		brokerBatchRec.Company = &companyRec
+		brokerBatchRec.CompanyBatch = &entity.CompanyBatch{
+			Id:      companyBatchId,
+			User:    user,
+			Company: &companyRec,
+		}
+
+		brokerBatchRecs = append(brokerBatchRecs, &brokerBatchRec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return brokerBatchRecs, nil
+}
+
+func (dao *BrokerBatchDAO) CreateBrokerBatch() (*model.BrokerBatch, error) {
+	insertStmt := `INSERT INTO company_broker_batch (
+		cbt_id,
+		usr_id,
+		cmp_id,
+		cbb_agent_id,
+		cbb_start_date,
+		cbb_qty,
+		cbb_avg_price,
+		cbb_total_price
+	) VALUES (?,?,?,?,?,?,?,?)`
+
+	stmt, err := dao.tx.Prepare(insertStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	brokerBatch := dao.brokerBatch
+
+	res, err := stmt.Exec(
+		brokerBatch.CompanyBatch.Id,
+		brokerBatch.User.Id,
+		brokerBatch.Company.Id,
+		brokerBatch.AgentId,
+		brokerBatch.StartDate,
+		brokerBatch.Qty,
+		brokerBatch.AvgPrice,
+		brokerBatch.TotalPrice,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	brokerBatchRec := *brokerBatch
+	brokerBatchRec.Id = lastId
+
+	log.Printf(
+		"BrokerBatchDAO.CreateBrokerBatch: created new broker batch [%d, %s, %s, %d]",
+		brokerBatchRec.Id,
+		brokerBatchRec.Company.Code,
+		brokerBatchRec.AgentId,
+		brokerBatchRec.Qty,
+	)
+
+	return &brokerBatchRec, nil
+}
+
+func (dao *BrokerBatchDAO) UpdateBrokerBatch() (*model.BrokerBatch, error) {
+	updateStmt := `UPDATE company_broker_batch SET
+		cbb_qty = ?,
+		cbb_avg_price = ?,
+		cbb_total_price = ?
+	WHERE cbb_id = ?`
+
+	stmt, err := dao.tx.Prepare(updateStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	brokerBatch := dao.brokerBatch
+
+	res, err := stmt.Exec(
+		brokerBatch.Qty,
+		brokerBatch.AvgPrice,
+		brokerBatch.TotalPrice,
+		brokerBatch.Id,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rowCnt, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowCnt != 1 {
+		details := fmt.Sprintf("expected 1 row, found %d rows", rowCnt)
+		return nil, utils.GetError("BrokerBatchDAO.UpdateBrokerBatch", "ERR_DB_001", details)
+	}
+
+	log.Printf(
+		"BrokerBatchDAO.UpdateBrokerBatch: updated broker batch [%d, %s, %s, qty = %d, avg = %.4f]",
+		brokerBatch.Id,
+		brokerBatch.Company.Code,
+		brokerBatch.AgentId,
+		brokerBatch.Qty,
+		brokerBatch.AvgPrice,
+	)
+
+	return brokerBatch, nil
+}
+
+func (dao *BrokerBatchDAO) GetBrokerBatchesByCompanyBatch() ([]*model.BrokerBatch, error) {
+	query := `SELECT
+		cbb_id,
+		cbb_agent_id,
+		cbb_start_date,
+		cbb_qty,
+		cbb_avg_price,
+		cbb_total_price
+	FROM company_broker_batch
+	WHERE cbt_id = ?
+	ORDER BY cbb_agent_id`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	companyBatch := dao.brokerBatch.CompanyBatch
+
+	rows, err := stmt.Query(companyBatch.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	brokerBatchRecs := make([]*model.BrokerBatch, 0)
+
+	for rows.Next() {
+		var brokerBatchRec model.BrokerBatch
+
+		err := rows.Scan(
+			&brokerBatchRec.Id,
+			&brokerBatchRec.AgentId,
+			&brokerBatchRec.StartDate,
+			&brokerBatchRec.Qty,
+			&brokerBatchRec.AvgPrice,
+			&brokerBatchRec.TotalPrice,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		brokerBatchRec.CompanyBatch = companyBatch
+		brokerBatchRec.User = companyBatch.User
+		brokerBatchRec.Company = companyBatch.Company
+
+		brokerBatchRecs = append(brokerBatchRecs, &brokerBatchRec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return brokerBatchRecs, nil
+}
+
+// DeleteBrokerBatch removes a broker batch merged into another one.
+func (dao *BrokerBatchDAO) DeleteBrokerBatch() error {
+	deleteStmt := `DELETE FROM company_broker_batch WHERE cbb_id = ?`
+
+	stmt, err := dao.tx.Prepare(deleteStmt)
+
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	brokerBatch := dao.brokerBatch
+
+	if _, err = stmt.Exec(brokerBatch.Id); err != nil {
+		return err
+	}
+
+	log.Printf(
+		"BrokerBatchDAO.DeleteBrokerBatch: deleted broker batch [%d, %s, %s]",
+		brokerBatch.Id,
+		brokerBatch.Company.Code,
+		brokerBatch.AgentId,
+	)
+
+	return nil
+}
+
+// MoveBrokerBatch attaches the broker batch to another company batch.
+func (dao *BrokerBatchDAO) MoveBrokerBatch(companyBatch *entity.CompanyBatch) (*model.BrokerBatch, error) {
+	updateStmt := `UPDATE company_broker_batch SET
+		cbt_id = ?,
+		cmp_id = ?
+	WHERE cbb_id = ?`
+
+	stmt, err := dao.tx.Prepare(updateStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	brokerBatch := *dao.brokerBatch
+
+	_, err = stmt.Exec(
+		companyBatch.Id,
+		companyBatch.Company.Id,
+		brokerBatch.Id,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	brokerBatch.CompanyBatch = companyBatch
+	brokerBatch.Company = companyBatch.Company
+
+	log.Printf(
+		"BrokerBatchDAO.MoveBrokerBatch: moved broker batch [%d, %s, %s] to company batch %d",
+		brokerBatch.Id,
+		brokerBatch.Company.Code,
+		brokerBatch.AgentId,
+		companyBatch.Id,
+	)
+
+	return &brokerBatch, nil
+}
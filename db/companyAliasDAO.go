@@ -0,0 +1,71 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type CompanyAliasDAO struct {
+	tx           *sql.Tx
+	companyAlias *model.CompanyAlias
+}
+
+func GetCompanyAliasDAO(tx *sql.Tx, companyAlias *model.CompanyAlias) *CompanyAliasDAO {
+	return &CompanyAliasDAO{
+		tx:           tx,
+		companyAlias: companyAlias,
+	}
+}
+
+func (dao *CompanyAliasDAO) LoadCompanyAliases() ([]model.CompanyAlias, error) {
+	query := `SELECT
+		cal_name,
+		cmp_code
+	FROM company_alias
+	ORDER BY cal_name`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	var mysqlErr *mysql.MySQLError
+
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errNoSuchTable {
+		log.Print("CompanyAliasDAO.LoadCompanyAliases: WARNING: table company_alias not found")
+		return []model.CompanyAlias{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.Query()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	companyAliases := make([]model.CompanyAlias, 0)
+
+	for rows.Next() {
+		var companyAlias model.CompanyAlias
+
+		if err := rows.Scan(&companyAlias.Name, &companyAlias.Code); err != nil {
+			return nil, err
+		}
+
+		companyAliases = append(companyAliases, companyAlias)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("CompanyAliasDAO.LoadCompanyAliases: found %d aliases", len(companyAliases))
+
+	return companyAliases, nil
+}
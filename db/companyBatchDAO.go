@@ -189,3 +189,135 @@ func (dao *CompanyBatchDAO) CreateCompanyBatch() (*entity.CompanyBatch, error) {
 
 	return &companyBatchRec, nil
 }
+
+func (dao *CompanyBatchDAO) GetCompanyBatchesByUser() ([]*entity.CompanyBatch, error) {
+	query := `SELECT
+		cbt.cbt_id,
+		cbt.cbt_start_date,
+		cbt.cbt_qty,
+		cbt.cbt_avg_price,
+		cbt.cbt_total_price,
+		cmp.cmp_id,
+		cmp.cmp_code,
+		cmp.cmp_name,
+		cmp.cmp_bdr,
+		cmp.cmp_etf
+	FROM company_batch cbt
+	INNER JOIN company cmp ON cbt.cmp_id = cmp.cmp_id
+	WHERE cbt.usr_id = ?
+		AND cbt.cbt_qty > 0
+	ORDER BY cmp.cmp_code`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	user := dao.companyBatch.User
+
+	rows, err := stmt.Query(user.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	companyBatchRecs := make([]*entity.CompanyBatch, 0)
+
+	for rows.Next() {
+		var companyBatchRec entity.CompanyBatch
+		var companyRec entity.Company
+		var bdr []uint8
+		var etf []uint8
+
+		err := rows.Scan(
+			&companyBatchRec.Id,
+			&companyBatchRec.StartDate,
+			&companyBatchRec.Qty,
+			&companyBatchRec.AvgPrice,
+			&companyBatchRec.TotalPrice,
+			&companyRec.Id,
+			&companyRec.Code,
+			&companyRec.Name,
+			&bdr,
+			&etf,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		setCompanyFlags(&companyRec, bdr, etf)
+
+		companyBatchRec.User = user
+		companyBatchRec.Company = &companyRec
+
+		companyBatchRecs = append(companyBatchRecs, &companyBatchRec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return companyBatchRecs, nil
+}
+
+// GetLastMarketDate returns the date of the latest movement of the company
+// batch (invoice items, trades, custody transfers, termo settlements and
+// corporate events of its company), zero when there is none.
+func (dao *CompanyBatchDAO) GetLastMarketDate() (utils.B3Date, error) {
+	query := `SELECT MAX(market_date) FROM (
+		SELECT bii.biv_market_date AS market_date
+		FROM item_batch itb
+		INNER JOIN broker_invoice_item bii ON itb.bii_id = bii.bii_id
+		WHERE itb.cbt_id = ?
+		UNION ALL
+		SELECT trd.biv_market_date AS market_date
+		FROM trade trd
+		WHERE trd.cbt_id = ?
+		UNION ALL
+		SELECT ctr.ctr_market_date AS market_date
+		FROM custody_transfer ctr
+		WHERE ctr.cbt_id = ?
+		UNION ALL
+		SELECT tms.tms_date AS market_date
+		FROM termo_settlement tms
+		WHERE tms.cbt_id = ?
+		UNION ALL
+		SELECT cev.cev_market_date AS market_date
+		FROM corporate_event cev
+		WHERE cev.usr_id = ?
+			AND (cev.cmp_id = ? OR cev.cev_target_cmp_id = ?)
+	) mov`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return utils.B3Date{}, err
+	}
+
+	defer stmt.Close()
+
+	companyBatch := dao.companyBatch
+	var lastMarketDate sql.NullTime
+
+	err = stmt.QueryRow(
+		companyBatch.Id,
+		companyBatch.Id,
+		companyBatch.Id,
+		companyBatch.Id,
+		companyBatch.User.Id,
+		companyBatch.Company.Id,
+		companyBatch.Company.Id,
+	).Scan(&lastMarketDate)
+
+	if err != nil {
+		return utils.B3Date{}, err
+	}
+
+	return getB3Date(lastMarketDate), nil
+}
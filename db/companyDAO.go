@@ -19,6 +19,16 @@ func GetCompanyDAO(tx *sql.Tx, company *entity.Company) *CompanyDAO {
 	}
 }
 
+// setCompanyFlags maps the bit columns cmp_bdr and cmp_etf
+func setCompanyFlags(
+	companyRec *entity.Company,
+	bdr []uint8,
+	etf []uint8,
+) {
+	companyRec.BDR = (len(bdr) > 0 && bdr[0] == 1)
+	companyRec.ETF = (len(etf) > 0 && etf[0] == 1)
+}
+
 func (dao *CompanyDAO) GetCompany() (*entity.Company, error) {
 	query := `SELECT
 		cmp_id,
@@ -55,8 +65,7 @@ func (dao *CompanyDAO) GetCompany() (*entity.Company, error) {
 		return nil, err
 	}
 
-	companyRec.BDR = (bdr[0] == 1)
-	companyRec.ETF = (etf[0] == 1)
+	setCompanyFlags(&companyRec, bdr, etf)
 
 	log.Printf(
 		"companyDAO.CreateCompany: found company [%d, %s, %s]",
@@ -107,6 +116,8 @@ func (dao *CompanyDAO) CreateCompany() (*entity.Company, error) {
 		Id:   lastId,
 		Code: company.Code,
 		Name: company.Name,
+		BDR:  company.BDR,
+		ETF:  company.ETF,
 	}
 
 	log.Printf(
@@ -118,3 +129,96 @@ func (dao *CompanyDAO) CreateCompany() (*entity.Company, error) {
 
 	return companyRec, nil
 }
+
+func (dao *CompanyDAO) UpdateCompanyFlags() (*entity.Company, error) {
+	updateStmt := `UPDATE company SET
+		cmp_bdr = ?,
+		cmp_etf = ?
+	WHERE cmp_id = ?`
+
+	stmt, err := dao.tx.Prepare(updateStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	company := dao.company
+
+	_, err = stmt.Exec(
+		company.BDR,
+		company.ETF,
+		company.Id,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf(
+		"companyDAO.UpdateCompanyFlags: updated company [%d, %s, bdr = %t, etf = %t]",
+		company.Id,
+		company.Code,
+		company.BDR,
+		company.ETF,
+	)
+
+	return company, nil
+}
+
+func (dao *CompanyDAO) GetCompanies() ([]*entity.Company, error) {
+	query := `SELECT
+		cmp_id,
+		cmp_code,
+		cmp_name,
+		cmp_bdr,
+		cmp_etf
+	FROM company
+	ORDER BY cmp_code`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.Query()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	companyRecs := make([]*entity.Company, 0)
+
+	for rows.Next() {
+		var companyRec entity.Company
+		var bdr []uint8
+		var etf []uint8
+
+		err := rows.Scan(
+			&companyRec.Id,
+			&companyRec.Code,
+			&companyRec.Name,
+			&bdr,
+			&etf,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		setCompanyFlags(&companyRec, bdr, etf)
+		companyRecs = append(companyRecs, &companyRec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return companyRecs, nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/go-sql-driver/mysql"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
 )
 
 func GetConnection() (*sql.DB, error) {
@@ -20,6 +21,7 @@ func GetConnection() (*sql.DB, error) {
 		Timeout:              timeout,
 		AllowNativePasswords: true,
 		ParseTime:            true,
+		Loc:                  utils.B3Location,
 	}
 
 	return sql.Open("mysql", config.FormatDSN())
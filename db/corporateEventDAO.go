@@ -0,0 +1,129 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type CorporateEventDAO struct {
+	tx             *sql.Tx
+	corporateEvent *model.CorporateEvent
+}
+
+func GetCorporateEventDAO(tx *sql.Tx, corporateEvent *model.CorporateEvent) *CorporateEventDAO {
+	return &CorporateEventDAO{
+		tx:             tx,
+		corporateEvent: corporateEvent,
+	}
+}
+
+func (dao *CorporateEventDAO) IsNewEventFile() (bool, error) {
+	filename := dao.corporateEvent.FileName
+	query := `SELECT cev_id FROM corporate_event WHERE cev_filename = ? LIMIT 1`
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	var corporateEventId int64
+
+	err = stmt.QueryRow(filename).Scan(
+		&corporateEventId,
+	)
+
+	if err == sql.ErrNoRows {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+func (dao *CorporateEventDAO) CreateCorporateEvent() (*model.CorporateEvent, error) {
+	insertStmt := `INSERT INTO corporate_event (
+		usr_id,
+		cev_type,
+		cev_filename,
+		cev_market_date,
+		cmp_id,
+		cev_target_cmp_id,
+		cev_from_qty,
+		cev_to_qty,
+		cev_cost_rate,
+		cev_source_qty,
+		cev_target_qty,
+		cev_cost_moved,
+		cev_cash,
+		cev_fraction_qty,
+		cev_fraction_cash,
+		cev_fraction_cost,
+		trd_id
+	) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`
+
+	stmt, err := dao.tx.Prepare(insertStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	corporateEvent := dao.corporateEvent
+
+	var tradeId sql.NullInt64
+
+	if corporateEvent.Trade != nil {
+		tradeId = sql.NullInt64{Int64: corporateEvent.Trade.Id, Valid: true}
+	}
+
+	res, err := stmt.Exec(
+		corporateEvent.User.Id,
+		corporateEvent.Type,
+		corporateEvent.FileName,
+		corporateEvent.MarketDate,
+		corporateEvent.Company.Id,
+		corporateEvent.Target.Id,
+		corporateEvent.FromQty,
+		corporateEvent.ToQty,
+		corporateEvent.CostRate,
+		corporateEvent.SourceQty,
+		corporateEvent.TargetQty,
+		corporateEvent.CostMoved,
+		corporateEvent.Cash,
+		corporateEvent.FractionQty,
+		corporateEvent.FractionCash,
+		corporateEvent.FractionCost,
+		tradeId,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	corporateEventRec := *corporateEvent
+	corporateEventRec.Id = lastId
+
+	log.Printf(
+		"CorporateEventDAO.CreateCorporateEvent: created corporate event [%d, %s, %s -> %s, %d:%d, rate = %.6f]",
+		corporateEventRec.Id,
+		corporateEventRec.Type,
+		corporateEventRec.Company.Code,
+		corporateEventRec.Target.Code,
+		corporateEventRec.FromQty,
+		corporateEventRec.ToQty,
+		corporateEventRec.CostRate,
+	)
+
+	return &corporateEventRec, nil
+}
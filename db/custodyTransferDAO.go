@@ -0,0 +1,108 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type CustodyTransferDAO struct {
+	tx              *sql.Tx
+	custodyTransfer *model.CustodyTransfer
+}
+
+func GetCustodyTransferDAO(tx *sql.Tx, custodyTransfer *model.CustodyTransfer) *CustodyTransferDAO {
+	return &CustodyTransferDAO{
+		tx:              tx,
+		custodyTransfer: custodyTransfer,
+	}
+}
+
+func (dao *CustodyTransferDAO) IsNewCustodyFile() (bool, error) {
+	filename := dao.custodyTransfer.FileName
+	query := `SELECT ctr_id FROM custody_transfer WHERE ctr_filename = ? LIMIT 1`
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	var custodyTransferId int64
+
+	err = stmt.QueryRow(filename).Scan(
+		&custodyTransferId,
+	)
+
+	if err == sql.ErrNoRows {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	log.Printf("CustodyTransferDAO.IsNewCustodyFile: file already imported [%d, %s]", custodyTransferId, filename)
+
+	return false, nil
+}
+
+func (dao *CustodyTransferDAO) CreateCustodyTransfer() (*model.CustodyTransfer, error) {
+	insertStmt := `INSERT INTO custody_transfer (
+		usr_id,
+		cmp_id,
+		cbt_id,
+		ctr_filename,
+		ctr_market_date,
+		ctr_qty,
+		ctr_total_price,
+		ctr_origin_broker,
+		ctr_agent_id,
+		ctr_broker_move
+	) VALUES (?,?,?,?,?,?,?,?,?,?)`
+
+	stmt, err := dao.tx.Prepare(insertStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	custodyTransfer := dao.custodyTransfer
+
+	res, err := stmt.Exec(
+		custodyTransfer.User.Id,
+		custodyTransfer.Company.Id,
+		custodyTransfer.CompanyBatch.Id,
+		custodyTransfer.FileName,
+		custodyTransfer.MarketDate,
+		custodyTransfer.Qty,
+		custodyTransfer.TotalPrice,
+		custodyTransfer.OriginBroker,
+		custodyTransfer.AgentId,
+		custodyTransfer.BrokerMove,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	custodyTransferRec := *custodyTransfer
+	custodyTransferRec.Id = lastId
+
+	log.Printf(
+		"CustodyTransferDAO.CreateCustodyTransfer: created custody transfer [%d, %s, q = %d, tot = %.4f]",
+		custodyTransferRec.Id,
+		custodyTransferRec.Company.Code,
+		custodyTransferRec.Qty,
+		custodyTransferRec.TotalPrice,
+	)
+
+	return &custodyTransferRec, nil
+}
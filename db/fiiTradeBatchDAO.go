@@ -0,0 +1,311 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type FiiTradeBatchDAO struct {
+	tx            *sql.Tx
+	fiiTradeBatch *model.FiiTradeBatch
+}
+
+func GetFiiTradeBatchDAO(tx *sql.Tx, fiiTradeBatch *model.FiiTradeBatch) *FiiTradeBatchDAO {
+	return &FiiTradeBatchDAO{
+		tx:            tx,
+		fiiTradeBatch: fiiTradeBatch,
+	}
+}
+
+func (dao *FiiTradeBatchDAO) GetFiiTradeBatch() (*model.FiiTradeBatch, error) {
+	query := `SELECT
+		tbf_id,
+		tbf_loss,
+		tbf_results,
+		tbf_total_tax,
+		tbf_total_trade
+	FROM trade_batch_fii
+	WHERE trb_id = ?`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	tradeBatch := dao.fiiTradeBatch.TradeBatch
+	var fiiTradeBatchRec model.FiiTradeBatch
+	var fiiData entity.TradeBatchData
+
+	err = stmt.QueryRow(tradeBatch.Id).Scan(
+		&fiiTradeBatchRec.Id,
+		&fiiData.AccLoss,
+		&fiiData.Results,
+		&fiiData.TotalTax,
+		&fiiData.TotalTrade,
+	)
+
+	if err == sql.ErrNoRows {
+		log.Printf(
+			"FiiTradeBatchDAO.GetFiiTradeBatch: not found [trb = %d]",
+			tradeBatch.Id,
+		)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	fiiTradeBatchRec.TradeBatch = tradeBatch
+	fiiTradeBatchRec.Data = &fiiData
+
+	return &fiiTradeBatchRec, nil
+}
+
+// GetLastFiiTradeBatch returns the FII data of the user's latest trade batch
+// started before the batch being created, its losses carry over.
+func (dao *FiiTradeBatchDAO) GetLastFiiTradeBatch() (*model.FiiTradeBatch, error) {
+	query := `SELECT
+		tbf.tbf_id,
+		tbf.trb_id,
+		trb.trb_start_date,
+		tbf.tbf_loss,
+		tbf.tbf_results,
+		tbf.tbf_total_tax,
+		tbf.tbf_total_trade
+	FROM trade_batch_fii tbf
+	INNER JOIN trade_batch trb ON tbf.trb_id = trb.trb_id
+	WHERE trb.usr_id = ?
+	  AND trb.trb_start_date < ?
+	ORDER BY trb.trb_start_date DESC
+	LIMIT 1`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	tradeBatch := dao.fiiTradeBatch.TradeBatch
+	var fiiTradeBatchRec model.FiiTradeBatch
+	var tradeBatchRec entity.TradeBatch
+	var fiiData entity.TradeBatchData
+
+	err = stmt.QueryRow(
+		tradeBatch.User.Id,
+		tradeBatch.StartDate,
+	).Scan(
+		&fiiTradeBatchRec.Id,
+		&tradeBatchRec.Id,
+		&tradeBatchRec.StartDate,
+		&fiiData.AccLoss,
+		&fiiData.Results,
+		&fiiData.TotalTax,
+		&fiiData.TotalTrade,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	log.Printf(
+		"FiiTradeBatchDAO.GetLastFiiTradeBatch: found last FII trade batch [%d, %s]",
+		fiiTradeBatchRec.Id,
+		tradeBatchRec.StartDate.Format(time.RFC3339),
+	)
+
+	tradeBatchRec.User = tradeBatch.User
+	fiiTradeBatchRec.TradeBatch = &tradeBatchRec
+	fiiTradeBatchRec.Data = &fiiData
+
+	return &fiiTradeBatchRec, nil
+}
+
+// GetFiiTradeBatchesByPeriod returns the FII data of the user's trade batches
+// started in [from, to), keyed by trade batch id.
+func (dao *FiiTradeBatchDAO) GetFiiTradeBatchesByPeriod(
+	from time.Time,
+	to time.Time,
+) (map[int64]*model.FiiTradeBatch, error) {
+	query := `SELECT
+		tbf.tbf_id,
+		tbf.trb_id,
+		trb.trb_start_date,
+		tbf.tbf_loss,
+		tbf.tbf_results,
+		tbf.tbf_total_tax,
+		tbf.tbf_total_trade
+	FROM trade_batch_fii tbf
+	INNER JOIN trade_batch trb ON tbf.trb_id = trb.trb_id
+	WHERE trb.usr_id = ?
+	  AND trb.trb_start_date >= ?
+	  AND trb.trb_start_date < ?`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	tradeBatch := dao.fiiTradeBatch.TradeBatch
+
+	rows, err := stmt.Query(
+		tradeBatch.User.Id,
+		from,
+		to,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	fiiTradeBatchRecs := make(map[int64]*model.FiiTradeBatch)
+
+	for rows.Next() {
+		var fiiTradeBatchRec model.FiiTradeBatch
+		var tradeBatchRec entity.TradeBatch
+		var fiiData entity.TradeBatchData
+
+		err := rows.Scan(
+			&fiiTradeBatchRec.Id,
+			&tradeBatchRec.Id,
+			&tradeBatchRec.StartDate,
+			&fiiData.AccLoss,
+			&fiiData.Results,
+			&fiiData.TotalTax,
+			&fiiData.TotalTrade,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tradeBatchRec.User = tradeBatch.User
+		fiiTradeBatchRec.TradeBatch = &tradeBatchRec
+		fiiTradeBatchRec.Data = &fiiData
+
+		fiiTradeBatchRecs[tradeBatchRec.Id] = &fiiTradeBatchRec
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return fiiTradeBatchRecs, nil
+}
+
+func (dao *FiiTradeBatchDAO) CreateFiiTradeBatch() (*model.FiiTradeBatch, error) {
+	insertStmt := `INSERT INTO trade_batch_fii (
+		trb_id,
+		tbf_loss,
+		tbf_results,
+		tbf_total_tax,
+		tbf_total_trade
+	) VALUES (?,?,?,?,?)`
+
+	stmt, err := dao.tx.Prepare(insertStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	fiiTradeBatch := dao.fiiTradeBatch
+
+	res, err := stmt.Exec(
+		fiiTradeBatch.TradeBatch.Id,
+		fiiTradeBatch.Data.AccLoss,
+		fiiTradeBatch.Data.Results,
+		fiiTradeBatch.Data.TotalTax,
+		fiiTradeBatch.Data.TotalTrade,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	if err != nil {
+		return nil, err
+	}
+
+	fiiTradeBatchRec := &model.FiiTradeBatch{
+		Id:         lastId,
+		TradeBatch: fiiTradeBatch.TradeBatch,
+		Data:       fiiTradeBatch.Data,
+	}
+
+	log.Printf(
+		"FiiTradeBatchDAO.CreateFiiTradeBatch: created FII trade batch [%d, trb = %d]",
+		fiiTradeBatchRec.Id,
+		fiiTradeBatchRec.TradeBatch.Id,
+	)
+
+	return fiiTradeBatchRec, nil
+}
+
+func (dao *FiiTradeBatchDAO) UpdateFiiTradeBatch() error {
+	updateStmt := `UPDATE trade_batch_fii SET
+		tbf_loss = ?,
+		tbf_results = ?,
+		tbf_total_tax = ?,
+		tbf_total_trade = ?
+	WHERE tbf_id = ?`
+
+	stmt, err := dao.tx.Prepare(updateStmt)
+
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	fiiTradeBatch := dao.fiiTradeBatch
+
+	res, err := stmt.Exec(
+		fiiTradeBatch.Data.AccLoss,
+		fiiTradeBatch.Data.Results,
+		fiiTradeBatch.Data.TotalTax,
+		fiiTradeBatch.Data.TotalTrade,
+		fiiTradeBatch.Id,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	rowCnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// unchanged rows report 0 affected rows
+	if rowCnt > 1 {
+		details := fmt.Sprintf("expected 1 row, found %d rows", rowCnt)
+		return utils.GetError("FiiTradeBatchDAO.UpdateFiiTradeBatch", "ERR_DB_001", details)
+	}
+
+	log.Printf(
+		"FiiTradeBatchDAO.UpdateFiiTradeBatch: updated FII trade batch [%d]",
+		fiiTradeBatch.Id,
+	)
+
+	return nil
+}
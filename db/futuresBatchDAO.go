@@ -0,0 +1,395 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type FuturesBatchDAO struct {
+	tx           *sql.Tx
+	futuresBatch *model.FuturesBatch
+}
+
+func GetFuturesBatchDAO(tx *sql.Tx, futuresBatch *model.FuturesBatch) *FuturesBatchDAO {
+	return &FuturesBatchDAO{
+		tx:           tx,
+		futuresBatch: futuresBatch,
+	}
+}
+
+func (dao *FuturesBatchDAO) GetFuturesBatch() (*model.FuturesBatch, error) {
+	query := `SELECT
+		ftb_id,
+		tgr_id,
+		ftb_dt_loss,
+		ftb_dt_results,
+		ftb_dt_total_tax,
+		ftb_dt_total_trade,
+		ftb_loss,
+		ftb_results,
+		ftb_total_tax,
+		ftb_total_trade
+	FROM futures_batch
+	WHERE usr_id = ?
+	  AND ftb_start_date = ?`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	futuresBatch := dao.futuresBatch
+	var taxGroupId int64
+	var futuresBatchRec model.FuturesBatch
+	var dayTradeData entity.TradeBatchData
+	var commonData entity.TradeBatchData
+
+	err = stmt.QueryRow(
+		futuresBatch.User.Id,
+		futuresBatch.StartDate,
+	).Scan(
+		&futuresBatchRec.Id,
+		&taxGroupId,
+		&dayTradeData.AccLoss,
+		&dayTradeData.Results,
+		&dayTradeData.TotalTax,
+		&dayTradeData.TotalTrade,
+		&commonData.AccLoss,
+		&commonData.Results,
+		&commonData.TotalTax,
+		&commonData.TotalTrade,
+	)
+
+	if err == sql.ErrNoRows {
+		log.Printf(
+			"FuturesBatchDAO.GetFuturesBatch: not found [usr = %d, startDate = %s]",
+			futuresBatch.User.Id,
+			futuresBatch.StartDate.Format("2006-01-02"),
+		)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	log.Printf(
+		"FuturesBatchDAO.GetFuturesBatch: found futures batch [%d, %s]",
+		futuresBatchRec.Id,
+		futuresBatch.StartDate.Format("2006-01-02"),
+	)
+
+	taxGroup := entity.TaxGroup{
+		Id: taxGroupId,
+	}
+
+	taxGroupDAO := GetTaxGroupDAO(dao.tx, &taxGroup)
+	taxGroupRec, err := taxGroupDAO.GetTaxGroup()
+
+	if err != nil {
+		return nil, err
+	}
+
+	futuresBatchRec.User = futuresBatch.User
+	futuresBatchRec.TaxGroup = taxGroupRec
+	futuresBatchRec.StartDate = futuresBatch.StartDate
+	futuresBatchRec.DayTrade = &dayTradeData
+	futuresBatchRec.Common = &commonData
+
+	return &futuresBatchRec, nil
+}
+
+// GetLastFuturesBatch returns the user's latest futures batch started before
+// the batch being created, its losses carry over.
+func (dao *FuturesBatchDAO) GetLastFuturesBatch() (*model.FuturesBatch, error) {
+	query := `SELECT
+		ftb_id,
+		ftb_start_date,
+		ftb_dt_loss,
+		ftb_dt_results,
+		ftb_dt_total_tax,
+		ftb_dt_total_trade,
+		ftb_loss,
+		ftb_results,
+		ftb_total_tax,
+		ftb_total_trade
+	FROM futures_batch
+	WHERE usr_id = ?
+	  AND ftb_start_date < ?
+	ORDER BY ftb_start_date DESC
+	LIMIT 1`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	futuresBatch := dao.futuresBatch
+	var futuresBatchRec model.FuturesBatch
+	var dayTradeData entity.TradeBatchData
+	var commonData entity.TradeBatchData
+
+	err = stmt.QueryRow(
+		futuresBatch.User.Id,
+		futuresBatch.StartDate,
+	).Scan(
+		&futuresBatchRec.Id,
+		&futuresBatchRec.StartDate,
+		&dayTradeData.AccLoss,
+		&dayTradeData.Results,
+		&dayTradeData.TotalTax,
+		&dayTradeData.TotalTrade,
+		&commonData.AccLoss,
+		&commonData.Results,
+		&commonData.TotalTax,
+		&commonData.TotalTrade,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	log.Printf(
+		"FuturesBatchDAO.GetLastFuturesBatch: found last futures batch [%d, %s]",
+		futuresBatchRec.Id,
+		futuresBatchRec.StartDate.Format(time.RFC3339),
+	)
+
+	futuresBatchRec.User = futuresBatch.User
+	futuresBatchRec.DayTrade = &dayTradeData
+	futuresBatchRec.Common = &commonData
+
+	return &futuresBatchRec, nil
+}
+
+// GetFuturesBatchesByPeriod returns the user's futures batches started in
+// [from, to) with their tax groups.
+func (dao *FuturesBatchDAO) GetFuturesBatchesByPeriod(from time.Time, to time.Time) ([]*model.FuturesBatch, error) {
+	query := `SELECT
+		ftb_id,
+		tgr_id,
+		ftb_start_date,
+		ftb_dt_loss,
+		ftb_dt_results,
+		ftb_dt_total_tax,
+		ftb_dt_total_trade,
+		ftb_loss,
+		ftb_results,
+		ftb_total_tax,
+		ftb_total_trade
+	FROM futures_batch
+	WHERE usr_id = ?
+	  AND ftb_start_date >= ?
+	  AND ftb_start_date < ?
+	ORDER BY ftb_start_date`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	futuresBatch := dao.futuresBatch
+
+	rows, err := stmt.Query(
+		futuresBatch.User.Id,
+		from,
+		to,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	futuresBatchRecs := make([]*model.FuturesBatch, 0, 12)
+	taxGroupIds := make([]int64, 0, 12)
+
+	for rows.Next() {
+		var taxGroupId int64
+		var futuresBatchRec model.FuturesBatch
+		var dayTradeData entity.TradeBatchData
+		var commonData entity.TradeBatchData
+
+		err := rows.Scan(
+			&futuresBatchRec.Id,
+			&taxGroupId,
+			&futuresBatchRec.StartDate,
+			&dayTradeData.AccLoss,
+			&dayTradeData.Results,
+			&dayTradeData.TotalTax,
+			&dayTradeData.TotalTrade,
+			&commonData.AccLoss,
+			&commonData.Results,
+			&commonData.TotalTax,
+			&commonData.TotalTrade,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		futuresBatchRec.User = futuresBatch.User
+		futuresBatchRec.DayTrade = &dayTradeData
+		futuresBatchRec.Common = &commonData
+
+		futuresBatchRecs = append(futuresBatchRecs, &futuresBatchRec)
+		taxGroupIds = append(taxGroupIds, taxGroupId)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows.Close()
+
+	for idx, futuresBatchRec := range futuresBatchRecs {
+		taxGroup := entity.TaxGroup{
+			Id: taxGroupIds[idx],
+		}
+
+		taxGroupDAO := GetTaxGroupDAO(dao.tx, &taxGroup)
+		taxGroupRec, err := taxGroupDAO.GetTaxGroup()
+
+		if err != nil {
+			return nil, err
+		}
+
+		futuresBatchRec.TaxGroup = taxGroupRec
+	}
+
+	return futuresBatchRecs, nil
+}
+
+func (dao *FuturesBatchDAO) CreateFuturesBatch() (*model.FuturesBatch, error) {
+	insertStmt := `INSERT INTO futures_batch (
+		tgr_id,
+		usr_id,
+		ftb_start_date,
+		ftb_dt_loss,
+		ftb_dt_results,
+		ftb_dt_total_tax,
+		ftb_dt_total_trade,
+		ftb_loss,
+		ftb_results,
+		ftb_total_tax,
+		ftb_total_trade
+	) VALUES (?,?,?,?,?,?,?,?,?,?,?)`
+
+	stmt, err := dao.tx.Prepare(insertStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	futuresBatch := dao.futuresBatch
+
+	res, err := stmt.Exec(
+		futuresBatch.TaxGroup.Id,
+		futuresBatch.User.Id,
+		futuresBatch.StartDate,
+		futuresBatch.DayTrade.AccLoss,
+		futuresBatch.DayTrade.Results,
+		futuresBatch.DayTrade.TotalTax,
+		futuresBatch.DayTrade.TotalTrade,
+		futuresBatch.Common.AccLoss,
+		futuresBatch.Common.Results,
+		futuresBatch.Common.TotalTax,
+		futuresBatch.Common.TotalTrade,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	if err != nil {
+		return nil, err
+	}
+
+	futuresBatchRec := *futuresBatch
+	futuresBatchRec.Id = lastId
+
+	log.Printf(
+		"FuturesBatchDAO.CreateFuturesBatch: created futures batch [%d, %s]",
+		futuresBatchRec.Id,
+		futuresBatchRec.StartDate.Format(time.RFC3339),
+	)
+
+	return &futuresBatchRec, nil
+}
+
+func (dao *FuturesBatchDAO) UpdateFuturesBatch() error {
+	updateStmt := `UPDATE futures_batch SET
+		ftb_dt_loss = ?,
+		ftb_dt_results = ?,
+		ftb_dt_total_tax = ?,
+		ftb_dt_total_trade = ?,
+		ftb_loss = ?,
+		ftb_results = ?,
+		ftb_total_tax = ?,
+		ftb_total_trade = ?
+	WHERE ftb_id = ?`
+
+	stmt, err := dao.tx.Prepare(updateStmt)
+
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	futuresBatch := dao.futuresBatch
+
+	res, err := stmt.Exec(
+		futuresBatch.DayTrade.AccLoss,
+		futuresBatch.DayTrade.Results,
+		futuresBatch.DayTrade.TotalTax,
+		futuresBatch.DayTrade.TotalTrade,
+		futuresBatch.Common.AccLoss,
+		futuresBatch.Common.Results,
+		futuresBatch.Common.TotalTax,
+		futuresBatch.Common.TotalTrade,
+		futuresBatch.Id,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	rowCnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// unchanged rows report 0 affected rows
+	if rowCnt > 1 {
+		details := fmt.Sprintf("expected 1 row, found %d rows", rowCnt)
+		return utils.GetError("FuturesBatchDAO.UpdateFuturesBatch", "ERR_DB_001", details)
+	}
+
+	log.Printf(
+		"FuturesBatchDAO.UpdateFuturesBatch: updated futures batch [%d]",
+		futuresBatch.Id,
+	)
+
+	return nil
+}
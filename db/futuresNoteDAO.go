@@ -0,0 +1,111 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type FuturesNoteDAO struct {
+	tx          *sql.Tx
+	futuresNote *model.FuturesNote
+}
+
+func GetFuturesNoteDAO(tx *sql.Tx, futuresNote *model.FuturesNote) *FuturesNoteDAO {
+	return &FuturesNoteDAO{
+		tx:          tx,
+		futuresNote: futuresNote,
+	}
+}
+
+func (dao *FuturesNoteDAO) IsNewFuturesNote() (bool, error) {
+	filename := dao.futuresNote.FileName
+	query := `SELECT ftn_id FROM futures_note WHERE ftn_filename = ?`
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	var futuresNoteId int64
+
+	err = stmt.QueryRow(filename).Scan(
+		&futuresNoteId,
+	)
+
+	if err == sql.ErrNoRows {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	log.Printf("FuturesNoteDAO.IsNewFuturesNote: note already exists [%d, %s]", futuresNoteId, filename)
+
+	return false, nil
+}
+
+func (dao *FuturesNoteDAO) CreateFuturesNote() (*model.FuturesNote, error) {
+	insertStmt := `INSERT INTO futures_note (
+		usr_id,
+		tgr_id,
+		ftb_id,
+		ftn_filename,
+		ftn_number,
+		ftn_agent_id,
+		ftn_market_date,
+		ftn_billing_date,
+		ftn_dt_adjustment,
+		ftn_adjustment,
+		ftn_fees,
+		ftn_net_value
+	) VALUES (?,?,?,?,?,?,?,?,?,?,?,?)`
+
+	stmt, err := dao.tx.Prepare(insertStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	futuresNote := dao.futuresNote
+
+	res, err := stmt.Exec(
+		futuresNote.User.Id,
+		futuresNote.TaxGroup.Id,
+		futuresNote.FuturesBatch.Id,
+		futuresNote.FileName,
+		futuresNote.Number,
+		futuresNote.AgentId,
+		futuresNote.MarketDate,
+		futuresNote.BillingDate,
+		futuresNote.DayTradeAdjustment,
+		futuresNote.Adjustment,
+		futuresNote.Fees,
+		futuresNote.NetValue,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	if err != nil {
+		return nil, err
+	}
+
+	futuresNoteRec := *futuresNote
+	futuresNoteRec.Id = lastId
+
+	log.Printf(
+		"FuturesNoteDAO.CreateFuturesNote: created futures note [%d, %s]",
+		futuresNoteRec.Id,
+		futuresNoteRec.FileName,
+	)
+
+	return &futuresNoteRec, nil
+}
@@ -10,12 +10,15 @@ import (
 type InvoiceDAO struct {
 	tx      *sql.Tx
 	invoice *entity.Invoice
+	agentId string
 }
 
-func GetInvoiceDAO(tx *sql.Tx, invoice *entity.Invoice) *InvoiceDAO {
+// GetInvoiceDAO agentId is the clearing agent of the note, empty when unknown.
+func GetInvoiceDAO(tx *sql.Tx, invoice *entity.Invoice, agentId string) *InvoiceDAO {
 	return &InvoiceDAO{
 		tx:      tx,
 		invoice: invoice,
+		agentId: agentId,
 	}
 }
 
@@ -47,6 +50,48 @@ func (dao *InvoiceDAO) IsNewInvoice() (bool, error) {
 	return false, nil
 }
 
+// HasAgentInvoice tells if the user already has an invoice of the agent on
+// the market date, invoices of unknown agent count as the same agent.
+func (dao *InvoiceDAO) HasAgentInvoice() (bool, error) {
+	query := `SELECT biv_id, biv_filename
+	FROM broker_invoice
+	WHERE usr_id = ?
+		AND biv_market_date = ?
+		AND (biv_agent_id = ? OR biv_agent_id = '')
+	LIMIT 1`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	invoice := dao.invoice
+	var invoiceID int64
+	var filename string
+
+	err = stmt.QueryRow(
+		invoice.User.Id,
+		invoice.MarketDate,
+		dao.agentId,
+	).Scan(
+		&invoiceID,
+		&filename,
+	)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	log.Printf("invoiceDAO.HasAgentInvoice: agent %s invoice found [%d, %s]", dao.agentId, invoiceID, filename)
+
+	return true, nil
+}
+
 func (dao *InvoiceDAO) CreateInvoice() (*entity.Invoice, error) {
 	insertStmt := `INSERT INTO broker_invoice (
 		usr_id,
@@ -58,8 +103,9 @@ func (dao *InvoiceDAO) CreateInvoice() (*entity.Invoice, error) {
 		biv_raw_value,
 		biv_net_value,
 		biv_total_sold,
-		biv_total_acquired
-	  ) VALUES (?,?,?,?,?,?,?,?,?,?)`
+		biv_total_acquired,
+		biv_agent_id
+	  ) VALUES (?,?,?,?,?,?,?,?,?,?,?)`
 
 	stmt, err := dao.tx.Prepare(insertStmt)
 	if err != nil {
@@ -80,6 +126,7 @@ func (dao *InvoiceDAO) CreateInvoice() (*entity.Invoice, error) {
 		invoice.NetValue,
 		invoice.TotalSold,
 		invoice.TotalAcquired,
+		dao.agentId,
 	)
 
 	if err != nil {
@@ -8,14 +8,17 @@ import (
 )
 
 type InvoiceItemDAO struct {
-	tx   *sql.Tx
-	item *entity.InvoiceItem
+	tx            *sql.Tx
+	item          *entity.InvoiceItem
+	marketSegment string
 }
 
-func GetInvoiceItemDAO(tx *sql.Tx, item *entity.InvoiceItem) *InvoiceItemDAO {
+// GetInvoiceItemDAO marketSegment is one of constants.MarketSegments.
+func GetInvoiceItemDAO(tx *sql.Tx, item *entity.InvoiceItem, marketSegment string) *InvoiceItemDAO {
 	return &InvoiceItemDAO{
-		tx:   tx,
-		item: item,
+		tx:            tx,
+		item:          item,
+		marketSegment: marketSegment,
 	}
 }
 
@@ -27,8 +30,9 @@ func (dao *InvoiceItemDAO) CreateItem() (*entity.InvoiceItem, error) {
 		bii_order,
 		bii_qty,
 		bii_price,
-		bii_debit
-	) VALUES (?,?,?,?,?,?,?)`
+		bii_debit,
+		bii_market_segment
+	) VALUES (?,?,?,?,?,?,?,?)`
 
 	stmt, err := dao.tx.Prepare(insertStmt)
 
@@ -48,6 +52,7 @@ func (dao *InvoiceItemDAO) CreateItem() (*entity.InvoiceItem, error) {
 		item.Qty,
 		item.Price,
 		item.Debit,
+		dao.marketSegment,
 	)
 
 	if err != nil {
@@ -70,11 +75,12 @@ func (dao *InvoiceItemDAO) CreateItem() (*entity.InvoiceItem, error) {
 	}
 
 	log.Printf(
-		"invoiceItemDAO.CreateItem: created new invoice item [%d, %d, %s, %d]",
+		"invoiceItemDAO.CreateItem: created new invoice item [%d, %d, %s, %d, %s]",
 		lastId,
 		item.Order,
 		item.Company.Code,
 		item.Qty,
+		dao.marketSegment,
 	)
 
 	return itemRec, nil
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type LendingContractDAO struct {
+	tx              *sql.Tx
+	lendingContract *model.LendingContract
+}
+
+func GetLendingContractDAO(tx *sql.Tx, lendingContract *model.LendingContract) *LendingContractDAO {
+	return &LendingContractDAO{
+		tx:              tx,
+		lendingContract: lendingContract,
+	}
+}
+
+func (dao *LendingContractDAO) IsNewLendingContract() (bool, error) {
+	lendingContract := dao.lendingContract
+	query := `SELECT lnc_id
+	FROM lending_contract
+	WHERE usr_id = ?
+	  AND lnc_contract = ?
+	  AND lnc_side = ?`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	var lendingContractId int64
+
+	err = stmt.QueryRow(
+		lendingContract.User.Id,
+		lendingContract.Contract,
+		lendingContract.Side,
+	).Scan(
+		&lendingContractId,
+	)
+
+	if err == sql.ErrNoRows {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	log.Printf(
+		"LendingContractDAO.IsNewLendingContract: contract already exists [%d, %s]",
+		lendingContractId,
+		lendingContract.Contract,
+	)
+
+	return false, nil
+}
+
+// GetLentQtyByCode returns the shares of the user out on lender contracts
+// open on date, keyed by company code.
+func (dao *LendingContractDAO) GetLentQtyByCode(date time.Time) (map[string]int64, error) {
+	query := `SELECT
+		cmp.cmp_code,
+		SUM(lnc.lnc_qty)
+	FROM lending_contract lnc
+	INNER JOIN company cmp ON lnc.cmp_id = cmp.cmp_id
+	WHERE lnc.usr_id = ?
+	  AND lnc.lnc_side = ?
+	  AND lnc.lnc_start_date <= ?
+	  AND lnc.lnc_end_date > ?
+	GROUP BY cmp.cmp_code`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.Query(
+		dao.lendingContract.User.Id,
+		constants.LendingSides.LENDER,
+		date,
+		date,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	lentQtyByCode := make(map[string]int64)
+
+	for rows.Next() {
+		var code string
+		var qty int64
+
+		if err := rows.Scan(&code, &qty); err != nil {
+			return nil, err
+		}
+
+		lentQtyByCode[code] = qty
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lentQtyByCode, nil
+}
+
+func (dao *LendingContractDAO) CreateLendingContract() (*model.LendingContract, error) {
+	insertStmt := `INSERT INTO lending_contract (
+		usr_id,
+		cmp_id,
+		tgr_id,
+		lnc_contract,
+		lnc_side,
+		lnc_agent_id,
+		lnc_start_date,
+		lnc_end_date,
+		lnc_qty,
+		lnc_rate,
+		lnc_value,
+		lnc_irrf,
+		lnc_fees
+	) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?)`
+
+	stmt, err := dao.tx.Prepare(insertStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	lendingContract := dao.lendingContract
+
+	res, err := stmt.Exec(
+		lendingContract.User.Id,
+		lendingContract.Company.Id,
+		lendingContract.TaxGroup.Id,
+		lendingContract.Contract,
+		lendingContract.Side,
+		lendingContract.AgentId,
+		lendingContract.StartDate,
+		lendingContract.EndDate,
+		lendingContract.Qty,
+		lendingContract.Rate,
+		lendingContract.Value,
+		lendingContract.Irrf,
+		lendingContract.Fees,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	if err != nil {
+		return nil, err
+	}
+
+	lendingContractRec := *lendingContract
+	lendingContractRec.Id = lastId
+
+	log.Printf(
+		"LendingContractDAO.CreateLendingContract: created lending contract [%d, %s, %s, %s]",
+		lendingContractRec.Id,
+		lendingContractRec.Contract,
+		lendingContractRec.Side,
+		lendingContractRec.Company.Code,
+	)
+
+	return &lendingContractRec, nil
+}
@@ -0,0 +1,12 @@
+-- Tax and fee rates by validity interval, an empty end date is open ended.
+-- Optional: without it the rates come from B3_RATE_TABLE_FILE and
+-- constants.TaxRates.
+CREATE TABLE IF NOT EXISTS tax_rate (
+  trt_id BIGINT NOT NULL AUTO_INCREMENT,
+  trt_code VARCHAR(16) NOT NULL,
+  trt_start_date DATE NOT NULL,
+  trt_end_date DATE NULL,
+  trt_rate DECIMAL(12, 8) NOT NULL,
+  PRIMARY KEY (trt_id),
+  KEY idx_trt_code_start (trt_code, trt_start_date)
+);
@@ -0,0 +1,16 @@
+-- Custody by broker next to the consolidated company_batch, one row per
+-- user, company and clearing agent.
+CREATE TABLE IF NOT EXISTS company_broker_batch (
+  cbb_id BIGINT NOT NULL AUTO_INCREMENT,
+  cbt_id BIGINT NOT NULL,
+  usr_id BIGINT NOT NULL,
+  cmp_id BIGINT NOT NULL,
+  cbb_agent_id VARCHAR(16) NOT NULL,
+  cbb_start_date DATE NOT NULL,
+  cbb_qty BIGINT NOT NULL,
+  cbb_avg_price DECIMAL(18, 6) NOT NULL,
+  cbb_total_price DECIMAL(18, 6) NOT NULL,
+  PRIMARY KEY (cbb_id),
+  UNIQUE KEY uk_cbb_user_company_agent (usr_id, cmp_id, cbb_agent_id),
+  KEY idx_cbb_cbt (cbt_id)
+);
@@ -0,0 +1,17 @@
+-- Opening balances and positions transferred from another broker.
+CREATE TABLE IF NOT EXISTS custody_transfer (
+  ctr_id BIGINT NOT NULL AUTO_INCREMENT,
+  usr_id BIGINT NOT NULL,
+  cmp_id BIGINT NOT NULL,
+  cbt_id BIGINT NOT NULL,
+  ctr_filename VARCHAR(255) NOT NULL,
+  ctr_market_date DATE NOT NULL,
+  ctr_qty BIGINT NOT NULL,
+  ctr_total_price DECIMAL(18, 6) NOT NULL,
+  ctr_origin_broker VARCHAR(128) NOT NULL DEFAULT '',
+  ctr_agent_id VARCHAR(16) NOT NULL DEFAULT '',
+  ctr_broker_move BIT(1) NOT NULL DEFAULT b'0',
+  PRIMARY KEY (ctr_id),
+  KEY idx_ctr_user_date (usr_id, ctr_market_date),
+  KEY idx_ctr_filename (ctr_filename)
+);
@@ -0,0 +1,4 @@
+-- Market segment of the invoice item, STANDARD or FRACTIONAL (ticker
+-- suffix F), the item company is always the standard ticker.
+ALTER TABLE broker_invoice_item
+  ADD COLUMN bii_market_segment VARCHAR(16) NOT NULL DEFAULT 'STANDARD';
@@ -0,0 +1,7 @@
+-- Security names printed on broker notes and their tickers. Optional:
+-- without it names resolve from B3_COMPANY_ALIAS_FILE and the registries.
+CREATE TABLE IF NOT EXISTS company_alias (
+  cal_name VARCHAR(128) NOT NULL,
+  cmp_code VARCHAR(16) NOT NULL,
+  PRIMARY KEY (cal_name)
+);
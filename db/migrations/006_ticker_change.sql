@@ -0,0 +1,12 @@
+-- Ticker changes, applied once effective. Optional: without it no ticker
+-- change is loaded.
+CREATE TABLE IF NOT EXISTS ticker_change (
+  tch_id BIGINT NOT NULL AUTO_INCREMENT,
+  tch_old_code VARCHAR(16) NOT NULL,
+  tch_new_code VARCHAR(16) NOT NULL,
+  tch_new_name VARCHAR(128) NOT NULL DEFAULT '',
+  tch_effective_date DATE NOT NULL,
+  tch_applied BIT(1) NOT NULL DEFAULT b'0',
+  PRIMARY KEY (tch_id),
+  UNIQUE KEY uk_tch_old_code (tch_old_code, tch_effective_date)
+);
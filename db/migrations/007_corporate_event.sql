@@ -0,0 +1,28 @@
+-- Corporate events applied to a user position and the cost split they
+-- made; cash fractions are kept as trades without an invoice item.
+CREATE TABLE IF NOT EXISTS corporate_event (
+  cev_id BIGINT NOT NULL AUTO_INCREMENT,
+  usr_id BIGINT NOT NULL,
+  cev_type VARCHAR(16) NOT NULL,
+  cev_filename VARCHAR(255) NOT NULL,
+  cev_market_date DATE NOT NULL,
+  cmp_id BIGINT NOT NULL,
+  cev_target_cmp_id BIGINT NULL,
+  cev_from_qty BIGINT NOT NULL,
+  cev_to_qty BIGINT NOT NULL,
+  cev_cost_rate DECIMAL(12, 8) NOT NULL,
+  cev_source_qty BIGINT NOT NULL,
+  cev_target_qty BIGINT NOT NULL,
+  cev_cost_moved DECIMAL(18, 6) NOT NULL,
+  cev_cash DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  cev_fraction_qty DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  cev_fraction_cash DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  cev_fraction_cost DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  trd_id BIGINT NULL,
+  PRIMARY KEY (cev_id),
+  KEY idx_cev_user_date (usr_id, cev_market_date),
+  KEY idx_cev_filename (cev_filename)
+);
+
+ALTER TABLE trade
+  MODIFY COLUMN bii_id BIGINT NULL;
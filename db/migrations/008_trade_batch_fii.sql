@@ -0,0 +1,11 @@
+-- Real estate fund (FII) results of a monthly trade batch.
+CREATE TABLE IF NOT EXISTS trade_batch_fii (
+  tbf_id BIGINT NOT NULL AUTO_INCREMENT,
+  trb_id BIGINT NOT NULL,
+  tbf_loss DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  tbf_results DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  tbf_total_tax DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  tbf_total_trade DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  PRIMARY KEY (tbf_id),
+  UNIQUE KEY uk_tbf_trb (trb_id)
+);
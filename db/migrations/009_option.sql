@@ -0,0 +1,27 @@
+-- Open option positions and the option results of a monthly trade batch.
+CREATE TABLE IF NOT EXISTS option_batch (
+  opb_id BIGINT NOT NULL AUTO_INCREMENT,
+  usr_id BIGINT NOT NULL,
+  cmp_id BIGINT NOT NULL,
+  und_cmp_id BIGINT NOT NULL,
+  opb_type VARCHAR(16) NOT NULL,
+  opb_strike DECIMAL(18, 6) NOT NULL,
+  opb_expiry_date DATE NOT NULL,
+  opb_start_date DATE NOT NULL,
+  opb_qty BIGINT NOT NULL,
+  opb_premium DECIMAL(18, 6) NOT NULL,
+  PRIMARY KEY (opb_id),
+  UNIQUE KEY uk_opb_user_company (usr_id, cmp_id),
+  KEY idx_opb_expiry (opb_expiry_date)
+);
+
+CREATE TABLE IF NOT EXISTS trade_batch_option (
+  tbo_id BIGINT NOT NULL AUTO_INCREMENT,
+  trb_id BIGINT NOT NULL,
+  tbo_loss DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  tbo_results DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  tbo_total_tax DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  tbo_total_trade DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  PRIMARY KEY (tbo_id),
+  UNIQUE KEY uk_tbo_trb (trb_id)
+);
@@ -0,0 +1,36 @@
+-- Monthly BM&F futures results and the notes that made them.
+CREATE TABLE IF NOT EXISTS futures_batch (
+  ftb_id BIGINT NOT NULL AUTO_INCREMENT,
+  usr_id BIGINT NOT NULL,
+  tgr_id BIGINT NOT NULL,
+  ftb_start_date DATE NOT NULL,
+  ftb_dt_loss DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  ftb_dt_results DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  ftb_dt_total_tax DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  ftb_dt_total_trade DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  ftb_loss DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  ftb_results DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  ftb_total_tax DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  ftb_total_trade DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  PRIMARY KEY (ftb_id),
+  UNIQUE KEY uk_ftb_user_start (usr_id, ftb_start_date)
+);
+
+CREATE TABLE IF NOT EXISTS futures_note (
+  ftn_id BIGINT NOT NULL AUTO_INCREMENT,
+  usr_id BIGINT NOT NULL,
+  tgr_id BIGINT NOT NULL,
+  ftb_id BIGINT NOT NULL,
+  ftn_filename VARCHAR(255) NOT NULL,
+  ftn_number BIGINT NOT NULL,
+  ftn_agent_id VARCHAR(16) NOT NULL,
+  ftn_market_date DATE NOT NULL,
+  ftn_billing_date DATE NOT NULL,
+  ftn_dt_adjustment DECIMAL(18, 6) NOT NULL,
+  ftn_adjustment DECIMAL(18, 6) NOT NULL,
+  ftn_fees DECIMAL(18, 6) NOT NULL,
+  ftn_net_value DECIMAL(18, 6) NOT NULL,
+  PRIMARY KEY (ftn_id),
+  UNIQUE KEY uk_ftn_filename (ftn_filename),
+  KEY idx_ftn_ftb (ftb_id)
+);
@@ -0,0 +1,30 @@
+-- Termo purchases held until settlement and their (early) settlements.
+CREATE TABLE IF NOT EXISTS termo_position (
+  tmp_id BIGINT NOT NULL AUTO_INCREMENT,
+  usr_id BIGINT NOT NULL,
+  cmp_id BIGINT NOT NULL,
+  bii_id BIGINT NOT NULL,
+  tgr_id BIGINT NOT NULL,
+  tmp_agent_id VARCHAR(16) NOT NULL,
+  tmp_open_date DATE NOT NULL,
+  tmp_due_date DATE NOT NULL,
+  tmp_qty BIGINT NOT NULL,
+  tmp_open_qty BIGINT NOT NULL,
+  tmp_price DECIMAL(18, 6) NOT NULL,
+  tmp_total_cost DECIMAL(18, 6) NOT NULL,
+  PRIMARY KEY (tmp_id),
+  KEY idx_tmp_user_due (usr_id, tmp_due_date)
+);
+
+CREATE TABLE IF NOT EXISTS termo_settlement (
+  tms_id BIGINT NOT NULL AUTO_INCREMENT,
+  tmp_id BIGINT NOT NULL,
+  cbt_id BIGINT NOT NULL,
+  tms_date DATE NOT NULL,
+  tms_qty BIGINT NOT NULL,
+  tms_total_cost DECIMAL(18, 6) NOT NULL,
+  tms_early BIT(1) NOT NULL DEFAULT b'0',
+  PRIMARY KEY (tms_id),
+  KEY idx_tms_tmp (tmp_id),
+  KEY idx_tms_date (tms_date)
+);
@@ -0,0 +1,20 @@
+-- Stock lending (BTC) contracts as lender or borrower.
+CREATE TABLE IF NOT EXISTS lending_contract (
+  lnc_id BIGINT NOT NULL AUTO_INCREMENT,
+  usr_id BIGINT NOT NULL,
+  cmp_id BIGINT NOT NULL,
+  tgr_id BIGINT NOT NULL,
+  lnc_contract VARCHAR(32) NOT NULL,
+  lnc_side VARCHAR(16) NOT NULL,
+  lnc_agent_id VARCHAR(16) NOT NULL,
+  lnc_start_date DATE NOT NULL,
+  lnc_end_date DATE NOT NULL,
+  lnc_qty BIGINT NOT NULL,
+  lnc_rate DECIMAL(12, 8) NOT NULL,
+  lnc_value DECIMAL(18, 6) NOT NULL,
+  lnc_irrf DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  lnc_fees DECIMAL(18, 6) NOT NULL DEFAULT 0,
+  PRIMARY KEY (lnc_id),
+  UNIQUE KEY uk_lnc_user_contract (usr_id, lnc_contract),
+  KEY idx_lnc_user_dates (usr_id, lnc_start_date, lnc_end_date)
+);
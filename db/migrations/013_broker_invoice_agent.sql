@@ -0,0 +1,6 @@
+-- Clearing agent of the invoice, empty on invoices loaded before it was kept;
+-- estimated invoices of the negotiation export are refused on a date the
+-- agent already has an invoice.
+ALTER TABLE broker_invoice
+  ADD COLUMN biv_agent_id VARCHAR(16) NOT NULL DEFAULT '',
+  ADD KEY idx_biv_user_date (usr_id, biv_market_date);
@@ -0,0 +1,39 @@
+-- Custody held before it was tracked by broker, kept under an empty agent;
+-- sales at a broker with less custody than sold draw the rest from it.
+INSERT INTO company_broker_batch (
+  cbt_id,
+  usr_id,
+  cmp_id,
+  cbb_agent_id,
+  cbb_start_date,
+  cbb_qty,
+  cbb_avg_price,
+  cbb_total_price
+)
+SELECT
+  cbt.cbt_id,
+  cbt.usr_id,
+  cbt.cmp_id,
+  '',
+  cbt.cbt_start_date,
+  cbt.cbt_qty - COALESCE(SUM(cbb.cbb_qty), 0),
+  cbt.cbt_avg_price,
+  cbt.cbt_avg_price * (cbt.cbt_qty - COALESCE(SUM(cbb.cbb_qty), 0))
+FROM company_batch cbt
+LEFT JOIN company_broker_batch cbb ON cbb.cbt_id = cbt.cbt_id
+WHERE cbt.cbt_qty > 0
+  AND NOT EXISTS (
+    SELECT 1
+    FROM company_broker_batch unt
+    WHERE unt.usr_id = cbt.usr_id
+      AND unt.cmp_id = cbt.cmp_id
+      AND unt.cbb_agent_id = ''
+  )
+GROUP BY
+  cbt.cbt_id,
+  cbt.usr_id,
+  cbt.cmp_id,
+  cbt.cbt_start_date,
+  cbt.cbt_qty,
+  cbt.cbt_avg_price
+HAVING cbt.cbt_qty - COALESCE(SUM(cbb.cbb_qty), 0) > 0;
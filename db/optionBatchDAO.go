@@ -0,0 +1,280 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type OptionBatchDAO struct {
+	tx          *sql.Tx
+	optionBatch *model.OptionBatch
+}
+
+func GetOptionBatchDAO(tx *sql.Tx, optionBatch *model.OptionBatch) *OptionBatchDAO {
+	return &OptionBatchDAO{
+		tx:          tx,
+		optionBatch: optionBatch,
+	}
+}
+
+// GetOptionBatch returns the open batch of the option series, nil when the
+// user holds no position on it.
+func (dao *OptionBatchDAO) GetOptionBatch() (*model.OptionBatch, error) {
+	query := `SELECT
+		opb.opb_id,
+		opb.opb_type,
+		opb.opb_strike,
+		opb.opb_expiry_date,
+		opb.opb_start_date,
+		opb.opb_qty,
+		opb.opb_premium,
+		und.cmp_id,
+		und.cmp_code,
+		und.cmp_name
+	FROM option_batch opb
+	INNER JOIN company und ON opb.und_cmp_id = und.cmp_id
+	WHERE opb.cmp_id = ?
+		AND opb.usr_id = ?
+		AND opb.opb_qty <> 0`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	optionBatch := dao.optionBatch
+	var optionBatchRec model.OptionBatch
+	var underlying entity.Company
+
+	err = stmt.QueryRow(
+		optionBatch.Company.Id,
+		optionBatch.User.Id,
+	).Scan(
+		&optionBatchRec.Id,
+		&optionBatchRec.Type,
+		&optionBatchRec.Strike,
+		&optionBatchRec.ExpiryDate,
+		&optionBatchRec.StartDate,
+		&optionBatchRec.Qty,
+		&optionBatchRec.Premium,
+		&underlying.Id,
+		&underlying.Code,
+		&underlying.Name,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	optionBatchRec.User = optionBatch.User
+	optionBatchRec.Company = optionBatch.Company
+	optionBatchRec.Underlying = &underlying
+
+	log.Printf(
+		"OptionBatchDAO.GetOptionBatch: found option batch [%d, %s, %d]",
+		optionBatchRec.Id,
+		optionBatchRec.Company.Code,
+		optionBatchRec.Qty,
+	)
+
+	return &optionBatchRec, nil
+}
+
+// GetExpiredOptionBatches returns the user's open option batches expiring
+// before until, ordered by expiry date.
+func (dao *OptionBatchDAO) GetExpiredOptionBatches(until time.Time) ([]*model.OptionBatch, error) {
+	query := `SELECT
+		opb.opb_id,
+		opb.opb_type,
+		opb.opb_strike,
+		opb.opb_expiry_date,
+		opb.opb_start_date,
+		opb.opb_qty,
+		opb.opb_premium,
+		cmp.cmp_id,
+		cmp.cmp_code,
+		cmp.cmp_name,
+		und.cmp_id,
+		und.cmp_code,
+		und.cmp_name
+	FROM option_batch opb
+	INNER JOIN company cmp ON opb.cmp_id = cmp.cmp_id
+	INNER JOIN company und ON opb.und_cmp_id = und.cmp_id
+	WHERE opb.usr_id = ?
+		AND opb.opb_qty <> 0
+		AND opb.opb_expiry_date < ?
+	ORDER BY opb.opb_expiry_date, cmp.cmp_code`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	user := dao.optionBatch.User
+
+	rows, err := stmt.Query(user.Id, until)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	optionBatchRecs := make([]*model.OptionBatch, 0)
+
+	for rows.Next() {
+		var optionBatchRec model.OptionBatch
+		var company entity.Company
+		var underlying entity.Company
+
+		err := rows.Scan(
+			&optionBatchRec.Id,
+			&optionBatchRec.Type,
+			&optionBatchRec.Strike,
+			&optionBatchRec.ExpiryDate,
+			&optionBatchRec.StartDate,
+			&optionBatchRec.Qty,
+			&optionBatchRec.Premium,
+			&company.Id,
+			&company.Code,
+			&company.Name,
+			&underlying.Id,
+			&underlying.Code,
+			&underlying.Name,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		optionBatchRec.User = user
+		optionBatchRec.Company = &company
+		optionBatchRec.Underlying = &underlying
+
+		optionBatchRecs = append(optionBatchRecs, &optionBatchRec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return optionBatchRecs, nil
+}
+
+func (dao *OptionBatchDAO) CreateOptionBatch() (*model.OptionBatch, error) {
+	insertStmt := `INSERT INTO option_batch (
+		usr_id,
+		cmp_id,
+		und_cmp_id,
+		opb_type,
+		opb_strike,
+		opb_expiry_date,
+		opb_start_date,
+		opb_qty,
+		opb_premium
+	) VALUES (?,?,?,?,?,?,?,?,?)`
+
+	stmt, err := dao.tx.Prepare(insertStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	optionBatch := dao.optionBatch
+
+	res, err := stmt.Exec(
+		optionBatch.User.Id,
+		optionBatch.Company.Id,
+		optionBatch.Underlying.Id,
+		optionBatch.Type,
+		optionBatch.Strike,
+		optionBatch.ExpiryDate,
+		optionBatch.StartDate,
+		optionBatch.Qty,
+		optionBatch.Premium,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	if err != nil {
+		return nil, err
+	}
+
+	optionBatchRec := *optionBatch
+	optionBatchRec.Id = lastId
+
+	log.Printf(
+		"OptionBatchDAO.CreateOptionBatch: created option batch [%d, %s, %d]",
+		optionBatchRec.Id,
+		optionBatchRec.Company.Code,
+		optionBatchRec.Qty,
+	)
+
+	return &optionBatchRec, nil
+}
+
+func (dao *OptionBatchDAO) UpdateOptionBatch() error {
+	updateStmt := `UPDATE option_batch SET
+		opb_qty = ?,
+		opb_premium = ?
+	WHERE opb_id = ?`
+
+	stmt, err := dao.tx.Prepare(updateStmt)
+
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	optionBatch := dao.optionBatch
+
+	res, err := stmt.Exec(
+		optionBatch.Qty,
+		optionBatch.Premium,
+		optionBatch.Id,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	rowCnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowCnt > 1 {
+		details := fmt.Sprintf("expected 1 row, found %d rows", rowCnt)
+		return utils.GetError("OptionBatchDAO.UpdateOptionBatch", "ERR_DB_001", details)
+	}
+
+	log.Printf(
+		"OptionBatchDAO.UpdateOptionBatch: record updated [%d, %s, %d]",
+		optionBatch.Id,
+		optionBatch.Company.Code,
+		optionBatch.Qty,
+	)
+
+	return nil
+}
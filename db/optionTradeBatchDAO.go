@@ -0,0 +1,311 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type OptionTradeBatchDAO struct {
+	tx               *sql.Tx
+	optionTradeBatch *model.OptionTradeBatch
+}
+
+func GetOptionTradeBatchDAO(tx *sql.Tx, optionTradeBatch *model.OptionTradeBatch) *OptionTradeBatchDAO {
+	return &OptionTradeBatchDAO{
+		tx:               tx,
+		optionTradeBatch: optionTradeBatch,
+	}
+}
+
+func (dao *OptionTradeBatchDAO) GetOptionTradeBatch() (*model.OptionTradeBatch, error) {
+	query := `SELECT
+		tbo_id,
+		tbo_loss,
+		tbo_results,
+		tbo_total_tax,
+		tbo_total_trade
+	FROM trade_batch_option
+	WHERE trb_id = ?`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	tradeBatch := dao.optionTradeBatch.TradeBatch
+	var optionTradeBatchRec model.OptionTradeBatch
+	var optionData entity.TradeBatchData
+
+	err = stmt.QueryRow(tradeBatch.Id).Scan(
+		&optionTradeBatchRec.Id,
+		&optionData.AccLoss,
+		&optionData.Results,
+		&optionData.TotalTax,
+		&optionData.TotalTrade,
+	)
+
+	if err == sql.ErrNoRows {
+		log.Printf(
+			"OptionTradeBatchDAO.GetOptionTradeBatch: not found [trb = %d]",
+			tradeBatch.Id,
+		)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	optionTradeBatchRec.TradeBatch = tradeBatch
+	optionTradeBatchRec.Data = &optionData
+
+	return &optionTradeBatchRec, nil
+}
+
+// GetLastOptionTradeBatch returns the option data of the user's latest trade
+// batch started before the batch being created, its losses carry over.
+func (dao *OptionTradeBatchDAO) GetLastOptionTradeBatch() (*model.OptionTradeBatch, error) {
+	query := `SELECT
+		tbo.tbo_id,
+		tbo.trb_id,
+		trb.trb_start_date,
+		tbo.tbo_loss,
+		tbo.tbo_results,
+		tbo.tbo_total_tax,
+		tbo.tbo_total_trade
+	FROM trade_batch_option tbo
+	INNER JOIN trade_batch trb ON tbo.trb_id = trb.trb_id
+	WHERE trb.usr_id = ?
+	  AND trb.trb_start_date < ?
+	ORDER BY trb.trb_start_date DESC
+	LIMIT 1`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	tradeBatch := dao.optionTradeBatch.TradeBatch
+	var optionTradeBatchRec model.OptionTradeBatch
+	var tradeBatchRec entity.TradeBatch
+	var optionData entity.TradeBatchData
+
+	err = stmt.QueryRow(
+		tradeBatch.User.Id,
+		tradeBatch.StartDate,
+	).Scan(
+		&optionTradeBatchRec.Id,
+		&tradeBatchRec.Id,
+		&tradeBatchRec.StartDate,
+		&optionData.AccLoss,
+		&optionData.Results,
+		&optionData.TotalTax,
+		&optionData.TotalTrade,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	log.Printf(
+		"OptionTradeBatchDAO.GetLastOptionTradeBatch: found last option trade batch [%d, %s]",
+		optionTradeBatchRec.Id,
+		tradeBatchRec.StartDate.Format(time.RFC3339),
+	)
+
+	tradeBatchRec.User = tradeBatch.User
+	optionTradeBatchRec.TradeBatch = &tradeBatchRec
+	optionTradeBatchRec.Data = &optionData
+
+	return &optionTradeBatchRec, nil
+}
+
+// GetOptionTradeBatchesByPeriod returns the option data of the user's trade
+// batches started in [from, to), keyed by trade batch id.
+func (dao *OptionTradeBatchDAO) GetOptionTradeBatchesByPeriod(
+	from time.Time,
+	to time.Time,
+) (map[int64]*model.OptionTradeBatch, error) {
+	query := `SELECT
+		tbo.tbo_id,
+		tbo.trb_id,
+		trb.trb_start_date,
+		tbo.tbo_loss,
+		tbo.tbo_results,
+		tbo.tbo_total_tax,
+		tbo.tbo_total_trade
+	FROM trade_batch_option tbo
+	INNER JOIN trade_batch trb ON tbo.trb_id = trb.trb_id
+	WHERE trb.usr_id = ?
+	  AND trb.trb_start_date >= ?
+	  AND trb.trb_start_date < ?`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	tradeBatch := dao.optionTradeBatch.TradeBatch
+
+	rows, err := stmt.Query(
+		tradeBatch.User.Id,
+		from,
+		to,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	optionTradeBatchRecs := make(map[int64]*model.OptionTradeBatch)
+
+	for rows.Next() {
+		var optionTradeBatchRec model.OptionTradeBatch
+		var tradeBatchRec entity.TradeBatch
+		var optionData entity.TradeBatchData
+
+		err := rows.Scan(
+			&optionTradeBatchRec.Id,
+			&tradeBatchRec.Id,
+			&tradeBatchRec.StartDate,
+			&optionData.AccLoss,
+			&optionData.Results,
+			&optionData.TotalTax,
+			&optionData.TotalTrade,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tradeBatchRec.User = tradeBatch.User
+		optionTradeBatchRec.TradeBatch = &tradeBatchRec
+		optionTradeBatchRec.Data = &optionData
+
+		optionTradeBatchRecs[tradeBatchRec.Id] = &optionTradeBatchRec
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return optionTradeBatchRecs, nil
+}
+
+func (dao *OptionTradeBatchDAO) CreateOptionTradeBatch() (*model.OptionTradeBatch, error) {
+	insertStmt := `INSERT INTO trade_batch_option (
+		trb_id,
+		tbo_loss,
+		tbo_results,
+		tbo_total_tax,
+		tbo_total_trade
+	) VALUES (?,?,?,?,?)`
+
+	stmt, err := dao.tx.Prepare(insertStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	optionTradeBatch := dao.optionTradeBatch
+
+	res, err := stmt.Exec(
+		optionTradeBatch.TradeBatch.Id,
+		optionTradeBatch.Data.AccLoss,
+		optionTradeBatch.Data.Results,
+		optionTradeBatch.Data.TotalTax,
+		optionTradeBatch.Data.TotalTrade,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	if err != nil {
+		return nil, err
+	}
+
+	optionTradeBatchRec := &model.OptionTradeBatch{
+		Id:         lastId,
+		TradeBatch: optionTradeBatch.TradeBatch,
+		Data:       optionTradeBatch.Data,
+	}
+
+	log.Printf(
+		"OptionTradeBatchDAO.CreateOptionTradeBatch: created option trade batch [%d, trb = %d]",
+		optionTradeBatchRec.Id,
+		optionTradeBatchRec.TradeBatch.Id,
+	)
+
+	return optionTradeBatchRec, nil
+}
+
+func (dao *OptionTradeBatchDAO) UpdateOptionTradeBatch() error {
+	updateStmt := `UPDATE trade_batch_option SET
+		tbo_loss = ?,
+		tbo_results = ?,
+		tbo_total_tax = ?,
+		tbo_total_trade = ?
+	WHERE tbo_id = ?`
+
+	stmt, err := dao.tx.Prepare(updateStmt)
+
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	optionTradeBatch := dao.optionTradeBatch
+
+	res, err := stmt.Exec(
+		optionTradeBatch.Data.AccLoss,
+		optionTradeBatch.Data.Results,
+		optionTradeBatch.Data.TotalTax,
+		optionTradeBatch.Data.TotalTrade,
+		optionTradeBatch.Id,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	rowCnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// unchanged rows report 0 affected rows
+	if rowCnt > 1 {
+		details := fmt.Sprintf("expected 1 row, found %d rows", rowCnt)
+		return utils.GetError("OptionTradeBatchDAO.UpdateOptionTradeBatch", "ERR_DB_001", details)
+	}
+
+	log.Printf(
+		"OptionTradeBatchDAO.UpdateOptionTradeBatch: updated option trade batch [%d]",
+		optionTradeBatch.Id,
+	)
+
+	return nil
+}
@@ -0,0 +1,268 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type PositionDAO struct {
+	tx   *sql.Tx
+	user *entity.User
+}
+
+func GetPositionDAO(tx *sql.Tx, user *entity.User) *PositionDAO {
+	return &PositionDAO{
+		tx:   tx,
+		user: user,
+	}
+}
+
+// GetPositionEvents returns the position events up to the until market date.
+func (dao *PositionDAO) GetPositionEvents(until time.Time) ([]model.PositionEvent, error) {
+	return dao.getPositionEvents(until, "bii.biv_market_date", "trd.biv_market_date")
+}
+
+// GetSettledPositionEvents returns the position events settled up to until,
+// invoice items are cut off by their billing (settlement) date as B3 custody
+// is.
+func (dao *PositionDAO) GetSettledPositionEvents(until time.Time) ([]model.PositionEvent, error) {
+	return dao.getPositionEvents(until, "biv.biv_billing_date", "biv.biv_billing_date")
+}
+
+// getPositionEvents itemDate and tradeDate are the columns invoice items and
+// trades are cut off by.
+func (dao *PositionDAO) getPositionEvents(
+	until time.Time,
+	itemDate string,
+	tradeDate string,
+) ([]model.PositionEvent, error) {
+	query := `SELECT * FROM (
+		SELECT
+			bii.biv_market_date AS market_date,
+			bii.bii_order AS item_order,
+			cmp.cmp_id,
+			cmp.cmp_code,
+			cmp.cmp_name,
+			cmp.cmp_bdr,
+			cmp.cmp_etf,
+			1 AS debit,
+			itb.itb_qty AS qty,
+			itb.itb_raw_price + itb.itb_total_tax AS total_price,
+			1 AS invoice_item,
+			0 AS total_tax,
+			0 AS results
+		FROM item_batch itb
+		INNER JOIN broker_invoice_item bii ON itb.bii_id = bii.bii_id
+		INNER JOIN broker_invoice biv ON bii.biv_id = biv.biv_id
+		INNER JOIN company cmp ON bii.cmp_id = cmp.cmp_id
+		WHERE biv.usr_id = ?
+			AND ` + itemDate + ` <= ?
+		UNION ALL
+		SELECT
+			ctr.ctr_market_date AS market_date,
+			0 AS item_order,
+			cmp.cmp_id,
+			cmp.cmp_code,
+			cmp.cmp_name,
+			cmp.cmp_bdr,
+			cmp.cmp_etf,
+			1 AS debit,
+			ctr.ctr_qty AS qty,
+			ctr.ctr_total_price AS total_price,
+			0 AS invoice_item,
+			0 AS total_tax,
+			0 AS results
+		FROM custody_transfer ctr
+		INNER JOIN company cmp ON ctr.cmp_id = cmp.cmp_id
+		WHERE ctr.usr_id = ?
+			AND ctr.ctr_market_date <= ?
+			AND ctr.ctr_broker_move = 0
+		UNION ALL
+		SELECT
+			trd.biv_market_date AS market_date,
+			bii.bii_order AS item_order,
+			cmp.cmp_id,
+			cmp.cmp_code,
+			cmp.cmp_name,
+			cmp.cmp_bdr,
+			cmp.cmp_etf,
+			0 AS debit,
+			trd.trd_qty AS qty,
+			trd.trd_qty * trd.trd_raw_price AS total_price,
+			1 AS invoice_item,
+			trd.trd_total_tax AS total_tax,
+			0 AS results
+		FROM trade trd
+		INNER JOIN broker_invoice_item bii ON trd.bii_id = bii.bii_id
+		INNER JOIN broker_invoice biv ON bii.biv_id = biv.biv_id
+		INNER JOIN company cmp ON bii.cmp_id = cmp.cmp_id
+		WHERE biv.usr_id = ?
+			AND ` + tradeDate + ` <= ?
+		UNION ALL
+		SELECT
+			cev.cev_market_date AS market_date,
+			0 AS item_order,
+			cmp.cmp_id,
+			cmp.cmp_code,
+			cmp.cmp_name,
+			cmp.cmp_bdr,
+			cmp.cmp_etf,
+			CASE WHEN cev.cev_type IN ('INCORPORATION', 'SUBSCRIPTION', 'UNIT_SPLIT', 'UNIT_MERGE') THEN 0 ELSE 1 END AS debit,
+			CASE WHEN cev.cev_type IN ('INCORPORATION', 'SUBSCRIPTION', 'UNIT_SPLIT', 'UNIT_MERGE') THEN cev.cev_source_qty ELSE 0 END AS qty,
+			CASE WHEN cev.cev_type IN ('INCORPORATION', 'SUBSCRIPTION', 'UNIT_SPLIT', 'UNIT_MERGE') THEN 0 ELSE -cev.cev_cost_moved END AS total_price,
+			0 AS invoice_item,
+			0 AS total_tax,
+			0 AS results
+		FROM corporate_event cev
+		INNER JOIN company cmp ON cev.cmp_id = cmp.cmp_id
+		WHERE cev.usr_id = ?
+			AND cev.cev_market_date <= ?
+		UNION ALL
+		SELECT
+			cev.cev_market_date AS market_date,
+			0 AS item_order,
+			cmp.cmp_id,
+			cmp.cmp_code,
+			cmp.cmp_name,
+			cmp.cmp_bdr,
+			cmp.cmp_etf,
+			1 AS debit,
+			cev.cev_target_qty AS qty,
+			cev.cev_cost_moved + cev.cev_cash - cev.cev_fraction_cost AS total_price,
+			0 AS invoice_item,
+			0 AS total_tax,
+			0 AS results
+		FROM corporate_event cev
+		INNER JOIN company cmp ON cev.cev_target_cmp_id = cmp.cmp_id
+		WHERE cev.usr_id = ?
+			AND cev.cev_market_date <= ?
+			AND (cev.cev_target_qty > 0 OR cev.cev_type = 'UNIT_MERGE')
+		UNION ALL
+		SELECT
+			cev.cev_market_date AS market_date,
+			0 AS item_order,
+			cmp.cmp_id,
+			cmp.cmp_code,
+			cmp.cmp_name,
+			cmp.cmp_bdr,
+			cmp.cmp_etf,
+			0 AS debit,
+			0 AS qty,
+			cev.cev_fraction_cash AS total_price,
+			0 AS invoice_item,
+			0 AS total_tax,
+			cev.cev_fraction_cash - cev.cev_fraction_cost AS results
+		FROM corporate_event cev
+		INNER JOIN company cmp ON cev.cev_target_cmp_id = cmp.cmp_id
+		WHERE cev.usr_id = ?
+			AND cev.cev_market_date <= ?
+			AND (cev.cev_fraction_cash > 0 OR cev.cev_fraction_cost > 0)
+		UNION ALL
+		SELECT
+			tms.tms_date AS market_date,
+			0 AS item_order,
+			cmp.cmp_id,
+			cmp.cmp_code,
+			cmp.cmp_name,
+			cmp.cmp_bdr,
+			cmp.cmp_etf,
+			1 AS debit,
+			tms.tms_qty AS qty,
+			tms.tms_total_cost AS total_price,
+			0 AS invoice_item,
+			0 AS total_tax,
+			0 AS results
+		FROM termo_settlement tms
+		INNER JOIN termo_position tmp ON tms.tmp_id = tmp.tmp_id
+		INNER JOIN company cmp ON tmp.cmp_id = cmp.cmp_id
+		WHERE tmp.usr_id = ?
+			AND tms.tms_date <= ?
+	) evt
+	ORDER BY market_date, item_order`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.Query(
+		dao.user.Id,
+		until,
+		dao.user.Id,
+		until,
+		dao.user.Id,
+		until,
+		dao.user.Id,
+		until,
+		dao.user.Id,
+		until,
+		dao.user.Id,
+		until,
+		dao.user.Id,
+		until,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	events := make([]model.PositionEvent, 0)
+
+	for rows.Next() {
+		var event model.PositionEvent
+		var companyRec entity.Company
+		var bdr []uint8
+		var etf []uint8
+		var debit int
+		var invoiceItem int
+
+		err := rows.Scan(
+			&event.MarketDate,
+			&event.Order,
+			&companyRec.Id,
+			&companyRec.Code,
+			&companyRec.Name,
+			&bdr,
+			&etf,
+			&debit,
+			&event.Qty,
+			&event.TotalPrice,
+			&invoiceItem,
+			&event.TotalTax,
+			&event.Results,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		setCompanyFlags(&companyRec, bdr, etf)
+
+		event.Company = &companyRec
+		event.Debit = (debit == 1)
+		event.InvoiceItem = (invoiceItem == 1)
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	log.Printf(
+		"PositionDAO.GetPositionEvents: found %d events [usr = %d, until = %s]",
+		len(events),
+		dao.user.Id,
+		until.Format(time.RFC3339),
+	)
+
+	return events, nil
+}
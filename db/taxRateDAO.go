@@ -0,0 +1,97 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+)
+
+// mysql ER_NO_SUCH_TABLE
+const errNoSuchTable = 1146
+
+type TaxRateDAO struct {
+	tx      *sql.Tx
+	taxRate *model.TaxRate
+}
+
+func GetTaxRateDAO(tx *sql.Tx, taxRate *model.TaxRate) *TaxRateDAO {
+	return &TaxRateDAO{
+		tx:      tx,
+		taxRate: taxRate,
+	}
+}
+
+func (dao *TaxRateDAO) LoadTaxRates() ([]model.TaxRate, error) {
+	query := `SELECT
+		trt_code,
+		trt_start_date,
+		trt_end_date,
+		trt_rate
+	FROM tax_rate
+	ORDER BY trt_code, trt_start_date`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	var mysqlErr *mysql.MySQLError
+
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errNoSuchTable {
+		log.Print("TaxRateDAO.LoadTaxRates: WARNING: table tax_rate not found, using default rates")
+		return []model.TaxRate{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.Query()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	taxRates := make([]model.TaxRate, 0)
+
+	for rows.Next() {
+		var taxRate model.TaxRate
+		var startDate sql.NullTime
+		var endDate sql.NullTime
+
+		err := rows.Scan(
+			&taxRate.Code,
+			&startDate,
+			&endDate,
+			&taxRate.Rate,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		taxRate.StartDate = getB3Date(startDate)
+		taxRate.EndDate = getB3Date(endDate)
+
+		taxRates = append(taxRates, taxRate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("TaxRateDAO.LoadTaxRates: found %d tax rates", len(taxRates))
+
+	return taxRates, nil
+}
+
+func getB3Date(value sql.NullTime) utils.B3Date {
+	if !value.Valid {
+		return utils.B3Date{}
+	}
+
+	return utils.B3DateOf(value.Time)
+}
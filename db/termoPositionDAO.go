@@ -0,0 +1,237 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type TermoPositionDAO struct {
+	tx            *sql.Tx
+	termoPosition *model.TermoPosition
+}
+
+func GetTermoPositionDAO(tx *sql.Tx, termoPosition *model.TermoPosition) *TermoPositionDAO {
+	return &TermoPositionDAO{
+		tx:            tx,
+		termoPosition: termoPosition,
+	}
+}
+
+func (dao *TermoPositionDAO) getTermoPositions(query string, args ...interface{}) ([]*model.TermoPosition, error) {
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	termoPositionRecs := make([]*model.TermoPosition, 0)
+
+	for rows.Next() {
+		var termoPositionRec model.TermoPosition
+		var company entity.Company
+
+		err := rows.Scan(
+			&termoPositionRec.Id,
+			&termoPositionRec.AgentId,
+			&termoPositionRec.OpenDate,
+			&termoPositionRec.DueDate,
+			&termoPositionRec.Qty,
+			&termoPositionRec.OpenQty,
+			&termoPositionRec.Price,
+			&termoPositionRec.TotalCost,
+			&company.Id,
+			&company.Code,
+			&company.Name,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		termoPositionRec.User = dao.termoPosition.User
+		termoPositionRec.Company = &company
+
+		termoPositionRecs = append(termoPositionRecs, &termoPositionRec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return termoPositionRecs, nil
+}
+
+// GetDueTermoPositions returns the user's open termo positions due until the
+// given date, oldest first.
+func (dao *TermoPositionDAO) GetDueTermoPositions(until time.Time) ([]*model.TermoPosition, error) {
+	query := `SELECT
+		tmp.tmp_id,
+		tmp.tmp_agent_id,
+		tmp.tmp_open_date,
+		tmp.tmp_due_date,
+		tmp.tmp_qty,
+		tmp.tmp_open_qty,
+		tmp.tmp_price,
+		tmp.tmp_total_cost,
+		cmp.cmp_id,
+		cmp.cmp_code,
+		cmp.cmp_name
+	FROM termo_position tmp
+	INNER JOIN company cmp ON tmp.cmp_id = cmp.cmp_id
+	WHERE tmp.usr_id = ?
+	  AND tmp.tmp_open_qty > 0
+	  AND tmp.tmp_due_date <= ?
+	ORDER BY tmp.tmp_due_date, tmp.tmp_id`
+
+	return dao.getTermoPositions(query, dao.termoPosition.User.Id, until)
+}
+
+// GetOpenTermoPositions returns the user's open termo positions on the
+// company, oldest first.
+func (dao *TermoPositionDAO) GetOpenTermoPositions() ([]*model.TermoPosition, error) {
+	query := `SELECT
+		tmp.tmp_id,
+		tmp.tmp_agent_id,
+		tmp.tmp_open_date,
+		tmp.tmp_due_date,
+		tmp.tmp_qty,
+		tmp.tmp_open_qty,
+		tmp.tmp_price,
+		tmp.tmp_total_cost,
+		cmp.cmp_id,
+		cmp.cmp_code,
+		cmp.cmp_name
+	FROM termo_position tmp
+	INNER JOIN company cmp ON tmp.cmp_id = cmp.cmp_id
+	WHERE tmp.usr_id = ?
+	  AND tmp.cmp_id = ?
+	  AND tmp.tmp_open_qty > 0
+	ORDER BY tmp.tmp_open_date, tmp.tmp_id`
+
+	termoPosition := dao.termoPosition
+
+	return dao.getTermoPositions(query, termoPosition.User.Id, termoPosition.Company.Id)
+}
+
+func (dao *TermoPositionDAO) CreateTermoPosition() (*model.TermoPosition, error) {
+	insertStmt := `INSERT INTO termo_position (
+		usr_id,
+		cmp_id,
+		bii_id,
+		tgr_id,
+		tmp_agent_id,
+		tmp_open_date,
+		tmp_due_date,
+		tmp_qty,
+		tmp_open_qty,
+		tmp_price,
+		tmp_total_cost
+	) VALUES (?,?,?,?,?,?,?,?,?,?,?)`
+
+	stmt, err := dao.tx.Prepare(insertStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	termoPosition := dao.termoPosition
+
+	res, err := stmt.Exec(
+		termoPosition.User.Id,
+		termoPosition.Company.Id,
+		termoPosition.Item.Id,
+		termoPosition.TaxGroup.Id,
+		termoPosition.AgentId,
+		termoPosition.OpenDate,
+		termoPosition.DueDate,
+		termoPosition.Qty,
+		termoPosition.OpenQty,
+		termoPosition.Price,
+		termoPosition.TotalCost,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	if err != nil {
+		return nil, err
+	}
+
+	termoPositionRec := *termoPosition
+	termoPositionRec.Id = lastId
+
+	log.Printf(
+		"TermoPositionDAO.CreateTermoPosition: created termo position [%d, %s, %d, due %s]",
+		termoPositionRec.Id,
+		termoPositionRec.Company.Code,
+		termoPositionRec.Qty,
+		termoPositionRec.DueDate.Format("2006-01-02"),
+	)
+
+	return &termoPositionRec, nil
+}
+
+func (dao *TermoPositionDAO) UpdateTermoPosition() error {
+	updateStmt := `UPDATE termo_position SET
+		tmp_open_qty = ?
+	WHERE tmp_id = ?`
+
+	stmt, err := dao.tx.Prepare(updateStmt)
+
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	termoPosition := dao.termoPosition
+
+	res, err := stmt.Exec(
+		termoPosition.OpenQty,
+		termoPosition.Id,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	rowCnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowCnt > 1 {
+		details := fmt.Sprintf("expected 1 row, found %d rows", rowCnt)
+		return utils.GetError("TermoPositionDAO.UpdateTermoPosition", "ERR_DB_001", details)
+	}
+
+	log.Printf(
+		"TermoPositionDAO.UpdateTermoPosition: record updated [%d, %s, open = %d]",
+		termoPosition.Id,
+		termoPosition.Company.Code,
+		termoPosition.OpenQty,
+	)
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type TermoSettlementDAO struct {
+	tx              *sql.Tx
+	termoSettlement *model.TermoSettlement
+}
+
+func GetTermoSettlementDAO(tx *sql.Tx, termoSettlement *model.TermoSettlement) *TermoSettlementDAO {
+	return &TermoSettlementDAO{
+		tx:              tx,
+		termoSettlement: termoSettlement,
+	}
+}
+
+func (dao *TermoSettlementDAO) CreateTermoSettlement() (*model.TermoSettlement, error) {
+	insertStmt := `INSERT INTO termo_settlement (
+		tmp_id,
+		cbt_id,
+		tms_date,
+		tms_qty,
+		tms_total_cost,
+		tms_early
+	) VALUES (?,?,?,?,?,?)`
+
+	stmt, err := dao.tx.Prepare(insertStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	termoSettlement := dao.termoSettlement
+
+	res, err := stmt.Exec(
+		termoSettlement.TermoPosition.Id,
+		termoSettlement.CompanyBatch.Id,
+		termoSettlement.SettlementDate,
+		termoSettlement.Qty,
+		termoSettlement.TotalCost,
+		termoSettlement.Early,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	if err != nil {
+		return nil, err
+	}
+
+	termoSettlementRec := *termoSettlement
+	termoSettlementRec.Id = lastId
+
+	log.Printf(
+		"TermoSettlementDAO.CreateTermoSettlement: created termo settlement [%d, %s, %d]",
+		termoSettlementRec.Id,
+		termoSettlementRec.TermoPosition.Company.Code,
+		termoSettlementRec.Qty,
+	)
+
+	return &termoSettlementRec, nil
+}
@@ -0,0 +1,331 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type TickerChangeDAO struct {
+	tx           *sql.Tx
+	tickerChange *model.TickerChange
+}
+
+func GetTickerChangeDAO(tx *sql.Tx, tickerChange *model.TickerChange) *TickerChangeDAO {
+	return &TickerChangeDAO{
+		tx:           tx,
+		tickerChange: tickerChange,
+	}
+}
+
+func (dao *TickerChangeDAO) LoadTickerChanges() ([]model.TickerChange, error) {
+	query := `SELECT
+		tch_id,
+		tch_old_code,
+		tch_new_code,
+		tch_new_name,
+		tch_effective_date,
+		tch_applied
+	FROM ticker_change
+	ORDER BY tch_effective_date, tch_id`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	var mysqlErr *mysql.MySQLError
+
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errNoSuchTable {
+		log.Print("TickerChangeDAO.LoadTickerChanges: WARNING: table ticker_change not found")
+		return []model.TickerChange{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.Query()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tickerChanges := make([]model.TickerChange, 0)
+
+	for rows.Next() {
+		var tickerChange model.TickerChange
+		var effectiveDate sql.NullTime
+		var applied []uint8
+
+		err := rows.Scan(
+			&tickerChange.Id,
+			&tickerChange.OldCode,
+			&tickerChange.NewCode,
+			&tickerChange.NewName,
+			&effectiveDate,
+			&applied,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tickerChange.EffectiveDate = getB3Date(effectiveDate)
+		tickerChange.Applied = (len(applied) > 0 && applied[0] == 1)
+
+		tickerChanges = append(tickerChanges, tickerChange)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("TickerChangeDAO.LoadTickerChanges: found %d ticker changes", len(tickerChanges))
+
+	return tickerChanges, nil
+}
+
+func (dao *TickerChangeDAO) CreateTickerChange() (*model.TickerChange, error) {
+	insertStmt := `INSERT INTO ticker_change (
+		tch_old_code,
+		tch_new_code,
+		tch_new_name,
+		tch_effective_date,
+		tch_applied
+	) VALUES (?,?,?,?,?)`
+
+	stmt, err := dao.tx.Prepare(insertStmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	tickerChange := dao.tickerChange
+
+	res, err := stmt.Exec(
+		tickerChange.OldCode,
+		tickerChange.NewCode,
+		tickerChange.NewName,
+		tickerChange.EffectiveDate.Time(),
+		tickerChange.Applied,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	tickerChangeRec := *tickerChange
+	tickerChangeRec.Id = lastId
+
+	log.Printf(
+		"TickerChangeDAO.CreateTickerChange: created ticker change [%d, %s -> %s, %s]",
+		tickerChangeRec.Id,
+		tickerChangeRec.OldCode,
+		tickerChangeRec.NewCode,
+		tickerChangeRec.EffectiveDate,
+	)
+
+	return &tickerChangeRec, nil
+}
+
+func (dao *TickerChangeDAO) SetApplied() error {
+	updateStmt := `UPDATE ticker_change SET tch_applied = 1 WHERE tch_id = ?`
+
+	stmt, err := dao.tx.Prepare(updateStmt)
+
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	res, err := stmt.Exec(dao.tickerChange.Id)
+
+	if err != nil {
+		return err
+	}
+
+	rowCnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowCnt != 1 {
+		details := fmt.Sprintf("expected 1 row, found %d rows", rowCnt)
+		return utils.GetError("TickerChangeDAO.SetApplied", "ERR_DB_001", details)
+	}
+
+	return nil
+}
+
+// GetOverlappingBatches returns, per user, the open batch of the old company
+// and the open batch of the new one when the user holds both.
+func (dao *TickerChangeDAO) GetOverlappingBatches(
+	oldCompany *entity.Company,
+	newCompany *entity.Company,
+) ([][2]*entity.CompanyBatch, error) {
+	query := `SELECT
+		usr.usr_id,
+		usr.usr_ext_uuid,
+		usr.usr_name,
+		old.cbt_id,
+		old.cbt_start_date,
+		old.cbt_qty,
+		old.cbt_avg_price,
+		old.cbt_total_price,
+		new.cbt_id,
+		new.cbt_start_date,
+		new.cbt_qty,
+		new.cbt_avg_price,
+		new.cbt_total_price
+	FROM company_batch old
+	INNER JOIN company_batch new ON old.usr_id = new.usr_id
+	INNER JOIN user usr ON old.usr_id = usr.usr_id
+	WHERE old.cmp_id = ?
+		AND new.cmp_id = ?
+		AND old.cbt_qty > 0
+		AND new.cbt_qty > 0`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.Query(oldCompany.Id, newCompany.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	pairs := make([][2]*entity.CompanyBatch, 0)
+
+	for rows.Next() {
+		var userRec entity.User
+		oldBatch := &entity.CompanyBatch{User: &userRec, Company: oldCompany}
+		newBatch := &entity.CompanyBatch{User: &userRec, Company: newCompany}
+
+		err := rows.Scan(
+			&userRec.Id,
+			&userRec.ExternalUUID,
+			&userRec.UserName,
+			&oldBatch.Id,
+			&oldBatch.StartDate,
+			&oldBatch.Qty,
+			&oldBatch.AvgPrice,
+			&oldBatch.TotalPrice,
+			&newBatch.Id,
+			&newBatch.StartDate,
+			&newBatch.Qty,
+			&newBatch.AvgPrice,
+			&newBatch.TotalPrice,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		pairs = append(pairs, [2]*entity.CompanyBatch{oldBatch, newBatch})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return pairs, nil
+}
+
+// MoveCompanyReferences points open batches, broker batches, invoice items,
+// custody transfers and corporate events of the old company to the new one;
+// closed batches stay on the old company. Empty broker batches of either
+// company on an agent both have are deleted first, the unique key allows one
+// row per user, company and agent.
+func (dao *TickerChangeDAO) MoveCompanyReferences(
+	oldCompany *entity.Company,
+	newCompany *entity.Company,
+) error {
+	deleteStmts := []string{
+		`DELETE cbb FROM company_broker_batch cbb
+		INNER JOIN company_broker_batch old ON old.usr_id = cbb.usr_id
+			AND old.cbb_agent_id = cbb.cbb_agent_id
+		WHERE old.cmp_id = ?
+			AND cbb.cmp_id = ?
+			AND cbb.cbb_qty = 0`,
+		`DELETE cbb FROM company_broker_batch cbb
+		INNER JOIN company_broker_batch new ON new.usr_id = cbb.usr_id
+			AND new.cbb_agent_id = cbb.cbb_agent_id
+		WHERE cbb.cmp_id = ?
+			AND new.cmp_id = ?
+			AND cbb.cbb_qty = 0`,
+	}
+
+	for _, deleteStmt := range deleteStmts {
+		res, err := dao.tx.Exec(deleteStmt, oldCompany.Id, newCompany.Id)
+
+		if err != nil {
+			return err
+		}
+
+		rowCnt, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		log.Printf(
+			"TickerChangeDAO.MoveCompanyReferences: [%s -> %s] %d rows deleted: %s",
+			oldCompany.Code,
+			newCompany.Code,
+			rowCnt,
+			deleteStmt,
+		)
+	}
+
+	updateStmts := []string{
+		`UPDATE company_batch SET cmp_id = ? WHERE cmp_id = ? AND cbt_qty > 0`,
+		`UPDATE company_broker_batch SET cmp_id = ? WHERE cmp_id = ?`,
+		`UPDATE broker_invoice_item SET cmp_id = ? WHERE cmp_id = ?`,
+		`UPDATE custody_transfer SET cmp_id = ? WHERE cmp_id = ?`,
+		`UPDATE corporate_event SET cmp_id = ? WHERE cmp_id = ?`,
+		`UPDATE corporate_event SET cev_target_cmp_id = ? WHERE cev_target_cmp_id = ?`,
+	}
+
+	for _, updateStmt := range updateStmts {
+		res, err := dao.tx.Exec(updateStmt, newCompany.Id, oldCompany.Id)
+
+		if err != nil {
+			return err
+		}
+
+		rowCnt, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		log.Printf(
+			"TickerChangeDAO.MoveCompanyReferences: [%s -> %s] %d rows: %s",
+			oldCompany.Code,
+			newCompany.Code,
+			rowCnt,
+			updateStmt,
+		)
+	}
+
+	return nil
+}
@@ -22,6 +22,9 @@ func GetTradeBatchDAO(tx *sql.Tx, tradeBatch *entity.TradeBatch) *TradeBatchDAO
 	}
 }
 
+// GetTradeBatch the trade batch of the user on the month of StartDate, matched
+// by year and month since batches stored before dates moved to the B3
+// location may not start at B3 midnight.
 func (dao *TradeBatchDAO) GetTradeBatch() (*entity.TradeBatch, error) {
 	query := `SELECT
 		trb_id,
@@ -38,7 +41,8 @@ func (dao *TradeBatchDAO) GetTradeBatch() (*entity.TradeBatch, error) {
 		trb_total_etf_tax
 	FROM trade_batch
 	WHERE usr_id = ?
-	  AND trb_start_date = ?`
+	  AND YEAR(trb_start_date) = ?
+	  AND MONTH(trb_start_date) = ?`
 
 	stmt, err := dao.tx.Prepare(query)
 
@@ -55,9 +59,12 @@ func (dao *TradeBatchDAO) GetTradeBatch() (*entity.TradeBatch, error) {
 	var bdrData entity.TradeBatchData
 	var etfData entity.TradeBatchData
 
+	startDate := utils.B3DateOf(tradeBatch.StartDate)
+
 	err = stmt.QueryRow(
 		tradeBatch.User.Id,
-		tradeBatch.StartDate.Format(time.RFC3339),
+		startDate.Year(),
+		int(startDate.Month()),
 	).Scan(
 		&tradeBatchRec.Id,
 		&taxGroupId,
@@ -87,7 +94,7 @@ func (dao *TradeBatchDAO) GetTradeBatch() (*entity.TradeBatch, error) {
 	log.Printf(
 		"TradeBatchDAO.GetTradeBatch: found trade batch [%d, %s]",
 		tradeBatchRec.Id,
-		tradeBatch.StartDate.Format("2006-01-01"),
+		tradeBatch.StartDate.Format("2006-01-02"),
 	)
 
 	taxGroup := entity.TaxGroup{
@@ -197,6 +204,122 @@ func (dao *TradeBatchDAO) GetLastTradeBatch() (*entity.TradeBatch, error) {
 	return &tradeBatchRec, nil
 }
 
+func (dao *TradeBatchDAO) GetTradeBatchesByPeriod(from time.Time, to time.Time) ([]*entity.TradeBatch, error) {
+	query := `SELECT
+		trb_id,
+		tgr_id,
+		trb_start_date,
+		trb_shr_loss,
+		trb_shr_results,
+		trb_total_shr_tax,
+		trb_total_shr_trade,
+		trb_bdr_loss,
+		trb_bdr_results,
+		trb_total_bdr_tax,
+		trb_etf_loss,
+		trb_etf_results,
+		trb_total_etf_tax
+	FROM trade_batch
+	WHERE usr_id = ?
+	  AND trb_start_date >= ?
+	  AND trb_start_date < ?
+	ORDER BY trb_start_date`
+
+	stmt, err := dao.tx.Prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	tradeBatch := dao.tradeBatch
+
+	rows, err := stmt.Query(
+		tradeBatch.User.Id,
+		from,
+		to,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tradeBatchRecs := make([]*entity.TradeBatch, 0, 12)
+	taxGroupIds := make([]int64, 0, 12)
+
+	for rows.Next() {
+		var taxGroupId int64
+		var tradeBatchRec entity.TradeBatch
+		var shrData entity.TradeBatchData
+		var bdrData entity.TradeBatchData
+		var etfData entity.TradeBatchData
+
+		err := rows.Scan(
+			&tradeBatchRec.Id,
+			&taxGroupId,
+			&tradeBatchRec.StartDate,
+			&shrData.AccLoss,
+			&shrData.Results,
+			&shrData.TotalTax,
+			&shrData.TotalTrade,
+			&bdrData.AccLoss,
+			&bdrData.Results,
+			&bdrData.TotalTax,
+			&etfData.AccLoss,
+			&etfData.Results,
+			&etfData.TotalTax,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tradeBatchRec.User = tradeBatch.User
+		tradeBatchRec.Shr = &shrData
+		tradeBatchRec.Bdr = &bdrData
+		tradeBatchRec.Etf = &etfData
+
+		tradeBatchRecs = append(tradeBatchRecs, &tradeBatchRec)
+		taxGroupIds = append(taxGroupIds, taxGroupId)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows.Close()
+
+	// tax groups are loaded after the cursor is closed, the tx connection
+	// can't run a second query while rows are still pending
+	for idx, tradeBatchRec := range tradeBatchRecs {
+		taxGroup := entity.TaxGroup{
+			Id: taxGroupIds[idx],
+		}
+
+		taxGroupDAO := GetTaxGroupDAO(dao.tx, &taxGroup)
+		taxGroupRec, err := taxGroupDAO.GetTaxGroup()
+
+		if err != nil {
+			return nil, err
+		}
+
+		tradeBatchRec.TaxGroup = taxGroupRec
+	}
+
+	log.Printf(
+		"TradeBatchDAO.GetTradeBatchesByPeriod: found %d trade batches [usr = %d, %s, %s]",
+		len(tradeBatchRecs),
+		tradeBatch.User.Id,
+		from.Format(time.RFC3339),
+		to.Format(time.RFC3339),
+	)
+
+	return tradeBatchRecs, nil
+}
+
 func (dao *TradeBatchDAO) CreateTradeBatch() (*entity.TradeBatch, error) {
 	insertStmt := `INSERT INTO trade_batch (
 		tgr_id,
@@ -44,10 +44,17 @@ func (dao *TradeDAO) CreateTrade() (*entity.Trade, error) {
 
 	trade := dao.trade
 
+	// trades not backed by an invoice item (corporate event fractions)
+	var itemId sql.NullInt64
+
+	if trade.Item.Id != 0 {
+		itemId = sql.NullInt64{Int64: trade.Item.Id, Valid: true}
+	}
+
 	res, err := stmt.Exec(
 		trade.CompanyBatch.Id,
 		trade.TradeBatch.Id,
-		trade.Item.Id,
+		itemId,
 		trade.TaxGroup.Id,
 		trade.MarketDate,
 		trade.Qty,
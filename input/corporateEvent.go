@@ -0,0 +1,27 @@
+package input
+
+// CorporateEventItem each FromQty shares of Company give ToQty shares of
+// Target. Incorporations move the whole cost basis, spin-offs move CostRate
+// of it; fractions of Target are paid at FractionPrice per share. Rights
+// grants give Target rights at zero cost, subscriptions exercise Qty rights
+// (all when zero) paying Price per Target share. Unit splits convert Qty
+// units of Company (all when zero) into the shares they bundle, unit merges
+// build Qty units of Target (as many as possible when zero) from its shares.
+type CorporateEventItem struct {
+	Type          string  `json:"type"`
+	Date          string  `json:"date"`
+	Company       Company `json:"company"`
+	Target        Company `json:"target"`
+	FromQty       int64   `json:"fromQty"`
+	ToQty         int64   `json:"toQty"`
+	CostRate      float64 `json:"costRate"`
+	FractionPrice float64 `json:"fractionPrice"`
+	Qty           int64   `json:"qty"`
+	Price         float64 `json:"price"`
+}
+
+type CorporateEvents struct {
+	FileName string               `json:"filename"`
+	Client   Client               `json:"client"`
+	Events   []CorporateEventItem `json:"events"`
+}
@@ -0,0 +1,18 @@
+package input
+
+type CustodyItem struct {
+	Company      Company `json:"company"`
+	Qty          int64   `json:"qty"`
+	TotalCost    float64 `json:"totalCost"`
+	Date         string  `json:"date"`
+	OriginBroker string  `json:"originBroker"`
+	AgentId      string  `json:"agentId"`
+}
+
+// CustodyTransfer brings opening balances or positions moved from another
+// broker, no invoice backs these items.
+type CustodyTransfer struct {
+	FileName string        `json:"filename"`
+	Client   Client        `json:"client"`
+	Items    []CustodyItem `json:"items"`
+}
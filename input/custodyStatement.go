@@ -0,0 +1,14 @@
+package input
+
+// CustodyStatementItem one row of B3's investor portal position export (Área
+// do Investidor, Posição), the custody of a ticker at one institution.
+type CustodyStatementItem struct {
+	Code        string
+	Institution string
+	Qty         int64
+}
+
+type CustodyStatement struct {
+	FileName string
+	Items    []CustodyStatementItem
+}
@@ -0,0 +1,29 @@
+package input
+
+// FuturesItem one line of a BM&F note, Adjustment is the daily settlement
+// (ajuste) of the line, negative when debited; DayTrade lines were opened and
+// closed on the note market date.
+type FuturesItem struct {
+	Code       string  `json:"code"`
+	Qty        int64   `json:"qty"`
+	Price      float64 `json:"price"`
+	Debit      bool    `json:"debit"`
+	DayTrade   bool    `json:"dayTrade"`
+	Adjustment float64 `json:"adjustment"`
+}
+
+// FuturesNote BM&F notes (mini index and mini dollar) settle the net ajuste
+// on the next trading day, Taxes are the note fees as printed (BRKFEE,
+// REGFEE, EMLFEE, ISSSPFEE and the day trade IRRFFEE).
+type FuturesNote struct {
+	NoteNum     int64         `json:"noteNum"`
+	FileName    string        `json:"filename"`
+	MarketDate  string        `json:"marketDate"`
+	BillingDate string        `json:"billingDate"`
+	AgentId     string        `json:"agentId"`
+	Adjustment  float64       `json:"adjustment"`
+	NetValue    float64       `json:"netValue"`
+	Client      Client        `json:"client"`
+	Items       []FuturesItem `json:"items"`
+	Taxes       []Tax         `json:"taxes"`
+}
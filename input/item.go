@@ -1,9 +1,15 @@
 package input
 
+// Item Market overrides the invoice market for option premiums, exercises
+// and termo purchases, Strike is the option strike printed on the note and
+// DueDate the termo settlement date.
 type Item struct {
 	Company Company `json:"company"`
 	Qty     int64   `json:"qty"`
 	Price   float64 `json:"price"`
 	Debit   bool    `json:"debit"`
 	Order   int64   `json:"order"`
+	Market  string  `json:"market"`
+	Strike  float64 `json:"strike"`
+	DueDate string  `json:"dueDate"`
 }
@@ -0,0 +1,25 @@
+package input
+
+// LendingContract a stock lending (BTC) contract of the client, Side is one
+// of constants.LendingSides. Lenders receive Value (gross rental income) with
+// Irrf withheld, borrowers pay Value as the lending fee; Fees are the broker
+// and B3 costs charged on the contract.
+type LendingContract struct {
+	Contract  string  `json:"contract"`
+	Side      string  `json:"side"`
+	Company   Company `json:"company"`
+	AgentId   string  `json:"agentId"`
+	Qty       int64   `json:"qty"`
+	StartDate string  `json:"startDate"`
+	EndDate   string  `json:"endDate"`
+	Rate      float64 `json:"rate"`
+	Value     float64 `json:"value"`
+	Irrf      float64 `json:"irrf"`
+	Fees      float64 `json:"fees"`
+}
+
+type LendingContracts struct {
+	FileName  string            `json:"filename"`
+	Client    Client            `json:"client"`
+	Contracts []LendingContract `json:"contracts"`
+}
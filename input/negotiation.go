@@ -0,0 +1,22 @@
+package input
+
+// Negotiation one trade of B3's investor portal negotiation export (Área do
+// Investidor, Negociação), Market and Institution are kept as exported, dates
+// as yyyy-mm-dd.
+type Negotiation struct {
+	Date        string
+	Debit       bool
+	Market      string
+	DueDate     string
+	Institution string
+	Code        string
+	Qty         int64
+	Price       float64
+	Value       float64
+}
+
+type Negotiations struct {
+	FileName string
+	Client   Client
+	Items    []Negotiation
+}
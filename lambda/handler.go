@@ -29,12 +29,14 @@ func Handler(ctx context.Context, req Request) (bool, error) {
 
 	log.Printf("lambda.Handler: Handling file %s", req.Filename)
 
-	invoiceInput, err := reader.S3FileReader(req.Filename)
+	invoiceInputs, err := reader.S3FileReader(req.Filename)
 	if err != nil {
 		return false, err
 	}
 
-	log.Printf("lambda.Handler: Skipping file: %s", invoiceInput.FileName)
+	for _, invoiceInput := range invoiceInputs {
+		log.Printf("lambda.Handler: Skipping file: %s", invoiceInput.FileName)
+	}
 
 	return false, nil
 }
@@ -0,0 +1,112 @@
+package local
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/reader"
+	"github.com/jarismar/b3c-invoice-reader-lambda/report"
+)
+
+// BatchHandler handles `<directory>` or `s3://<invoice/userID/yyyy_mm/>`, the
+// invoice files found (yyyy_mm_dd_NNNNNNNNN.json) are processed sequentially
+// by market date and invoice number, files and invoices already on the DB are
+// skipped; a summary of the run is printed at the end.
+func BatchHandler(source string) (bool, error) {
+	log.Printf("local.BatchHandler: processing %s", source)
+
+	var fileNames []string
+	var err error
+
+	readFile := reader.LocalFileReader
+
+	if strings.HasPrefix(source, "s3://") {
+		fileNames, err = reader.S3InvoiceFiles(strings.TrimPrefix(source, "s3://"))
+		readFile = reader.S3FileReader
+	} else {
+		fileNames, err = reader.LocalInvoiceFiles(source)
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	invoiceProcessor, err := getInvoiceProcessor()
+	if err != nil {
+		return false, err
+	}
+
+	defer invoiceProcessor.Close()
+
+	ingestion := &model.Ingestion{
+		Source:  source,
+		Results: make([]*model.IngestionResult, 0, len(fileNames)),
+	}
+
+	invoiceInputs := make([]*input.Invoice, 0, len(fileNames))
+
+	for _, fileName := range fileNames {
+		baseName := path.Base(fileName)
+		isStored, err := invoiceProcessor.isStoredFile(baseName)
+
+		if err != nil {
+			return false, err
+		}
+
+		if isStored {
+			ingestion.Results = append(ingestion.Results, &model.IngestionResult{
+				FileName: baseName,
+				Status:   constants.IngestionStatuses.SKIPPED,
+			})
+			continue
+		}
+
+		fileInvoices, err := readFile(fileName)
+
+		if err != nil {
+			ingestion.Results = append(ingestion.Results, &model.IngestionResult{
+				FileName: baseName,
+				Status:   constants.IngestionStatuses.FAILED,
+				Err:      err,
+			})
+			continue
+		}
+
+		invoiceInputs = append(invoiceInputs, fileInvoices...)
+	}
+
+	// files holding a list of invoices may interleave with the others
+	reader.SortInvoices(invoiceInputs)
+
+	results, err := invoiceProcessor.processInvoices(invoiceInputs)
+	ingestion.Results = append(ingestion.Results, results...)
+
+	report := report.GetIngestionReport(ingestion)
+	report.Run()
+
+	if err != nil {
+		return false, err
+	}
+
+	failed := 0
+
+	for _, result := range ingestion.Results {
+		if result.Status == constants.IngestionStatuses.FAILED {
+			failed++
+		}
+	}
+
+	log.Printf("local.BatchHandler: done processing %s, %d files, %d failed", source, len(fileNames), failed)
+
+	if failed > 0 {
+		err = fmt.Errorf("local.BatchHandler: error: %d of %d invoices failed", failed, len(ingestion.Results))
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,94 @@
+package local
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/reader"
+	"github.com/jarismar/b3c-invoice-reader-lambda/report"
+	"github.com/jarismar/b3c-invoice-reader-lambda/service"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+)
+
+// CorporateEventHandler handles `event <event_yyyy_mm_dd_name.json>`
+func CorporateEventHandler(args []string) (bool, error) {
+	if len(args) != 1 {
+		err := fmt.Errorf("local.CorporateEventHandler: error: usage event <file>")
+		return false, err
+	}
+
+	fileNameStr := args[0]
+	log.Printf("local.CorporateEventHandler: processing file %s", fileNameStr)
+
+	eventInput, err := reader.CorporateEventFileReader(fileNameStr)
+	if err != nil {
+		return false, err
+	}
+
+	tickerStore, err := getTickerStore()
+	if err != nil {
+		return false, err
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		return false, err
+	}
+
+	defer conn.Close()
+
+	if err = applyTickerChanges(conn, tickerStore); err != nil {
+		return false, err
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	companyStore := store.GetCompanyStore()
+
+	companyAliasStore, err := service.LoadCompanyAliases(tx, store.GetCompanyAliasStore())
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	taxRateService := service.GetTaxRateService(tx, store.GetTaxRateStore())
+	taxRateStore, err := taxRateService.LoadTaxRates()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	corporateEventService := service.GetCorporateEventService(
+		tx,
+		eventInput,
+		store.GetTaxStore(),
+		companyStore,
+		store.GetCompanyBatchStore(),
+		taxRateStore,
+		store.GetBrokerBatchStore(),
+		tickerStore,
+		companyAliasStore,
+		store.GetFiiTradeBatchStore(),
+		store.GetOptionTradeBatchStore(),
+	)
+
+	corporateEvents, err := corporateEventService.ProcessCorporateEvents()
+
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	corporateEventReport := report.GetCorporateEventReport(corporateEvents)
+	corporateEventReport.Run()
+
+	tx.Commit()
+
+	log.Printf("local.CorporateEventHandler: done processing file: %s", fileNameStr)
+
+	return true, nil
+}
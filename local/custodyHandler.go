@@ -0,0 +1,83 @@
+package local
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/reader"
+	"github.com/jarismar/b3c-invoice-reader-lambda/report"
+	"github.com/jarismar/b3c-invoice-reader-lambda/service"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+)
+
+// CustodyHandler handles `custody <custody_yyyy_mm_dd_name.json>`
+func CustodyHandler(args []string) (bool, error) {
+	if len(args) != 1 {
+		err := fmt.Errorf("local.CustodyHandler: error: usage custody <file>")
+		return false, err
+	}
+
+	fileNameStr := args[0]
+	log.Printf("local.CustodyHandler: processing file %s", fileNameStr)
+
+	custodyInput, err := reader.CustodyFileReader(fileNameStr)
+	if err != nil {
+		return false, err
+	}
+
+	tickerStore, err := getTickerStore()
+	if err != nil {
+		return false, err
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		return false, err
+	}
+
+	defer conn.Close()
+
+	if err = applyTickerChanges(conn, tickerStore); err != nil {
+		return false, err
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	companyStore := store.GetCompanyStore()
+
+	companyAliasStore, err := service.LoadCompanyAliases(tx, store.GetCompanyAliasStore())
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	custodyService := service.GetCustodyService(
+		tx,
+		custodyInput,
+		companyStore,
+		store.GetCompanyBatchStore(),
+		store.GetBrokerBatchStore(),
+		tickerStore,
+		companyAliasStore,
+	)
+
+	custodyTransfers, err := custodyService.ProcessCustodyTransfer()
+
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	custodyReport := report.GetCustodyReport(custodyTransfers)
+	custodyReport.Run()
+
+	tx.Commit()
+
+	log.Printf("local.CustodyHandler: done processing file: %s", fileNameStr)
+
+	return true, nil
+}
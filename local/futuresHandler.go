@@ -0,0 +1,77 @@
+package local
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/calendar"
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/reader"
+	"github.com/jarismar/b3c-invoice-reader-lambda/report"
+	"github.com/jarismar/b3c-invoice-reader-lambda/service"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+)
+
+// FuturesHandler handles `futures <futures_yyyy_mm_dd_name.json>`
+func FuturesHandler(args []string) (bool, error) {
+	if len(args) != 1 {
+		err := fmt.Errorf("local.FuturesHandler: error: usage futures <file>")
+		return false, err
+	}
+
+	fileNameStr := args[0]
+	log.Printf("local.FuturesHandler: processing file %s", fileNameStr)
+
+	noteInput, err := reader.FuturesFileReader(fileNameStr)
+	if err != nil {
+		return false, err
+	}
+
+	b3Calendar, err := calendar.GetB3Calendar()
+	if err != nil {
+		return false, err
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		return false, err
+	}
+
+	defer conn.Close()
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	taxRateService := service.GetTaxRateService(tx, store.GetTaxRateStore())
+	taxRateStore, err := taxRateService.LoadTaxRates()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	futuresService := service.GetFuturesService(
+		tx,
+		noteInput,
+		store.GetTaxStore(),
+		taxRateStore,
+		b3Calendar,
+	)
+
+	futuresNote, err := futuresService.ProcessFuturesNote()
+
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	futuresReport := report.GetFuturesReport(futuresNote)
+	futuresReport.Run()
+
+	tx.Commit()
+
+	log.Printf("local.FuturesHandler: done processing file: %s", fileNameStr)
+
+	return true, nil
+}
@@ -1,70 +1,162 @@
 package local
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
-	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
 	"github.com/jarismar/b3c-invoice-reader-lambda/reader"
-	"github.com/jarismar/b3c-invoice-reader-lambda/report"
 	"github.com/jarismar/b3c-invoice-reader-lambda/service"
 	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
 )
 
+// getTickerStore loads the ticker registry set on B3_TICKER_FILE, without it
+// companies are classified from their ticker suffix.
+func getTickerStore() (*store.TickerStore, error) {
+	tickerStore := store.GetTickerStore()
+	tickerFileName, ok := os.LookupEnv("B3_TICKER_FILE")
+
+	if ok && tickerFileName != "" {
+		if err := tickerStore.LoadFile(tickerFileName); err != nil {
+			return nil, err
+		}
+	}
+
+	return tickerStore, nil
+}
+
+// applyTickerChanges applies the pending ticker changes effective today on
+// their own transaction, before the input that needs the successors is
+// processed.
+func applyTickerChanges(conn *sql.DB, tickerStore *store.TickerStore) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	tickerChangeService := service.GetTickerChangeService(tx, tickerStore, store.GetCompanyStore())
+	if _, err = tickerChangeService.ApplyTickerChanges(utils.B3Today()); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// getInvoiceFileReader picks the reader of an invoice file, text files are
+// extracted SINACOR broker notes.
+func getInvoiceFileReader(fileName string) func(string) ([]*input.Invoice, error) {
+	if strings.HasSuffix(strings.ToLower(fileName), ".txt") {
+		return reader.SinacorFileReader
+	}
+
+	return reader.LocalFileReader
+}
+
 func Handler() (bool, error) {
+	if len(os.Args) > 1 && os.Args[1] == "irpf" {
+		return IrpfHandler(os.Args[2:])
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "custody" {
+		return CustodyHandler(os.Args[2:])
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "event" {
+		return CorporateEventHandler(os.Args[2:])
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "ticker-change" {
+		return TickerChangeHandler(os.Args[2:])
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "futures" {
+		return FuturesHandler(os.Args[2:])
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "termo" {
+		return TermoHandler(os.Args[2:])
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "lending" {
+		return LendingHandler(os.Args[2:])
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "negotiation" {
+		return NegotiationHandler(os.Args[2:])
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		return ReconcileHandler(os.Args[2:])
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "watch" {
+		return WatchHandler(os.Args[2:])
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "positions" {
+		return PositionHandler(os.Args[2:])
+	}
+
 	if len(os.Args) != 2 {
-		err := fmt.Errorf("local.Handler: error: missing filename on arg1 <invoice>")
+		err := fmt.Errorf("local.Handler: error: missing filename on arg1 <invoice|directory|s3://prefix>")
 		return false, err
 	}
 
 	fileNameStr := os.Args[1]
+
+	if strings.HasPrefix(fileNameStr, "s3://") {
+		return BatchHandler(fileNameStr)
+	}
+
+	if fileInfo, err := os.Stat(fileNameStr); err == nil && fileInfo.IsDir() {
+		return BatchHandler(fileNameStr)
+	}
+
 	log.Printf("local.Hander: processing file %s", fileNameStr)
 
-	invoiceInput, err := reader.LocalFileReader(fileNameStr)
+	readFile := getInvoiceFileReader(fileNameStr)
+
+	invoiceInputs, err := readFile(fileNameStr)
 	if err != nil {
 		return false, err
 	}
 
-	log.Print("local.Handler: going to process invoice: ", invoiceInput.FileName)
-
-	conn, err := db.GetConnection()
+	invoiceProcessor, err := getInvoiceProcessor()
 	if err != nil {
 		return false, err
 	}
 
-	defer conn.Close()
+	defer invoiceProcessor.Close()
 
-	tx, err := conn.Begin()
+	results, err := invoiceProcessor.processInvoices(invoiceInputs)
 	if err != nil {
 		return false, err
 	}
 
-	invoiceService := service.GetInvoiceService(
-		tx,
-		invoiceInput,
-		store.GetTaxStore(),
-		store.GetCompanyStore(),
-		store.GetCompanyBatchStore(),
-		store.GetBrokerTaxStore(),
-	)
+	failed := 0
 
-	invoiceRec, err := invoiceService.ProcessInvoice()
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
 
-	// TODO self checking
+	log.Printf(
+		"local.Handler: done processing file: %s, %d invoices, %d failed",
+		fileNameStr,
+		len(results),
+		failed,
+	)
 
-	if err != nil {
-		tx.Rollback()
+	if failed > 0 {
+		err = fmt.Errorf("local.Handler: error: %d of %d invoices failed", failed, len(results))
 		return false, err
 	}
 
-	report := report.GetConsoleReport(invoiceRec)
-	report.Run()
-
-	tx.Commit()
-	// tx.Rollback()
-
-	log.Printf("local.Handler: done processing file: %s", fileNameStr)
-
 	return true, nil
 }
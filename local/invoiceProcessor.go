@@ -0,0 +1,341 @@
+package local
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/calendar"
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/report"
+	"github.com/jarismar/b3c-invoice-reader-lambda/service"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// invoiceStores the stores shared by the invoices of one transaction, they
+// cache DB rows so a rolled back transaction must not hand them on.
+type invoiceStores struct {
+	taxStore              *store.TaxStore
+	companyStore          *store.CompanyStore
+	companyBatchStore     *store.CompanyBatchStore
+	taxRateStore          *store.TaxRateStore
+	brokerStore           *store.BrokerStore
+	brokerBatchStore      *store.BrokerBatchStore
+	companyAliasStore     *store.CompanyAliasStore
+	fiiTradeBatchStore    *store.FiiTradeBatchStore
+	optionTradeBatchStore *store.OptionTradeBatchStore
+}
+
+// invoiceProcessor processes the invoices read from one file in the given
+// order, each on its own transaction or, with B3_INVOICE_TRANSACTION=FILE,
+// all of them on a single one.
+type invoiceProcessor struct {
+	conn         *sql.DB
+	b3Calendar   *calendar.B3Calendar
+	tickerStore  *store.TickerStore
+	allOrNothing bool
+	stop         <-chan struct{}
+}
+
+func getInvoiceProcessor() (*invoiceProcessor, error) {
+	b3Calendar, err := calendar.GetB3Calendar()
+	if err != nil {
+		return nil, err
+	}
+
+	tickerStore, err := getTickerStore()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	transactionMode, _ := os.LookupEnv("B3_INVOICE_TRANSACTION")
+
+	return &invoiceProcessor{
+		conn:         conn,
+		b3Calendar:   b3Calendar,
+		tickerStore:  tickerStore,
+		allOrNothing: strings.ToUpper(transactionMode) == "FILE",
+	}, nil
+}
+
+// setStop stops processing before the next transaction once stop is closed,
+// the invoices left are missing from the results.
+func (iproc *invoiceProcessor) setStop(stop <-chan struct{}) {
+	iproc.stop = stop
+}
+
+func (iproc *invoiceProcessor) isStopped() bool {
+	select {
+	case <-iproc.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (iproc *invoiceProcessor) Close() {
+	iproc.conn.Close()
+}
+
+// isStoredFile tells whether an invoice file is already on broker_invoice, a
+// listed file found there is skipped without being read.
+func (iproc *invoiceProcessor) isStoredFile(fileName string) (bool, error) {
+	tx, err := iproc.conn.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	invoiceDAO := db.GetInvoiceDAO(tx, &entity.Invoice{FileName: fileName}, "")
+	isNew, err := invoiceDAO.IsNewInvoice()
+	if err != nil {
+		return false, err
+	}
+
+	return !isNew, nil
+}
+
+// getInvoiceStores loads the aliases, tax rates and broker registry for a new
+// transaction.
+func (iproc *invoiceProcessor) getInvoiceStores(tx *sql.Tx) (*invoiceStores, error) {
+	companyAliasStore, err := service.LoadCompanyAliases(tx, store.GetCompanyAliasStore())
+	if err != nil {
+		return nil, err
+	}
+
+	taxRateService := service.GetTaxRateService(tx, store.GetTaxRateStore())
+	taxRateStore, err := taxRateService.LoadTaxRates()
+	if err != nil {
+		return nil, err
+	}
+
+	brokerStore := store.GetBrokerStore()
+	brokerFileName, ok := os.LookupEnv("B3_BROKER_FILE")
+	if ok && brokerFileName != "" {
+		if err = brokerStore.LoadFile(brokerFileName); err != nil {
+			return nil, err
+		}
+	}
+
+	return &invoiceStores{
+		taxStore:              store.GetTaxStore(),
+		companyStore:          store.GetCompanyStore(),
+		companyBatchStore:     store.GetCompanyBatchStore(),
+		taxRateStore:          taxRateStore,
+		brokerStore:           brokerStore,
+		brokerBatchStore:      store.GetBrokerBatchStore(),
+		companyAliasStore:     companyAliasStore,
+		fiiTradeBatchStore:    store.GetFiiTradeBatchStore(),
+		optionTradeBatchStore: store.GetOptionTradeBatchStore(),
+	}, nil
+}
+
+// processInvoice processes one invoice and returns its report, to be printed
+// once the transaction commits; invoices already on the DB are skipped with
+// no report.
+func (iproc *invoiceProcessor) processInvoice(
+	tx *sql.Tx,
+	invoiceInput *input.Invoice,
+	stores *invoiceStores,
+) (*report.ConsoleReport, error) {
+	invoiceDAO := db.GetInvoiceDAO(tx, &entity.Invoice{FileName: invoiceInput.FileName}, invoiceInput.AgentId)
+	isNew, err := invoiceDAO.IsNewInvoice()
+	if err != nil {
+		return nil, err
+	}
+
+	if !isNew {
+		log.Print("local.invoiceProcessor: invoice already on DB, skipping: ", invoiceInput.FileName)
+		return nil, nil
+	}
+
+	log.Print("local.invoiceProcessor: going to process invoice: ", invoiceInput.FileName)
+
+	// per company brokerage is charged once per invoice
+	invoiceService := service.GetInvoiceService(
+		tx,
+		invoiceInput,
+		stores.taxStore,
+		stores.companyStore,
+		stores.companyBatchStore,
+		store.GetBrokerTaxStore(),
+		iproc.b3Calendar,
+		stores.taxRateStore,
+		stores.brokerStore,
+		stores.brokerBatchStore,
+		iproc.tickerStore,
+		stores.companyAliasStore,
+		stores.fiiTradeBatchStore,
+		stores.optionTradeBatchStore,
+	)
+
+	invoiceRec, err := invoiceService.ProcessInvoice()
+	if err != nil {
+		return nil, err
+	}
+
+	return report.GetConsoleReport(invoiceRec, stores.fiiTradeBatchStore, stores.optionTradeBatchStore), nil
+}
+
+func getIngestionStatus(processed bool) string {
+	if processed {
+		return constants.IngestionStatuses.PROCESSED
+	}
+
+	return constants.IngestionStatuses.SKIPPED
+}
+
+// getNotProcessed returns the results of the invoices left after a failed
+// one, later invoices may depend on the position it would have changed.
+func getNotProcessed(invoiceInputs []*input.Invoice, failed *input.Invoice) []*model.IngestionResult {
+	results := make([]*model.IngestionResult, 0, len(invoiceInputs))
+
+	for _, invoiceInput := range invoiceInputs {
+		results = append(results, &model.IngestionResult{
+			FileName: invoiceInput.FileName,
+			Status:   constants.IngestionStatuses.NOT_PROCESSED,
+			Err:      fmt.Errorf("not processed, invoice %s failed", failed.FileName),
+		})
+	}
+
+	return results
+}
+
+// processOnOwnTransaction commits each invoice apart, the first failed invoice
+// is rolled back and stops the run, the invoices after it are not processed.
+func (iproc *invoiceProcessor) processOnOwnTransaction(invoiceInputs []*input.Invoice) ([]*model.IngestionResult, error) {
+	results := make([]*model.IngestionResult, 0, len(invoiceInputs))
+
+	for idx, invoiceInput := range invoiceInputs {
+		if iproc.isStopped() {
+			log.Printf("local.invoiceProcessor: stopped, %d invoices left", len(invoiceInputs)-len(results))
+			break
+		}
+
+		tx, err := iproc.conn.Begin()
+		if err != nil {
+			return results, err
+		}
+
+		result := &model.IngestionResult{FileName: invoiceInput.FileName}
+		results = append(results, result)
+
+		stores, err := iproc.getInvoiceStores(tx)
+		if err != nil {
+			tx.Rollback()
+			result.Status = constants.IngestionStatuses.FAILED
+			result.Err = err
+			return results, err
+		}
+
+		consoleReport, err := iproc.processInvoice(tx, invoiceInput, stores)
+		if err != nil {
+			log.Printf("local.invoiceProcessor: error processing invoice %s: %s", invoiceInput.FileName, err.Error())
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+
+		if err != nil {
+			result.Status = constants.IngestionStatuses.FAILED
+			result.Err = err
+			results = append(results, getNotProcessed(invoiceInputs[idx+1:], invoiceInput)...)
+			break
+		}
+
+		if consoleReport != nil {
+			consoleReport.Run()
+		}
+
+		result.Status = getIngestionStatus(consoleReport != nil)
+	}
+
+	return results, nil
+}
+
+// processOnSingleTransaction commits the invoices together, the first
+// failure rolls all of them back; reports are printed after the commit.
+func (iproc *invoiceProcessor) processOnSingleTransaction(invoiceInputs []*input.Invoice) ([]*model.IngestionResult, error) {
+	results := make([]*model.IngestionResult, 0, len(invoiceInputs))
+
+	tx, err := iproc.conn.Begin()
+	if err != nil {
+		return results, err
+	}
+
+	stores, err := iproc.getInvoiceStores(tx)
+	if err != nil {
+		tx.Rollback()
+		return results, err
+	}
+
+	consoleReports := make([]*report.ConsoleReport, 0, len(invoiceInputs))
+
+	for _, invoiceInput := range invoiceInputs {
+		result := &model.IngestionResult{FileName: invoiceInput.FileName}
+		results = append(results, result)
+
+		consoleReport, err := iproc.processInvoice(tx, invoiceInput, stores)
+		if err != nil {
+			tx.Rollback()
+
+			for _, rolledBack := range results {
+				rolledBack.Status = constants.IngestionStatuses.FAILED
+				rolledBack.Err = fmt.Errorf("rolled back, invoice %s failed", invoiceInput.FileName)
+			}
+
+			result.Err = err
+			results = append(results, getNotProcessed(invoiceInputs[len(results):], invoiceInput)...)
+			return results, err
+		}
+
+		if consoleReport != nil {
+			consoleReports = append(consoleReports, consoleReport)
+		}
+
+		result.Status = getIngestionStatus(consoleReport != nil)
+	}
+
+	if err = tx.Commit(); err != nil {
+		for _, result := range results {
+			result.Status = constants.IngestionStatuses.FAILED
+			result.Err = err
+		}
+
+		return results, err
+	}
+
+	for _, consoleReport := range consoleReports {
+		consoleReport.Run()
+	}
+
+	return results, nil
+}
+
+// processInvoices applies the pending ticker changes, then processes the
+// invoices in the given order up to the first failure; failures of invoices
+// on their own transaction are kept on their results while the error stops
+// the run.
+func (iproc *invoiceProcessor) processInvoices(invoiceInputs []*input.Invoice) ([]*model.IngestionResult, error) {
+	if err := applyTickerChanges(iproc.conn, iproc.tickerStore); err != nil {
+		return nil, err
+	}
+
+	if iproc.allOrNothing {
+		return iproc.processOnSingleTransaction(invoiceInputs)
+	}
+
+	return iproc.processOnOwnTransaction(invoiceInputs)
+}
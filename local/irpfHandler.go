@@ -0,0 +1,91 @@
+package local
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/report"
+	"github.com/jarismar/b3c-invoice-reader-lambda/service"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// IrpfHandler handles `irpf <clientId> <year> [csv|json|console]`
+func IrpfHandler(args []string) (bool, error) {
+	if len(args) < 2 || len(args) > 3 {
+		err := fmt.Errorf("local.IrpfHandler: error: usage irpf <clientId> <year> [csv|json|console]")
+		return false, err
+	}
+
+	clientId := args[0]
+	year, err := strconv.Atoi(args[1])
+
+	if err != nil {
+		return false, err
+	}
+
+	format := constants.ReportFormats.CONSOLE
+
+	if len(args) == 3 {
+		format = args[2]
+	}
+
+	log.Printf("local.IrpfHandler: building worksheet for %s, year %d", clientId, year)
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		return false, err
+	}
+
+	defer conn.Close()
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	// read only
+	defer tx.Rollback()
+
+	userService := service.GetUserService(tx, &entity.User{
+		ExternalUUID: clientId,
+	})
+
+	userRec, err := userService.LoadUser()
+	if err != nil {
+		return false, err
+	}
+
+	if userRec == nil {
+		err = fmt.Errorf("local.IrpfHandler: error: user %s not found", clientId)
+		return false, err
+	}
+
+	taxRateService := service.GetTaxRateService(tx, store.GetTaxRateStore())
+	taxRateStore, err := taxRateService.LoadTaxRates()
+	if err != nil {
+		return false, err
+	}
+
+	tickerStore, err := getTickerStore()
+	if err != nil {
+		return false, err
+	}
+
+	irpfService := service.GetIrpfService(tx, userRec, year, taxRateStore, tickerStore)
+	worksheet, err := irpfService.GetWorksheet()
+	if err != nil {
+		return false, err
+	}
+
+	irpfReport := report.GetIrpfReport(worksheet, format, os.Stdout)
+	if err = irpfReport.Run(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
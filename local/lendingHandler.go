@@ -0,0 +1,85 @@
+package local
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/reader"
+	"github.com/jarismar/b3c-invoice-reader-lambda/report"
+	"github.com/jarismar/b3c-invoice-reader-lambda/service"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+)
+
+// LendingHandler handles `lending <lending_yyyy_mm_dd_name.json>`
+func LendingHandler(args []string) (bool, error) {
+	if len(args) != 1 {
+		err := fmt.Errorf("local.LendingHandler: error: usage lending <file>")
+		return false, err
+	}
+
+	fileNameStr := args[0]
+	log.Printf("local.LendingHandler: processing file %s", fileNameStr)
+
+	lendingInput, err := reader.LendingFileReader(fileNameStr)
+	if err != nil {
+		return false, err
+	}
+
+	tickerStore, err := getTickerStore()
+	if err != nil {
+		return false, err
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		return false, err
+	}
+
+	defer conn.Close()
+
+	if err = applyTickerChanges(conn, tickerStore); err != nil {
+		return false, err
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	companyStore := store.GetCompanyStore()
+
+	taxRateService := service.GetTaxRateService(tx, store.GetTaxRateStore())
+	taxRateStore, err := taxRateService.LoadTaxRates()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	lendingService := service.GetLendingService(
+		tx,
+		lendingInput,
+		store.GetTaxStore(),
+		companyStore,
+		taxRateStore,
+		tickerStore,
+		store.GetFiiTradeBatchStore(),
+		store.GetOptionTradeBatchStore(),
+	)
+
+	lendingContracts, err := lendingService.ProcessLendingContracts()
+
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	lendingReport := report.GetLendingReport(lendingContracts)
+	lendingReport.Run()
+
+	tx.Commit()
+
+	log.Printf("local.LendingHandler: done processing file: %s", fileNameStr)
+
+	return true, nil
+}
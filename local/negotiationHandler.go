@@ -0,0 +1,196 @@
+package local
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/calendar"
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/reader"
+	"github.com/jarismar/b3c-invoice-reader-lambda/report"
+	"github.com/jarismar/b3c-invoice-reader-lambda/service"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// NegotiationHandler handles `negotiation <clientId> <negociacao.csv>`, the
+// trades of B3's negotiation export are processed as one invoice per broker
+// and market date, oldest first, on a single transaction; invoices already
+// on the DB and dates the broker already has an invoice on are skipped.
+func NegotiationHandler(args []string) (bool, error) {
+	if len(args) != 2 {
+		err := fmt.Errorf("local.NegotiationHandler: error: usage negotiation <clientId> <file>")
+		return false, err
+	}
+
+	fileNameStr := args[1]
+	log.Printf("local.NegotiationHandler: processing file %s", fileNameStr)
+
+	negotiations, err := reader.NegotiationFileReader(fileNameStr)
+	if err != nil {
+		return false, err
+	}
+
+	negotiations.Client = input.Client{Id: args[0]}
+
+	b3Calendar, err := calendar.GetB3Calendar()
+	if err != nil {
+		return false, err
+	}
+
+	tickerStore, err := getTickerStore()
+	if err != nil {
+		return false, err
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		return false, err
+	}
+
+	defer conn.Close()
+
+	if err = applyTickerChanges(conn, tickerStore); err != nil {
+		return false, err
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	companyStore := store.GetCompanyStore()
+
+	companyAliasStore, err := service.LoadCompanyAliases(tx, store.GetCompanyAliasStore())
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	taxRateService := service.GetTaxRateService(tx, store.GetTaxRateStore())
+	taxRateStore, err := taxRateService.LoadTaxRates()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	brokerStore := store.GetBrokerStore()
+	brokerFileName, ok := os.LookupEnv("B3_BROKER_FILE")
+	if ok && brokerFileName != "" {
+		if err = brokerStore.LoadFile(brokerFileName); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	negotiationService := service.GetNegotiationService(negotiations, b3Calendar, taxRateStore, brokerStore)
+
+	invoiceInputs, err := negotiationService.GetInvoices()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	taxStore := store.GetTaxStore()
+	companyBatchStore := store.GetCompanyBatchStore()
+	brokerBatchStore := store.GetBrokerBatchStore()
+	fiiTradeBatchStore := store.GetFiiTradeBatchStore()
+	optionTradeBatchStore := store.GetOptionTradeBatchStore()
+
+	userService := service.GetUserService(tx, &entity.User{ExternalUUID: negotiations.Client.Id})
+	userRec, err := userService.LoadUser()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	skipped := 0
+
+	for _, invoiceInput := range invoiceInputs {
+		marketDate, err := utils.GetDateObject(invoiceInput.MarketDate)
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+
+		invoiceDAO := db.GetInvoiceDAO(tx, &entity.Invoice{
+			FileName:   invoiceInput.FileName,
+			User:       userRec,
+			MarketDate: marketDate,
+		}, invoiceInput.AgentId)
+
+		isNew, err := invoiceDAO.IsNewInvoice()
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+
+		if !isNew {
+			skipped++
+			continue
+		}
+
+		// the broker note of the date may already be on the DB
+		if userRec != nil {
+			hasInvoice, err := invoiceDAO.HasAgentInvoice()
+			if err != nil {
+				tx.Rollback()
+				return false, err
+			}
+
+			if hasInvoice {
+				log.Printf(
+					"local.NegotiationHandler: WARNING: skipping %s, agent %s already has an invoice on %s",
+					invoiceInput.FileName,
+					invoiceInput.AgentId,
+					invoiceInput.MarketDate,
+				)
+				skipped++
+				continue
+			}
+		}
+
+		log.Print("local.NegotiationHandler: going to process invoice: ", invoiceInput.FileName)
+
+		// per company brokerage is charged once per invoice
+		invoiceService := service.GetInvoiceService(
+			tx,
+			invoiceInput,
+			taxStore,
+			companyStore,
+			companyBatchStore,
+			store.GetBrokerTaxStore(),
+			b3Calendar,
+			taxRateStore,
+			brokerStore,
+			brokerBatchStore,
+			tickerStore,
+			companyAliasStore,
+			fiiTradeBatchStore,
+			optionTradeBatchStore,
+		)
+
+		invoiceRec, err := invoiceService.ProcessInvoice()
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+
+		report := report.GetConsoleReport(invoiceRec, fiiTradeBatchStore, optionTradeBatchStore)
+		report.Run()
+	}
+
+	tx.Commit()
+
+	log.Printf(
+		"local.NegotiationHandler: done processing file: %s, %d invoices, %d skipped",
+		fileNameStr,
+		len(invoiceInputs)-skipped,
+		skipped,
+	)
+
+	return true, nil
+}
@@ -0,0 +1,64 @@
+package local
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/report"
+	"github.com/jarismar/b3c-invoice-reader-lambda/service"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// PositionHandler handles `positions <clientId>`
+func PositionHandler(args []string) (bool, error) {
+	if len(args) != 1 {
+		err := fmt.Errorf("local.PositionHandler: error: usage positions <clientId>")
+		return false, err
+	}
+
+	clientId := args[0]
+	log.Printf("local.PositionHandler: loading positions for %s", clientId)
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		return false, err
+	}
+
+	defer conn.Close()
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	// read only
+	defer tx.Rollback()
+
+	userService := service.GetUserService(tx, &entity.User{
+		ExternalUUID: clientId,
+	})
+
+	userRec, err := userService.LoadUser()
+	if err != nil {
+		return false, err
+	}
+
+	if userRec == nil {
+		err = fmt.Errorf("local.PositionHandler: error: user %s not found", clientId)
+		return false, err
+	}
+
+	positionService := service.GetPositionService(tx, userRec)
+	positions, err := positionService.GetPositions()
+	if err != nil {
+		return false, err
+	}
+
+	positionReport := report.GetPositionReport(userRec, positions)
+	if err = positionReport.Run(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
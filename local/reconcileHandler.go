@@ -0,0 +1,107 @@
+package local
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/reader"
+	"github.com/jarismar/b3c-invoice-reader-lambda/report"
+	"github.com/jarismar/b3c-invoice-reader-lambda/service"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// ReconcileHandler handles `reconcile <clientId> <yyyy-mm-dd> <posicao.csv>`
+func ReconcileHandler(args []string) (bool, error) {
+	if len(args) != 3 {
+		err := fmt.Errorf("local.ReconcileHandler: error: usage reconcile <clientId> <yyyy-mm-dd> <file>")
+		return false, err
+	}
+
+	clientId := args[0]
+
+	statementDate, err := utils.ParseB3Date(args[1])
+	if err != nil {
+		return false, err
+	}
+
+	fileNameStr := args[2]
+	log.Printf("local.ReconcileHandler: reconciling %s on %s with %s", clientId, statementDate, fileNameStr)
+
+	statement, err := reader.CustodyStatementFileReader(fileNameStr)
+	if err != nil {
+		return false, err
+	}
+
+	tickerStore, err := getTickerStore()
+	if err != nil {
+		return false, err
+	}
+
+	brokerStore := store.GetBrokerStore()
+	brokerFileName, ok := os.LookupEnv("B3_BROKER_FILE")
+	if ok && brokerFileName != "" {
+		if err = brokerStore.LoadFile(brokerFileName); err != nil {
+			return false, err
+		}
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		return false, err
+	}
+
+	defer conn.Close()
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	// read only
+	defer tx.Rollback()
+
+	userService := service.GetUserService(tx, &entity.User{
+		ExternalUUID: clientId,
+	})
+
+	userRec, err := userService.LoadUser()
+	if err != nil {
+		return false, err
+	}
+
+	if userRec == nil {
+		err = fmt.Errorf("local.ReconcileHandler: error: user %s not found", clientId)
+		return false, err
+	}
+
+	// statement tickers are read as their successors
+	tickerChangeService := service.GetTickerChangeService(tx, tickerStore, store.GetCompanyStore())
+	if _, err = tickerChangeService.LoadTickerChanges(); err != nil {
+		return false, err
+	}
+
+	reconcileService := service.GetReconcileService(
+		tx,
+		userRec,
+		statement,
+		statementDate,
+		brokerStore,
+		tickerStore,
+	)
+
+	reconciliation, err := reconcileService.Reconcile()
+	if err != nil {
+		return false, err
+	}
+
+	reconcileReport := report.GetReconcileReport(reconciliation)
+	if err = reconcileReport.Run(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
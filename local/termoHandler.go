@@ -0,0 +1,131 @@
+package local
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/report"
+	"github.com/jarismar/b3c-invoice-reader-lambda/service"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// TermoHandler handles `termo <clientId> <yyyy-mm-dd> [code qty [price]]`,
+// without a code it settles the positions due until the date, with one it
+// settles qty shares of code early.
+func TermoHandler(args []string) (bool, error) {
+	if len(args) != 2 && len(args) != 4 && len(args) != 5 {
+		err := fmt.Errorf("local.TermoHandler: error: usage termo <clientId> <yyyy-mm-dd> [code qty [price]]")
+		return false, err
+	}
+
+	clientId := args[0]
+
+	settlementDate, err := utils.ParseB3Date(args[1])
+	if err != nil {
+		return false, err
+	}
+
+	var qty int64
+	var price float64
+
+	if len(args) > 2 {
+		if qty, err = strconv.ParseInt(args[3], 10, 64); err != nil {
+			return false, err
+		}
+	}
+
+	if len(args) == 5 {
+		if price, err = strconv.ParseFloat(args[4], 64); err != nil {
+			return false, err
+		}
+	}
+
+	log.Printf("local.TermoHandler: settling termo positions of %s on %s", clientId, settlementDate)
+
+	tickerStore, err := getTickerStore()
+	if err != nil {
+		return false, err
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		return false, err
+	}
+
+	defer conn.Close()
+
+	if err = applyTickerChanges(conn, tickerStore); err != nil {
+		return false, err
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	userService := service.GetUserService(tx, &entity.User{
+		ExternalUUID: clientId,
+	})
+
+	userRec, err := userService.LoadUser()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if userRec == nil {
+		tx.Rollback()
+		err = fmt.Errorf("local.TermoHandler: error: user %s not found", clientId)
+		return false, err
+	}
+
+	termoService := service.GetTermoService(
+		tx,
+		userRec,
+		store.GetCompanyBatchStore(),
+		store.GetBrokerBatchStore(),
+	)
+
+	var termoSettlements []*model.TermoSettlement
+
+	if len(args) == 2 {
+		termoSettlements, err = termoService.SettleDuePositions(settlementDate.Time())
+	} else {
+		code := tickerStore.GetSuccessor(strings.ToUpper(args[2]))
+
+		companyDAO := db.GetCompanyDAO(tx, &entity.Company{Code: code})
+		var companyRec *entity.Company
+		if companyRec, err = companyDAO.GetCompany(); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+
+		if companyRec == nil {
+			tx.Rollback()
+			err = fmt.Errorf("local.TermoHandler: error: company %s not found", code)
+			return false, err
+		}
+
+		termoSettlements, err = termoService.SettleEarly(companyRec, qty, price, settlementDate.Time())
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	termoReport := report.GetTermoReport(termoSettlements)
+	termoReport.Run()
+
+	tx.Commit()
+
+	log.Printf("local.TermoHandler: settled %d termo positions", len(termoSettlements))
+
+	return true, nil
+}
@@ -0,0 +1,84 @@
+package local
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/service"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+)
+
+// TickerChangeHandler handles `ticker-change <oldCode> <newCode> <yyyy-mm-dd> [newName]`
+func TickerChangeHandler(args []string) (bool, error) {
+	if len(args) != 3 && len(args) != 4 {
+		err := fmt.Errorf("local.TickerChangeHandler: error: usage ticker-change <oldCode> <newCode> <yyyy-mm-dd> [newName]")
+		return false, err
+	}
+
+	effectiveDate, err := utils.ParseB3Date(args[2])
+	if err != nil {
+		return false, err
+	}
+
+	tickerChange := &model.TickerChange{
+		OldCode:       strings.ToUpper(args[0]),
+		NewCode:       strings.ToUpper(args[1]),
+		EffectiveDate: effectiveDate,
+	}
+
+	if len(args) == 4 {
+		tickerChange.NewName = args[3]
+	}
+
+	log.Printf(
+		"local.TickerChangeHandler: %s -> %s on %s",
+		tickerChange.OldCode,
+		tickerChange.NewCode,
+		tickerChange.EffectiveDate,
+	)
+
+	tickerStore, err := getTickerStore()
+	if err != nil {
+		return false, err
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		return false, err
+	}
+
+	defer conn.Close()
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	tickerChangeService := service.GetTickerChangeService(tx, tickerStore, store.GetCompanyStore())
+	today := utils.B3Today()
+
+	if _, err = tickerChangeService.ApplyTickerChanges(today); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	tickerChangeRec, err := tickerChangeService.CreateTickerChange(tickerChange, today)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	tx.Commit()
+
+	log.Printf(
+		"local.TickerChangeHandler: ticker change %d recorded, applied = %t",
+		tickerChangeRec.Id,
+		tickerChangeRec.Applied,
+	)
+
+	return true, nil
+}
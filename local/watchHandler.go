@@ -0,0 +1,220 @@
+package local
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+)
+
+const (
+	watchProcessedDir = "processed"
+	watchFailedDir    = "failed"
+)
+
+// watchedFile the size and modification time of a file on the last poll, a
+// file is only read once both stop changing.
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+}
+
+// getReadyFiles lists the invoice files (json or SINACOR text) of the
+// directory unchanged since the previous poll and older than the interval,
+// files still being written are left for a later poll.
+func getReadyFiles(dirName string, watchedFiles map[string]watchedFile, interval time.Duration) ([]string, error) {
+	entries, err := os.ReadDir(dirName)
+	if err != nil {
+		return nil, err
+	}
+
+	readyFiles := make([]string, 0)
+	found := make(map[string]bool)
+
+	for _, entry := range entries {
+		name := entry.Name()
+		extension := strings.ToLower(filepath.Ext(name))
+
+		if entry.IsDir() || strings.HasPrefix(name, ".") || (extension != ".json" && extension != ".txt") {
+			continue
+		}
+
+		fileInfo, err := entry.Info()
+		if err != nil {
+			// removed since listed
+			continue
+		}
+
+		found[name] = true
+		current := watchedFile{
+			size:    fileInfo.Size(),
+			modTime: fileInfo.ModTime(),
+		}
+
+		previous, ok := watchedFiles[name]
+		watchedFiles[name] = current
+
+		if ok && previous == current && time.Since(current.modTime) >= interval {
+			readyFiles = append(readyFiles, name)
+		}
+	}
+
+	for name := range watchedFiles {
+		if !found[name] {
+			delete(watchedFiles, name)
+		}
+	}
+
+	// invoice file names sort by market date and number
+	sort.Strings(readyFiles)
+
+	return readyFiles, nil
+}
+
+// moveWatchedFile moves a file to the processed or failed directory, a file
+// of the same name already there is replaced.
+func moveWatchedFile(dirName string, name string, targetDir string) {
+	target := filepath.Join(dirName, targetDir, name)
+
+	if err := os.Rename(filepath.Join(dirName, name), target); err != nil {
+		log.Printf("local.WatchHandler: error moving %s to %s: %s", name, targetDir, err.Error())
+		return
+	}
+
+	log.Printf("local.WatchHandler: %s moved to %s", name, targetDir)
+}
+
+// processWatchedFile runs one file through the invoice pipeline, it stays in
+// place when processing stopped before its last invoice.
+func processWatchedFile(invoiceProcessor *invoiceProcessor, dirName string, name string) {
+	fileName := filepath.Join(dirName, name)
+	log.Printf("local.WatchHandler: processing file %s", fileName)
+
+	readFile := getInvoiceFileReader(fileName)
+
+	invoiceInputs, err := readFile(fileName)
+	if err != nil {
+		log.Printf("local.WatchHandler: error reading file %s: %s", name, err.Error())
+		moveWatchedFile(dirName, name, watchFailedDir)
+		return
+	}
+
+	results, err := invoiceProcessor.processInvoices(invoiceInputs)
+	if err != nil {
+		log.Printf("local.WatchHandler: error processing file %s: %s", name, err.Error())
+		moveWatchedFile(dirName, name, watchFailedDir)
+		return
+	}
+
+	if len(results) < len(invoiceInputs) {
+		log.Printf("local.WatchHandler: stopped processing %s, it stays for the next run", name)
+		return
+	}
+
+	for _, result := range results {
+		if result.Status == constants.IngestionStatuses.FAILED {
+			moveWatchedFile(dirName, name, watchFailedDir)
+			return
+		}
+	}
+
+	moveWatchedFile(dirName, name, watchProcessedDir)
+}
+
+// WatchHandler handles `watch <directory> [seconds]`, the directory is polled
+// every few seconds (2 by default) and each new invoice file, json or SINACOR
+// text, is processed once it stops changing and then moved to processed/ or
+// failed/; SIGINT and SIGTERM stop the watch after the transaction in flight.
+func WatchHandler(args []string) (bool, error) {
+	if len(args) < 1 || len(args) > 2 {
+		err := fmt.Errorf("local.WatchHandler: error: usage watch <directory> [seconds]")
+		return false, err
+	}
+
+	dirName := args[0]
+	interval := 2 * time.Second
+
+	if len(args) == 2 {
+		seconds, err := strconv.Atoi(args[1])
+		if err != nil || seconds <= 0 {
+			err = fmt.Errorf("local.WatchHandler: error: invalid interval %s", args[1])
+			return false, err
+		}
+
+		interval = time.Duration(seconds) * time.Second
+	}
+
+	dirInfo, err := os.Stat(dirName)
+	if err != nil {
+		return false, err
+	}
+
+	if !dirInfo.IsDir() {
+		err = fmt.Errorf("local.WatchHandler: error: %s is not a directory", dirName)
+		return false, err
+	}
+
+	for _, targetDir := range []string{watchProcessedDir, watchFailedDir} {
+		if err = os.MkdirAll(filepath.Join(dirName, targetDir), 0755); err != nil {
+			return false, err
+		}
+	}
+
+	invoiceProcessor, err := getInvoiceProcessor()
+	if err != nil {
+		return false, err
+	}
+
+	defer invoiceProcessor.Close()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	stop := make(chan struct{})
+	invoiceProcessor.setStop(stop)
+
+	go func() {
+		sig := <-signals
+		log.Printf("local.WatchHandler: %s received, stopping after the transaction in flight", sig)
+		close(stop)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	watchedFiles := make(map[string]watchedFile)
+
+	log.Printf("local.WatchHandler: watching %s every %s", dirName, interval)
+
+	for {
+		select {
+		case <-stop:
+			log.Printf("local.WatchHandler: done watching %s", dirName)
+			return true, nil
+		case <-ticker.C:
+		}
+
+		readyFiles, err := getReadyFiles(dirName, watchedFiles, interval)
+		if err != nil {
+			return false, err
+		}
+
+		for _, name := range readyFiles {
+			if invoiceProcessor.isStopped() {
+				break
+			}
+
+			processWatchedFile(invoiceProcessor, dirName, name)
+			delete(watchedFiles, name)
+		}
+	}
+}
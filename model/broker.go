@@ -0,0 +1,20 @@
+package model
+
+// BrokerageTier charges Fixed + Rate * value for order values up to UpTo,
+// the last tier may leave UpTo as zero to cover any value.
+type BrokerageTier struct {
+	UpTo  float64 `json:"upTo"`
+	Fixed float64 `json:"fixed"`
+	Rate  float64 `json:"rate"`
+}
+
+// Broker IssRate is nil when not set on the registry, zero is a broker
+// exempt from ISS.
+type Broker struct {
+	AgentId        string          `json:"agentId"`
+	Name           string          `json:"name"`
+	BrokerageModel string          `json:"brokerageModel"`
+	FlatFee        float64         `json:"flatFee"`
+	Tiers          []BrokerageTier `json:"tiers"`
+	IssRate        *float64        `json:"issRate"`
+}
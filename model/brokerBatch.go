@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// BrokerBatch is the custody of a company at one broker, the consolidated
+// CompanyBatch (all brokers) keeps driving average prices and trade results.
+type BrokerBatch struct {
+	Id           int64
+	CompanyBatch *entity.CompanyBatch
+	User         *entity.User
+	Company      *entity.Company
+	AgentId      string
+	StartDate    time.Time
+	Qty          int64
+	AvgPrice     float64
+	TotalPrice   float64
+}
+
+// Position pairs the consolidated company batch with its custody by broker,
+// LentQty are the shares out on lending contracts (still in the batch).
+type Position struct {
+	CompanyBatch  *entity.CompanyBatch
+	BrokerBatches []*BrokerBatch
+	LentQty       int64
+}
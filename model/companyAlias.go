@@ -0,0 +1,8 @@
+package model
+
+// CompanyAlias maps a security specification printed on broker notes
+// ("PETROBRAS PN N2") to its ticker.
+type CompanyAlias struct {
+	Name string `json:"name"`
+	Code string `json:"code"`
+}
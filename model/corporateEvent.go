@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// CorporateEvent is the audit record of a restructuring applied to a user
+// position. CostRate is the cost-basis split factor, the share of the
+// Company cost moved to Target, Cash is paid on subscriptions; fractions of
+// Target sold for cash are kept as Trade.
+type CorporateEvent struct {
+	Id           int64
+	User         *entity.User
+	Type         string
+	FileName     string
+	MarketDate   time.Time
+	Company      *entity.Company
+	Target       *entity.Company
+	FromQty      int64
+	ToQty        int64
+	CostRate     float64
+	SourceQty    int64
+	TargetQty    int64
+	CostMoved    float64
+	Cash         float64
+	FractionQty  float64
+	FractionCash float64
+	FractionCost float64
+	Trade        *entity.Trade
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// CustodyTransfer is the source record of a position imported without an
+// invoice (opening balance or transfer from another broker). BrokerMove marks
+// a transfer between tracked brokers, it only moves the custody by broker.
+type CustodyTransfer struct {
+	Id           int64
+	User         *entity.User
+	Company      *entity.Company
+	CompanyBatch *entity.CompanyBatch
+	FileName     string
+	MarketDate   time.Time
+	Qty          int64
+	TotalPrice   float64
+	OriginBroker string
+	AgentId      string
+	BrokerMove   bool
+}
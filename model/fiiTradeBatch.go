@@ -0,0 +1,14 @@
+package model
+
+import (
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// FiiTradeBatch holds the monthly real estate fund (FII) results of a trade
+// batch, FII gains are taxed apart from shares with no exemption and their
+// losses only offset later FII gains.
+type FiiTradeBatch struct {
+	Id         int64
+	TradeBatch *entity.TradeBatch
+	Data       *entity.TradeBatchData
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// FuturesBatch holds the monthly BM&F futures results of a user apart from
+// the spot trade batch, day trade and common (position) results carry their
+// own losses.
+type FuturesBatch struct {
+	Id        int64
+	User      *entity.User
+	TaxGroup  *entity.TaxGroup
+	StartDate time.Time
+	DayTrade  *entity.TradeBatchData
+	Common    *entity.TradeBatchData
+}
+
+// FuturesNote a processed BM&F note, the adjustments are the net ajuste of
+// its day trade and position lines, Fees the note fees except withheld IR.
+type FuturesNote struct {
+	Id                 int64
+	User               *entity.User
+	TaxGroup           *entity.TaxGroup
+	FuturesBatch       *FuturesBatch
+	Number             int64
+	FileName           string
+	AgentId            string
+	MarketDate         time.Time
+	BillingDate        time.Time
+	DayTradeAdjustment float64
+	Adjustment         float64
+	Fees               float64
+	NetValue           float64
+}
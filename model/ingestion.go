@@ -0,0 +1,16 @@
+package model
+
+// IngestionResult the outcome of one invoice, or of a file that could not be
+// read; Status is one of constants.IngestionStatuses.
+type IngestionResult struct {
+	FileName string
+	Status   string
+	Err      error
+}
+
+// Ingestion a batch run over the invoice files of the Source, a directory or
+// an S3 prefix.
+type Ingestion struct {
+	Source  string
+	Results []*IngestionResult
+}
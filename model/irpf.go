@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// PositionEvent is a buy (item batch, custody transfer, corporate event
+// target, termo settlement) or a sell (trade, incorporated company, converted
+// unit or shares) affecting a position,
+// TotalPrice carries acquisition cost (taxes included) for buys and the raw
+// sale value for trades, whose taxes are on TotalTax; spin-offs are buys of
+// zero quantity with the negative cost moved out. InvoiceItem tells buys and
+// trades of invoices, the ones that can be day trades. Cash paid for
+// corporate event fractions is a sell of zero quantity whose Results were
+// realized outside invoices.
+type PositionEvent struct {
+	MarketDate  time.Time
+	Order       int64
+	Company     *entity.Company
+	Debit       bool
+	Qty         int64
+	TotalPrice  float64
+	TotalTax    float64
+	InvoiceItem bool
+	Results     float64
+}
+
+type IrpfAsset struct {
+	Group       string  `json:"group"`
+	Code        string  `json:"code"`
+	Ticker      string  `json:"ticker"`
+	Name        string  `json:"name"`
+	AssetType   string  `json:"assetType"`
+	PrevQty     int64   `json:"prevQty"`
+	PrevTotal   float64 `json:"prevTotal"`
+	Qty         int64   `json:"qty"`
+	Total       float64 `json:"total"`
+	Description string  `json:"description"`
+}
+
+type IrpfMonth struct {
+	Month           int     `json:"month"`
+	CommonResults   float64 `json:"commonResults"`
+	CommonAccLoss   float64 `json:"commonAccLoss"`
+	CommonIRDue     float64 `json:"commonIRDue"`
+	DayTradeResults float64 `json:"dayTradeResults"`
+	DayTradeAccLoss float64 `json:"dayTradeAccLoss"`
+	DayTradeIRDue   float64 `json:"dayTradeIRDue"`
+	FiiResults      float64 `json:"fiiResults"`
+	FiiAccLoss      float64 `json:"fiiAccLoss"`
+	FiiIRPaid       float64 `json:"fiiIRPaid"`
+}
+
+type IrpfExemptGain struct {
+	Month     int     `json:"month"`
+	TotalSold float64 `json:"totalSold"`
+	Gain      float64 `json:"gain"`
+}
+
+type IrpfWorksheet struct {
+	UserName        string           `json:"userName"`
+	UserUUID        string           `json:"userUUID"`
+	Year            int              `json:"year"`
+	Assets          []IrpfAsset      `json:"assets"`
+	Months          []IrpfMonth      `json:"months"`
+	ExemptGains     []IrpfExemptGain `json:"exemptGains"`
+	TotalExemptGain float64          `json:"totalExemptGain"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// LendingContract a processed stock lending (BTC) contract, lender income
+// and its withholding are kept on an earnings tax group, borrower fees are a
+// cost of the trade batch of the contract end month.
+type LendingContract struct {
+	Id        int64
+	User      *entity.User
+	Company   *entity.Company
+	TaxGroup  *entity.TaxGroup
+	Contract  string
+	Side      string
+	AgentId   string
+	StartDate time.Time
+	EndDate   time.Time
+	Qty       int64
+	Rate      float64
+	Value     float64
+	Irrf      float64
+	Fees      float64
+}
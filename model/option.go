@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// OptionSeries is an option ticker split in its parts, Root is the
+// underlying ticker without its class suffix; Type holds one of
+// constants.OptionTypes; Week is the week of weekly series (W1 to W5), zero
+// for monthly ones.
+type OptionSeries struct {
+	Code        string
+	Root        string
+	Type        string
+	ExpiryMonth time.Month
+	StrikeCode  string
+	Week        int
+}
+
+// OptionBatch is the open position of one option series, Qty is negative for
+// written (sold) options; Premium is the premium paid for long positions or
+// received for written ones, always positive.
+type OptionBatch struct {
+	Id         int64
+	User       *entity.User
+	Company    *entity.Company
+	Underlying *entity.Company
+	Type       string
+	Strike     float64
+	ExpiryDate time.Time
+	StartDate  time.Time
+	Qty        int64
+	Premium    float64
+}
+
+// OptionTradeBatch holds the monthly option results of a trade batch, they
+// are common operations with no exemption and share the IRFEE of shares.
+type OptionTradeBatch struct {
+	Id         int64
+	TradeBatch *entity.TradeBatch
+	Data       *entity.TradeBatchData
+}
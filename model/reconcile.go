@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// Discrepancy a ticker whose stored quantity differs from B3's custody
+// statement, AgentId is empty on the consolidated (all brokers) comparison;
+// Status is one of constants.ReconcileStatuses and Hint the likely cause.
+type Discrepancy struct {
+	Code         string
+	AgentId      string
+	StatementQty int64
+	StoredQty    int64
+	Status       string
+	Hint         string
+}
+
+// Reconciliation compares a custody statement with the stored positions on
+// Date, ByBroker tells if custody by broker was compared too.
+type Reconciliation struct {
+	User          *entity.User
+	Date          time.Time
+	FileName      string
+	ByBroker      bool
+	Matched       int
+	Discrepancies []*Discrepancy
+}
@@ -0,0 +1,24 @@
+package model
+
+import "github.com/jarismar/b3c-invoice-reader-lambda/utils"
+
+// TaxRate is the value of a tax or fee rate valid on [StartDate, EndDate],
+// zero dates leave the interval open.
+type TaxRate struct {
+	Code      string
+	StartDate utils.B3Date
+	EndDate   utils.B3Date
+	Rate      float64
+}
+
+func (rate *TaxRate) IsValidOn(date utils.B3Date) bool {
+	if !rate.StartDate.IsZero() && date.Before(rate.StartDate) {
+		return false
+	}
+
+	if !rate.EndDate.IsZero() && date.After(rate.EndDate) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// TermoPosition a forward purchase (termo) waiting for settlement, TotalCost
+// is the contract value (forward price, financing included) plus the note
+// fees; OpenQty is what is left after partial early settlements.
+type TermoPosition struct {
+	Id        int64
+	User      *entity.User
+	Company   *entity.Company
+	Item      *entity.InvoiceItem
+	TaxGroup  *entity.TaxGroup
+	AgentId   string
+	OpenDate  time.Time
+	DueDate   time.Time
+	Qty       int64
+	OpenQty   int64
+	Price     float64
+	TotalCost float64
+}
+
+// TermoSettlement shares of a termo position delivered into the company
+// batch, Early is set on settlements before the due date (liquidação
+// antecipada).
+type TermoSettlement struct {
+	Id             int64
+	TermoPosition  *TermoPosition
+	CompanyBatch   *entity.CompanyBatch
+	SettlementDate time.Time
+	Qty            int64
+	TotalCost      float64
+	Early          bool
+}
@@ -0,0 +1,19 @@
+package model
+
+// Ticker is one entry of the ticker registry, AssetClass holds one of
+// constants.AssetClasses; Composition lists the shares bundled by a unit.
+type Ticker struct {
+	Code        string
+	AssetClass  string
+	Isin        string
+	Issuer      string
+	LotSize     int64
+	Composition []UnitComponent
+}
+
+// UnitComponent is one share bundled by a unit, TAEE11 holds 1 TAEE3 and
+// 2 TAEE4.
+type UnitComponent struct {
+	Code string
+	Qty  int64
+}
@@ -0,0 +1,14 @@
+package model
+
+import "github.com/jarismar/b3c-invoice-reader-lambda/utils"
+
+// TickerChange records OldCode being replaced by NewCode from EffectiveDate
+// on, Applied tells if positions were already moved to the successor.
+type TickerChange struct {
+	Id            int64
+	OldCode       string
+	NewCode       string
+	NewName       string
+	EffectiveDate utils.B3Date
+	Applied       bool
+}
@@ -1,14 +1,134 @@
 package reader
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path"
+	"sort"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/jarismar/b3c-invoice-reader-lambda/input"
 )
 
-func S3FileReader(fileName string) (*input.Invoice, error) {
-	log.Fatal("S3FileReader: error: Not implemented")
-	err := fmt.Errorf("S3FileReader: error: Not implemented")
-	return nil, err
+// defaultS3Bucket the bucket of the invoice files, B3_S3_BUCKET overrides it.
+const defaultS3Bucket = "b3c-data"
+
+func getS3Bucket() string {
+	if bucket, ok := os.LookupEnv("B3_S3_BUCKET"); ok && bucket != "" {
+		return bucket
+	}
+
+	return defaultS3Bucket
+}
+
+// getS3Client loads the AWS credentials and region from the environment, the
+// lambda role or the shared config files.
+func getS3Client(ctx context.Context) (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+
+	if err != nil {
+		log.Printf("reader.getS3Client: error loading AWS config: %s", err.Error())
+		return nil, err
+	}
+
+	return s3.NewFromConfig(cfg), nil
+}
+
+// S3InvoiceFiles lists the invoice files of an S3 prefix
+// (invoice/<userID>/<yyyy_mm>/) like LocalInvoiceFiles, their keys sort them
+// by month, market date and invoice number; other objects are ignored.
+func S3InvoiceFiles(prefix string) ([]string, error) {
+	ctx := context.TODO()
+	client, err := getS3Client(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := getS3Bucket()
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	keys := make([]string, 0)
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			log.Printf("reader.S3InvoiceFiles: error listing s3://%s/%s", bucket, prefix)
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+
+			if !invoiceFileName.MatchString(path.Base(key)) {
+				continue
+			}
+
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	log.Printf("reader.S3InvoiceFiles: %d invoice files found on s3://%s/%s", len(keys), bucket, prefix)
+
+	return keys, nil
+}
+
+// S3FileReader reads an invoice json of the bucket, or a list of invoices of
+// several notes ordered by market date and number, like LocalFileReader.
+func S3FileReader(key string) ([]*input.Invoice, error) {
+	baseName := path.Base(key)
+
+	if !invoiceFileName.MatchString(baseName) {
+		log.Printf("reader.S3FileReader: invalid file name: %s", baseName)
+		err := fmt.Errorf("invalid file name: %s", baseName)
+		return nil, err
+	}
+
+	ctx := context.TODO()
+	client, err := getS3Client(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := getS3Bucket()
+	object, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+
+	if err != nil {
+		log.Printf("reader.S3FileReader: error opening s3://%s/%s", bucket, key)
+		return nil, err
+	}
+
+	defer object.Body.Close()
+
+	jsonContent, err := io.ReadAll(object.Body)
+
+	if err != nil {
+		log.Printf("reader.S3FileReader: error reading s3://%s/%s", bucket, key)
+		return nil, err
+	}
+
+	invoices, err := parseInvoices(key, jsonContent)
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("reader.S3FileReader: success loading: s3://%s/%s, %d invoices", bucket, key, len(invoices))
+
+	return invoices, nil
 }
@@ -0,0 +1,55 @@
+package reader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+)
+
+func CorporateEventFileReader(fileName string) (*input.CorporateEvents, error) {
+	baseName := filepath.Base(fileName)
+	filenamePattern := regexp.MustCompile(`^event_\d{4}_\d{2}_\d{2}_\w+\.json$`)
+
+	if !filenamePattern.MatchString(baseName) {
+		log.Printf("reader.CorporateEventFileReader: invalid file name: %s", baseName)
+		err := fmt.Errorf("invalid file name: %s", baseName)
+		return nil, err
+	}
+
+	eventFile, err := os.Open(fileName)
+
+	if err != nil {
+		log.Printf("reader.CorporateEventFileReader: error opening file: %s", fileName)
+		return nil, err
+	}
+
+	defer eventFile.Close()
+
+	jsonContent, err := io.ReadAll(eventFile)
+
+	if err != nil {
+		log.Printf("reader.CorporateEventFileReader: error reading file: %s", fileName)
+		return nil, err
+	}
+
+	var corporateEvents input.CorporateEvents
+
+	if err = json.Unmarshal(jsonContent, &corporateEvents); err != nil {
+		log.Printf("reader.CorporateEventFileReader: error parsing file: %s", fileName)
+		return nil, err
+	}
+
+	if corporateEvents.FileName == "" {
+		corporateEvents.FileName = baseName
+	}
+
+	log.Printf("reader.CorporateEventFileReader: success loading: %s", fileName)
+
+	return &corporateEvents, nil
+}
@@ -0,0 +1,20 @@
+package reader
+
+import (
+	"encoding/csv"
+	"strings"
+)
+
+// readCsvRecords parses B3 exports, comma or semicolon separated as told by
+// the header line.
+func readCsvRecords(content string) ([][]string, error) {
+	csvReader := csv.NewReader(strings.NewReader(content))
+	csvReader.FieldsPerRecord = -1
+
+	firstLine, _, _ := strings.Cut(content, "\n")
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		csvReader.Comma = ';'
+	}
+
+	return csvReader.ReadAll()
+}
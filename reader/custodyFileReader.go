@@ -0,0 +1,55 @@
+package reader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+)
+
+func CustodyFileReader(fileName string) (*input.CustodyTransfer, error) {
+	baseName := filepath.Base(fileName)
+	filenamePattern := regexp.MustCompile(`^custody_\d{4}_\d{2}_\d{2}_\w+\.json$`)
+
+	if !filenamePattern.MatchString(baseName) {
+		log.Printf("reader.CustodyFileReader: invalid file name: %s", baseName)
+		err := fmt.Errorf("invalid file name: %s", baseName)
+		return nil, err
+	}
+
+	custodyFile, err := os.Open(fileName)
+
+	if err != nil {
+		log.Printf("reader.CustodyFileReader: error opening file: %s", fileName)
+		return nil, err
+	}
+
+	defer custodyFile.Close()
+
+	jsonContent, err := io.ReadAll(custodyFile)
+
+	if err != nil {
+		log.Printf("reader.CustodyFileReader: error reading file: %s", fileName)
+		return nil, err
+	}
+
+	var custodyTransfer input.CustodyTransfer
+
+	if err = json.Unmarshal(jsonContent, &custodyTransfer); err != nil {
+		log.Printf("reader.CustodyFileReader: error parsing file: %s", fileName)
+		return nil, err
+	}
+
+	if custodyTransfer.FileName == "" {
+		custodyTransfer.FileName = baseName
+	}
+
+	log.Printf("reader.CustodyFileReader: success loading: %s", fileName)
+
+	return &custodyTransfer, nil
+}
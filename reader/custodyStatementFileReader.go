@@ -0,0 +1,151 @@
+package reader
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+)
+
+// custodyStatementColumns header names of B3's position export, compared
+// after utils.NormalizeSecurityName; the ticker is read from "Produto"
+// ("PETR4 - PETROLEO BRASILEIRO S.A. PETROBRAS") when there is no code column.
+var custodyStatementColumns = map[string]string{
+	"code":        "CODIGO DE NEGOCIACAO",
+	"product":     "PRODUTO",
+	"institution": "INSTITUICAO",
+	"qty":         "QUANTIDADE",
+}
+
+// tickerCode matches the exchange tickers of equities, funds and BDRs.
+var tickerCode = regexp.MustCompile(`^[A-Z0-9]{4}[0-9]{1,2}$`)
+
+func getCustodyStatementColumnIndexes(header []string) (map[string]int, error) {
+	indexes := make(map[string]int)
+
+	for idx, name := range header {
+		name = utils.NormalizeSecurityName(strings.TrimPrefix(name, "\ufeff"))
+
+		for column, columnName := range custodyStatementColumns {
+			if name == columnName {
+				indexes[column] = idx
+			}
+		}
+	}
+
+	_, hasCode := indexes["code"]
+	_, hasProduct := indexes["product"]
+
+	if !hasCode && !hasProduct {
+		details := "custody statement: missing column CODIGO DE NEGOCIACAO"
+		return nil, utils.GetError("reader.CustodyStatementFileReader", "ERR_SYS_001", details)
+	}
+
+	for _, column := range []string{"institution", "qty"} {
+		if _, ok := indexes[column]; !ok {
+			details := fmt.Sprintf("custody statement: missing column %s", custodyStatementColumns[column])
+			return nil, utils.GetError("reader.CustodyStatementFileReader", "ERR_SYS_001", details)
+		}
+	}
+
+	return indexes, nil
+}
+
+// CustodyStatementFileReader reads the position csv exported by B3's investor
+// portal, rows without a ticker (fixed income, treasury, totals) are skipped.
+func CustodyStatementFileReader(fileName string) (*input.CustodyStatement, error) {
+	baseName := filepath.Base(fileName)
+	filenamePattern := regexp.MustCompile(`(?i)^posicao.*\.csv$`)
+
+	if !filenamePattern.MatchString(baseName) {
+		log.Printf("reader.CustodyStatementFileReader: invalid file name: %s", baseName)
+		err := fmt.Errorf("invalid file name: %s", baseName)
+		return nil, err
+	}
+
+	statementFile, err := os.Open(fileName)
+
+	if err != nil {
+		log.Printf("reader.CustodyStatementFileReader: error opening file: %s", fileName)
+		return nil, err
+	}
+
+	defer statementFile.Close()
+
+	content, err := io.ReadAll(statementFile)
+
+	if err != nil {
+		log.Printf("reader.CustodyStatementFileReader: error reading file: %s", fileName)
+		return nil, err
+	}
+
+	records, err := readCsvRecords(string(content))
+
+	if err != nil {
+		log.Printf("reader.CustodyStatementFileReader: error parsing file: %s", fileName)
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		err = fmt.Errorf("empty file: %s", baseName)
+		return nil, err
+	}
+
+	indexes, err := getCustodyStatementColumnIndexes(records[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	getField := func(record []string, column string) string {
+		idx, ok := indexes[column]
+
+		if !ok || idx >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[idx])
+	}
+
+	statement := &input.CustodyStatement{
+		FileName: baseName,
+		Items:    make([]input.CustodyStatementItem, 0, len(records)-1),
+	}
+
+	for lineNum, record := range records[1:] {
+		code := getField(record, "code")
+
+		if code == "" || code == "-" {
+			code, _, _ = strings.Cut(getField(record, "product"), " - ")
+		}
+
+		code = strings.ToUpper(strings.TrimSpace(code))
+
+		if !tickerCode.MatchString(code) {
+			continue
+		}
+
+		qty, err := utils.ParseBRQty(getField(record, "qty"))
+
+		if err != nil {
+			details := fmt.Sprintf("custody statement line %d: %s", lineNum+2, err.Error())
+			return nil, utils.GetError("reader.CustodyStatementFileReader", "ERR_SYS_001", details)
+		}
+
+		statement.Items = append(statement.Items, input.CustodyStatementItem{
+			Code:        code,
+			Institution: getField(record, "institution"),
+			Qty:         qty,
+		})
+	}
+
+	log.Printf("reader.CustodyStatementFileReader: success loading: %s", fileName)
+
+	return statement, nil
+}
@@ -0,0 +1,55 @@
+package reader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+)
+
+func FuturesFileReader(fileName string) (*input.FuturesNote, error) {
+	baseName := filepath.Base(fileName)
+	filenamePattern := regexp.MustCompile(`^futures_\d{4}_\d{2}_\d{2}_\w+\.json$`)
+
+	if !filenamePattern.MatchString(baseName) {
+		log.Printf("reader.FuturesFileReader: invalid file name: %s", baseName)
+		err := fmt.Errorf("invalid file name: %s", baseName)
+		return nil, err
+	}
+
+	futuresFile, err := os.Open(fileName)
+
+	if err != nil {
+		log.Printf("reader.FuturesFileReader: error opening file: %s", fileName)
+		return nil, err
+	}
+
+	defer futuresFile.Close()
+
+	jsonContent, err := io.ReadAll(futuresFile)
+
+	if err != nil {
+		log.Printf("reader.FuturesFileReader: error reading file: %s", fileName)
+		return nil, err
+	}
+
+	var futuresNote input.FuturesNote
+
+	if err = json.Unmarshal(jsonContent, &futuresNote); err != nil {
+		log.Printf("reader.FuturesFileReader: error parsing file: %s", fileName)
+		return nil, err
+	}
+
+	if futuresNote.FileName == "" {
+		futuresNote.FileName = baseName
+	}
+
+	log.Printf("reader.FuturesFileReader: success loading: %s", fileName)
+
+	return &futuresNote, nil
+}
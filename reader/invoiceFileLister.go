@@ -0,0 +1,35 @@
+package reader
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// LocalInvoiceFiles lists the invoice files of a directory, their names sort
+// them by market date and invoice number; other files are ignored.
+func LocalInvoiceFiles(dirName string) ([]string, error) {
+	entries, err := os.ReadDir(dirName)
+
+	if err != nil {
+		log.Printf("reader.LocalInvoiceFiles: error reading directory: %s", dirName)
+		return nil, err
+	}
+
+	fileNames := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.IsDir() || !invoiceFileName.MatchString(entry.Name()) {
+			continue
+		}
+
+		fileNames = append(fileNames, filepath.Join(dirName, entry.Name()))
+	}
+
+	sort.Strings(fileNames)
+
+	log.Printf("reader.LocalInvoiceFiles: %d invoice files found on %s", len(fileNames), dirName)
+
+	return fileNames, nil
+}
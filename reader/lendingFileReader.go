@@ -0,0 +1,55 @@
+package reader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+)
+
+func LendingFileReader(fileName string) (*input.LendingContracts, error) {
+	baseName := filepath.Base(fileName)
+	filenamePattern := regexp.MustCompile(`^lending_\d{4}_\d{2}_\d{2}_\w+\.json$`)
+
+	if !filenamePattern.MatchString(baseName) {
+		log.Printf("reader.LendingFileReader: invalid file name: %s", baseName)
+		err := fmt.Errorf("invalid file name: %s", baseName)
+		return nil, err
+	}
+
+	lendingFile, err := os.Open(fileName)
+
+	if err != nil {
+		log.Printf("reader.LendingFileReader: error opening file: %s", fileName)
+		return nil, err
+	}
+
+	defer lendingFile.Close()
+
+	jsonContent, err := io.ReadAll(lendingFile)
+
+	if err != nil {
+		log.Printf("reader.LendingFileReader: error reading file: %s", fileName)
+		return nil, err
+	}
+
+	var lendingContracts input.LendingContracts
+
+	if err = json.Unmarshal(jsonContent, &lendingContracts); err != nil {
+		log.Printf("reader.LendingFileReader: error parsing file: %s", fileName)
+		return nil, err
+	}
+
+	if lendingContracts.FileName == "" {
+		lendingContracts.FileName = baseName
+	}
+
+	log.Printf("reader.LendingFileReader: success loading: %s", fileName)
+
+	return &lendingContracts, nil
+}
@@ -1,6 +1,7 @@
 package reader
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,15 +9,87 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 
 	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
 )
 
-func LocalFileReader(fileName string) (*input.Invoice, error) {
+// invoiceFileName invoice files are named after the market date and number of
+// their note, yyyy_mm_dd_NNNNNNNNN.json.
+var invoiceFileName = regexp.MustCompile(`^\d{4}_\d{2}_\d{2}_\d{9}\.json$`)
+
+// SortInvoices orders invoices by market date and invoice number, the order
+// their positions must be processed; market dates are compared as dates, so
+// RFC3339 and yyyy-mm-dd values may be mixed, invalid ones come first.
+func SortInvoices(invoices []*input.Invoice) {
+	marketDates := make(map[*input.Invoice]utils.B3Date, len(invoices))
+
+	for _, invoice := range invoices {
+		marketDates[invoice], _ = utils.ParseB3Date(invoice.MarketDate)
+	}
+
+	sort.SliceStable(invoices, func(i, j int) bool {
+		iDate := marketDates[invoices[i]]
+		jDate := marketDates[invoices[j]]
+
+		if !iDate.Equal(jDate) {
+			return iDate.Before(jDate)
+		}
+
+		return invoices[i].InvoiceNum < invoices[j].InvoiceNum
+	})
+}
+
+// parseInvoices reads the content of an invoice json, or a list of invoices
+// of several notes ordered by market date and number.
+func parseInvoices(fileName string, jsonContent []byte) ([]*input.Invoice, error) {
+	invoices := make([]*input.Invoice, 0)
+	trimmedContent := bytes.TrimSpace(jsonContent)
+	var err error
+
+	if len(trimmedContent) > 0 && trimmedContent[0] == '[' {
+		err = json.Unmarshal(trimmedContent, &invoices)
+	} else {
+		var invoice input.Invoice
+		err = json.Unmarshal(trimmedContent, &invoice)
+		invoices = append(invoices, &invoice)
+	}
+
+	if err != nil {
+		log.Printf("reader.parseInvoices: error parsing file: %s", fileName)
+		return nil, err
+	}
+
+	// invoices of a list are named after their own note
+	for _, invoice := range invoices {
+		if invoice.FileName == "" && len(invoices) > 1 {
+			marketDate, err := utils.ParseB3Date(invoice.MarketDate)
+
+			if err != nil {
+				log.Printf("reader.parseInvoices: invalid market date on invoice %d: %s", invoice.InvoiceNum, fileName)
+				return nil, err
+			}
+
+			invoice.FileName = fmt.Sprintf(
+				"%s_%09d.json",
+				marketDate.Format("2006_01_02"),
+				invoice.InvoiceNum,
+			)
+		}
+	}
+
+	SortInvoices(invoices)
+
+	return invoices, nil
+}
+
+// LocalFileReader reads an invoice json, or a list of invoices of several
+// notes ordered by market date and number.
+func LocalFileReader(fileName string) ([]*input.Invoice, error) {
 	baseName := filepath.Base(fileName)
-	filenamePattern := regexp.MustCompile(`^\d{4}_\d{2}_\d{2}_\d{9}\.json$`)
 
-	if !filenamePattern.MatchString(baseName) {
+	if !invoiceFileName.MatchString(baseName) {
 		log.Printf("reader.LocalFileReader: invalid file name: %s", baseName)
 		err := fmt.Errorf("invalid file name: %s", baseName)
 		return nil, err
@@ -38,11 +111,13 @@ func LocalFileReader(fileName string) (*input.Invoice, error) {
 		return nil, err
 	}
 
-	var invoice input.Invoice
+	invoices, err := parseInvoices(fileName, jsonContent)
 
-	json.Unmarshal(jsonContent, &invoice)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Printf("reader.LocalFileReader: success loading: %s", fileName)
+	log.Printf("reader.LocalFileReader: success loading: %s, %d invoices", fileName, len(invoices))
 
-	return &invoice, nil
+	return invoices, nil
 }
@@ -0,0 +1,40 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLocalFileReaderList reads a list mixing RFC3339 and plain market dates,
+// sorted by date then number and named after their own note.
+func TestLocalFileReaderList(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "2024_02_16_000000001.json")
+	content := `[
+		{"invoiceNum": 12, "marketDate": "2024-02-16T00:00:00-03:00"},
+		{"invoiceNum": 3, "marketDate": "2024-02-15"},
+		{"invoiceNum": 11, "marketDate": "2024-02-16"}
+	]`
+
+	if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	invoices, err := LocalFileReader(fileName)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"2024_02_15_000000003.json",
+		"2024_02_16_000000011.json",
+		"2024_02_16_000000012.json",
+	}
+
+	for idx, invoice := range invoices {
+		if invoice.FileName != want[idx] {
+			t.Errorf("invoice %d = %s, want %s", idx, invoice.FileName, want[idx])
+		}
+	}
+}
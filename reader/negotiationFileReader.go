@@ -0,0 +1,185 @@
+package reader
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+)
+
+// negotiationColumns header names of B3's negotiation export, compared after
+// utils.NormalizeSecurityName ("Data do Negócio" is "DATA DO NEGOCIO").
+var negotiationColumns = map[string]string{
+	"date":        "DATA DO NEGOCIO",
+	"side":        "TIPO DE MOVIMENTACAO",
+	"market":      "MERCADO",
+	"dueDate":     "PRAZO VENCIMENTO",
+	"institution": "INSTITUICAO",
+	"code":        "CODIGO DE NEGOCIACAO",
+	"qty":         "QUANTIDADE",
+	"price":       "PRECO",
+	"value":       "VALOR",
+}
+
+func getNegotiationColumnIndexes(header []string) (map[string]int, error) {
+	indexes := make(map[string]int)
+
+	for idx, name := range header {
+		name = utils.NormalizeSecurityName(strings.TrimPrefix(name, "\ufeff"))
+
+		for column, columnName := range negotiationColumns {
+			if name == columnName {
+				indexes[column] = idx
+			}
+		}
+	}
+
+	for column, columnName := range negotiationColumns {
+		if _, ok := indexes[column]; !ok && column != "dueDate" && column != "value" {
+			details := fmt.Sprintf("negotiation file: missing column %s", columnName)
+			return nil, utils.GetError("reader.NegotiationFileReader", "ERR_SYS_001", details)
+		}
+	}
+
+	return indexes, nil
+}
+
+func getNegotiation(record []string, indexes map[string]int) (*input.Negotiation, error) {
+	getField := func(column string) string {
+		idx, ok := indexes[column]
+
+		if !ok || idx >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[idx])
+	}
+
+	date, err := utils.ParseBRDate(getField("date"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	negotiation := &input.Negotiation{
+		Date:        date.String(),
+		Market:      getField("market"),
+		Institution: getField("institution"),
+		Code:        strings.ToUpper(getField("code")),
+	}
+
+	switch utils.NormalizeSecurityName(getField("side")) {
+	case "COMPRA":
+		negotiation.Debit = true
+	case "VENDA":
+		negotiation.Debit = false
+	default:
+		return nil, fmt.Errorf("invalid side %s", getField("side"))
+	}
+
+	if dueDate := getField("dueDate"); dueDate != "" && dueDate != "-" {
+		dueDateObj, err := utils.ParseBRDate(dueDate)
+
+		if err != nil {
+			return nil, err
+		}
+
+		negotiation.DueDate = dueDateObj.String()
+	}
+
+	if negotiation.Qty, err = utils.ParseBRQty(getField("qty")); err != nil {
+		return nil, err
+	}
+
+	if negotiation.Price, err = utils.ParseBRNumber(getField("price")); err != nil {
+		return nil, err
+	}
+
+	if negotiation.Value, err = utils.ParseBRNumber(getField("value")); err != nil {
+		return nil, err
+	}
+
+	if negotiation.Value == 0 {
+		negotiation.Value = negotiation.Price * float64(negotiation.Qty)
+	}
+
+	return negotiation, nil
+}
+
+// NegotiationFileReader reads the negotiation csv exported by B3's investor
+// portal, comma or semicolon separated, prices either as "R$ 1.234,56" or
+// "1234.56".
+func NegotiationFileReader(fileName string) (*input.Negotiations, error) {
+	baseName := filepath.Base(fileName)
+	filenamePattern := regexp.MustCompile(`(?i)^negociacao.*\.csv$`)
+
+	if !filenamePattern.MatchString(baseName) {
+		log.Printf("reader.NegotiationFileReader: invalid file name: %s", baseName)
+		err := fmt.Errorf("invalid file name: %s", baseName)
+		return nil, err
+	}
+
+	negotiationFile, err := os.Open(fileName)
+
+	if err != nil {
+		log.Printf("reader.NegotiationFileReader: error opening file: %s", fileName)
+		return nil, err
+	}
+
+	defer negotiationFile.Close()
+
+	content, err := io.ReadAll(negotiationFile)
+
+	if err != nil {
+		log.Printf("reader.NegotiationFileReader: error reading file: %s", fileName)
+		return nil, err
+	}
+
+	records, err := readCsvRecords(string(content))
+
+	if err != nil {
+		log.Printf("reader.NegotiationFileReader: error parsing file: %s", fileName)
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		err = fmt.Errorf("empty file: %s", baseName)
+		return nil, err
+	}
+
+	indexes, err := getNegotiationColumnIndexes(records[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	negotiations := &input.Negotiations{
+		FileName: baseName,
+		Items:    make([]input.Negotiation, 0, len(records)-1),
+	}
+
+	for lineNum, record := range records[1:] {
+		if len(strings.TrimSpace(strings.Join(record, ""))) == 0 {
+			continue
+		}
+
+		negotiation, err := getNegotiation(record, indexes)
+
+		if err != nil {
+			details := fmt.Sprintf("negotiation file line %d: %s", lineNum+2, err.Error())
+			return nil, utils.GetError("reader.NegotiationFileReader", "ERR_SYS_001", details)
+		}
+
+		negotiations.Items = append(negotiations.Items, *negotiation)
+	}
+
+	log.Printf("reader.NegotiationFileReader: success loading: %s", fileName)
+
+	return negotiations, nil
+}
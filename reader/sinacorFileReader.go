@@ -0,0 +1,61 @@
+package reader
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+)
+
+// SinacorFileReader reads the extracted text of SINACOR broker notes, one
+// invoice per note ordered by market date; invoices are named after their
+// market date and number (yyyy_mm_dd_NNNNNNNNN.txt) so the same note is found
+// on the DB whatever the file name.
+func SinacorFileReader(fileName string) ([]*input.Invoice, error) {
+	baseName := filepath.Base(fileName)
+
+	if !strings.HasSuffix(strings.ToLower(baseName), ".txt") {
+		log.Printf("reader.SinacorFileReader: invalid file name: %s", baseName)
+		err := fmt.Errorf("invalid file name: %s", baseName)
+		return nil, err
+	}
+
+	noteFile, err := os.Open(fileName)
+
+	if err != nil {
+		log.Printf("reader.SinacorFileReader: error opening file: %s", fileName)
+		return nil, err
+	}
+
+	defer noteFile.Close()
+
+	content, err := io.ReadAll(noteFile)
+
+	if err != nil {
+		log.Printf("reader.SinacorFileReader: error reading file: %s", fileName)
+		return nil, err
+	}
+
+	invoices, err := ParseSinacorNotes(string(content))
+
+	if err != nil {
+		log.Printf("reader.SinacorFileReader: error parsing file: %s", fileName)
+		return nil, err
+	}
+
+	for _, invoice := range invoices {
+		invoice.FileName = fmt.Sprintf(
+			"%s_%09d.txt",
+			strings.ReplaceAll(invoice.MarketDate, "-", "_"),
+			invoice.InvoiceNum,
+		)
+	}
+
+	log.Printf("reader.SinacorFileReader: success loading: %s, %d notes", fileName, len(invoices))
+
+	return invoices, nil
+}
@@ -0,0 +1,384 @@
+package reader
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+)
+
+// sinacorAmount a value of the note summary, "1.234,56 D"
+const sinacorAmount = `([0-9.]+,[0-9]{2})\s*([DC])?`
+
+var sinacorHeader = regexp.MustCompile(`^\s*([0-9]+)\s+([0-9]+)\s+([0-9]{2}/[0-9]{2}/[0-9]{4})\s*$`)
+
+// sinacorContinued the summary of a page whose note goes on the next page.
+var sinacorContinued = regexp.MustCompile(`(?i)\bCONTINUA\s*\.\.\.`)
+
+var sinacorClientLabel = regexp.MustCompile(`(?i)^\s*cliente\b`)
+
+var sinacorClient = regexp.MustCompile(`^\s*([0-9][0-9.-]*)\s+(\S.*?)\s*(?:\s{2,}.*)?$`)
+
+var sinacorAgent = regexp.MustCompile(`(?i)agente de compensa\S*\s*:?\s*([0-9]+)`)
+
+// sinacorItem a negotiation line: Q, Negociação, C/V, Tipo mercado, Prazo,
+// Especificação do título, Obs., Quantidade, Preço, Valor and D/C.
+var sinacorItem = regexp.MustCompile(
+	`^\s*(?:\S+\s+)?[0-9]-BOVESPA\s+([CV])\s+` +
+		`(VISTA|FRACIONARIO|OPCAO DE COMPRA|OPCAO DE VENDA|EXERC OPC COMPRA|EXERC OPC VENDA|TERMO)\s+` +
+		`(?:([0-9]{2}/[0-9]{2}|[0-9]+)\s+)?` +
+		`(\S.*?)\s+` +
+		`(?:[#2DFHT8AB]{1,3}\s+)?` +
+		`([0-9.]+)\s+([0-9.]+,[0-9]{2,})\s+([0-9.]+,[0-9]{2})\s+([DC])\s*$`,
+)
+
+var sinacorStrike = regexp.MustCompile(`^[0-9.]+,[0-9]{2}$`)
+
+// sinacorSummary lines of Resumo dos Negócios and Resumo Financeiro, both
+// columns may share a text line.
+var sinacorSummary = map[string]*regexp.Regexp{
+	"spotSales":      regexp.MustCompile(`(?i)vendas\s+\S\s+vista\s+` + sinacorAmount),
+	"spotPurchases":  regexp.MustCompile(`(?i)compras\s+\S\s+vista\s+` + sinacorAmount),
+	"optionPurchase": regexp.MustCompile(`(?i)op\S+es\s*-\s*compras\s+` + sinacorAmount),
+	"optionSales":    regexp.MustCompile(`(?i)op\S+es\s*-\s*vendas\s+` + sinacorAmount),
+	"termo":          regexp.MustCompile(`(?i)opera\S+es\s+\S\s+termo\s+` + sinacorAmount),
+	"rawValue":       regexp.MustCompile(`(?i)valor\s+das\s+opera\S+es\s+` + sinacorAmount),
+	"net":            regexp.MustCompile(`(?i)l\S+quido\s+para\s+([0-9]{2}/[0-9]{2}/[0-9]{4})\s+` + sinacorAmount),
+}
+
+// sinacorTaxes fee lines of the financial summary by tax code, IRRF prints
+// its base before the withheld value.
+var sinacorTaxes = []struct {
+	code    string
+	pattern *regexp.Regexp
+}{
+	{constants.TaxTypes.SETFEE, regexp.MustCompile(`(?i)taxa\s+de\s+liquida\S+o\s+` + sinacorAmount)},
+	{constants.TaxTypes.EMLFEE, regexp.MustCompile(`(?i)emolumentos\s+` + sinacorAmount)},
+	{constants.TaxTypes.BRKFEE, regexp.MustCompile(`(?i)(?:corretagem|taxa\s+operacional)\s+` + sinacorAmount)},
+	{constants.TaxTypes.ISSSPFEE, regexp.MustCompile(`(?i)\bISS\b.*?\s` + sinacorAmount + `\s*$`)},
+	{constants.TaxTypes.IRRFFEE, regexp.MustCompile(`(?i)\bI\.?R\.?R\.?F\.?.*?\s` + sinacorAmount + `\s*$`)},
+}
+
+// sinacorMarkets note markets kept on the item, spot and fractional trades
+// take the invoice market.
+var sinacorMarkets = map[string]string{
+	"VISTA":            "",
+	"FRACIONARIO":      "",
+	"OPCAO DE COMPRA":  constants.Markets.CALL,
+	"OPCAO DE VENDA":   constants.Markets.PUT,
+	"EXERC OPC COMPRA": constants.Markets.CALL_EXERCISE,
+	"EXERC OPC VENDA":  constants.Markets.PUT_EXERCISE,
+	"TERMO":            constants.Markets.TERMO,
+}
+
+func getSinacorError(details string) error {
+	return utils.GetError("reader.ParseSinacorNote", "ERR_SYS_001", details)
+}
+
+func getSinacorAmount(value string) float64 {
+	amount, _ := utils.ParseBRNumber(value)
+	return amount
+}
+
+// getSinacorItem builds the item of a negotiation line, option series come
+// first on the security name ("PETRB380 PN 38,00 PETR") and termo purchases
+// carry their term in days.
+func getSinacorItem(match []string, marketDate utils.B3Date, order int64) (*input.Item, error) {
+	market := sinacorMarkets[match[2]]
+	name := strings.Join(strings.Fields(match[4]), " ")
+
+	qty, err := utils.ParseBRQty(match[5])
+
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := utils.ParseBRNumber(match[6])
+
+	if err != nil {
+		return nil, err
+	}
+
+	item := &input.Item{
+		Company: input.Company{Name: name},
+		Qty:     qty,
+		Price:   price,
+		Debit:   match[1] == "C",
+		Order:   order,
+		Market:  market,
+	}
+
+	markets := constants.Markets
+
+	switch market {
+	case markets.CALL, markets.PUT, markets.CALL_EXERCISE, markets.PUT_EXERCISE:
+		tokens := strings.Fields(name)
+		item.Company.Code = tokens[0]
+
+		for _, token := range tokens[1:] {
+			if sinacorStrike.MatchString(token) {
+				item.Strike, _ = utils.ParseBRNumber(token)
+			}
+		}
+	case markets.TERMO:
+		var days int
+
+		if _, err := fmt.Sscanf(match[3], "%d", &days); err != nil || days <= 0 {
+			return nil, fmt.Errorf("termo %s without term", name)
+		}
+
+		item.DueDate = marketDate.AddDays(days).String()
+	}
+
+	return item, nil
+}
+
+// ParseSinacorNote builds the invoice of a broker note on the SINACOR layout
+// from its extracted text (pdftotext -layout). Items without a ticker are
+// resolved from the security name later on; the client is the broker client
+// code and the agent is read from "Agente de compensação", notes without it
+// are rejected.
+func ParseSinacorNote(text string) (*input.Invoice, error) {
+	invoice := &input.Invoice{
+		Market: constants.Markets.SPOT,
+		Items:  make([]input.Item, 0),
+		Taxes:  make([]input.Tax, 0),
+	}
+
+	var marketDate utils.B3Date
+	summary := make(map[string][]string)
+	taxes := make(map[string][]string)
+	expectClient := false
+	itemsValue := 0.0
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r", ""), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if marketDate.IsZero() {
+			if match := sinacorHeader.FindStringSubmatch(line); match != nil {
+				fmt.Sscanf(match[1], "%d", &invoice.InvoiceNum)
+
+				date, err := utils.ParseBRDate(match[3])
+
+				if err != nil {
+					return nil, getSinacorError(err.Error())
+				}
+
+				marketDate = date
+				invoice.MarketDate = date.String()
+				continue
+			}
+		}
+
+		if expectClient {
+			if match := sinacorClient.FindStringSubmatch(line); match != nil {
+				invoice.Client = input.Client{
+					Id:   match[1],
+					Name: match[2],
+				}
+				expectClient = false
+				continue
+			}
+		}
+
+		if invoice.Client.Id == "" && sinacorClientLabel.MatchString(line) {
+			expectClient = true
+			continue
+		}
+
+		if match := sinacorAgent.FindStringSubmatch(line); match != nil && invoice.AgentId == "" {
+			invoice.AgentId = match[1]
+		}
+
+		if match := sinacorItem.FindStringSubmatch(line); match != nil {
+			if marketDate.IsZero() {
+				return nil, getSinacorError("negotiation line before the note header")
+			}
+
+			item, err := getSinacorItem(match, marketDate, int64(len(invoice.Items)+1))
+
+			if err != nil {
+				return nil, getSinacorError(fmt.Sprintf("[%s]", err.Error()))
+			}
+
+			invoice.Items = append(invoice.Items, *item)
+			itemsValue = itemsValue + getSinacorAmount(match[7])
+			continue
+		}
+
+		for key, pattern := range sinacorSummary {
+			if match := pattern.FindStringSubmatch(line); match != nil && summary[key] == nil {
+				summary[key] = match
+			}
+		}
+
+		for _, tax := range sinacorTaxes {
+			if match := tax.pattern.FindStringSubmatch(line); match != nil && taxes[tax.code] == nil {
+				taxes[tax.code] = match
+			}
+		}
+	}
+
+	if marketDate.IsZero() || invoice.InvoiceNum == 0 {
+		return nil, getSinacorError("note number and market date not found")
+	}
+
+	if len(invoice.Items) == 0 {
+		return nil, getSinacorError(fmt.Sprintf("[note %d, no negotiation lines]", invoice.InvoiceNum))
+	}
+
+	if invoice.AgentId == "" {
+		return nil, getSinacorError(fmt.Sprintf("[note %d, clearing agent not found]", invoice.InvoiceNum))
+	}
+
+	if summary["rawValue"] == nil || summary["net"] == nil {
+		return nil, getSinacorError(fmt.Sprintf("[note %d, financial summary not found]", invoice.InvoiceNum))
+	}
+
+	invoice.RawValue = getSinacorAmount(summary["rawValue"][1])
+
+	if math.Abs(invoice.RawValue-itemsValue) > 0.01 {
+		details := fmt.Sprintf(
+			"[note %d, items = %.2f, operations value = %.2f]",
+			invoice.InvoiceNum,
+			itemsValue,
+			invoice.RawValue,
+		)
+		return nil, getSinacorError(details)
+	}
+
+	for _, key := range []string{"spotSales", "optionSales"} {
+		if summary[key] != nil {
+			invoice.TotalSold = invoice.TotalSold + getSinacorAmount(summary[key][1])
+		}
+	}
+
+	for _, key := range []string{"spotPurchases", "optionPurchase", "termo"} {
+		if summary[key] != nil {
+			invoice.TotalAcquired = invoice.TotalAcquired + getSinacorAmount(summary[key][1])
+		}
+	}
+
+	billingDate, err := utils.ParseBRDate(summary["net"][1])
+
+	if err != nil {
+		return nil, getSinacorError(err.Error())
+	}
+
+	invoice.BillingDate = billingDate.String()
+	invoice.NetValue = getSinacorAmount(summary["net"][2])
+
+	if summary["net"][3] == "D" {
+		invoice.NetValue = -invoice.NetValue
+	}
+
+	for _, tax := range sinacorTaxes {
+		if match := taxes[tax.code]; match != nil {
+			invoice.Taxes = append(invoice.Taxes, input.Tax{
+				Code:   tax.code,
+				Source: constants.TaxSources.INVOICE,
+				Value:  getSinacorAmount(match[1]),
+			})
+		}
+	}
+
+	return invoice, nil
+}
+
+// sinacorNote the pages of one note, numbered and dated by their headers.
+type sinacorNote struct {
+	number    string
+	date      string
+	lines     []string
+	continued bool
+}
+
+// splitSinacorNotes splits an export of several notes by their page headers,
+// consecutive pages of the same note and date are merged and a page marked
+// "CONTINUA..." must be followed by another page of its note.
+func splitSinacorNotes(text string) ([]string, error) {
+	notes := make([]*sinacorNote, 0)
+	leading := make([]string, 0)
+	var note *sinacorNote
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r", ""), "\n") {
+		if match := sinacorHeader.FindStringSubmatch(line); match != nil {
+			if note == nil || note.number != match[1] || note.date != match[3] {
+				if note != nil && note.continued {
+					details := fmt.Sprintf("[note %s continues on a missing page]", note.number)
+					return nil, getSinacorError(details)
+				}
+
+				note = &sinacorNote{
+					number: match[1],
+					date:   match[3],
+					lines:  leading,
+				}
+				notes = append(notes, note)
+				leading = make([]string, 0)
+			}
+
+			note.continued = false
+		}
+
+		if note == nil {
+			leading = append(leading, line)
+			continue
+		}
+
+		if sinacorContinued.MatchString(line) {
+			note.continued = true
+		}
+
+		note.lines = append(note.lines, line)
+	}
+
+	if note != nil && note.continued {
+		details := fmt.Sprintf("[note %s continues on a missing page]", note.number)
+		return nil, getSinacorError(details)
+	}
+
+	texts := make([]string, 0, len(notes))
+
+	for _, note := range notes {
+		texts = append(texts, strings.Join(note.lines, "\n"))
+	}
+
+	return texts, nil
+}
+
+// ParseSinacorNotes builds the invoices of an export holding one or more
+// notes, ordered by market date and note number.
+func ParseSinacorNotes(text string) ([]*input.Invoice, error) {
+	texts, err := splitSinacorNotes(text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(texts) == 0 {
+		return nil, getSinacorError("note number and market date not found")
+	}
+
+	invoices := make([]*input.Invoice, 0, len(texts))
+
+	for _, noteText := range texts {
+		invoice, err := ParseSinacorNote(noteText)
+
+		if err != nil {
+			return nil, err
+		}
+
+		invoices = append(invoices, invoice)
+	}
+
+	SortInvoices(invoices)
+
+	return invoices, nil
+}
@@ -0,0 +1,192 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// compareGolden compares the invoices parsed from noteFile with the golden
+// file next to it, rewriting it on -update.
+func compareGolden(t *testing.T, noteFile string, value interface{}) {
+	t.Helper()
+
+	got, err := json.MarshalIndent(value, "", "  ")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got = append(got, '\n')
+	goldenFile := strings.TrimSuffix(noteFile, ".txt") + ".golden.json"
+
+	if *update {
+		if err := os.WriteFile(goldenFile, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(goldenFile)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("invoice differs from %s\ngot:\n%s\nwant:\n%s", goldenFile, got, want)
+	}
+}
+
+// TestParseSinacorNote parses the anonymized notes of testdata/sinacor and
+// compares the invoices with their golden files, run with -update to rewrite
+// them after a deliberate change.
+func TestParseSinacorNote(t *testing.T) {
+	noteFiles, err := filepath.Glob(filepath.Join("testdata", "sinacor", "*.txt"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(noteFiles) == 0 {
+		t.Fatal("no sample notes found")
+	}
+
+	for _, noteFile := range noteFiles {
+		name := strings.TrimSuffix(filepath.Base(noteFile), ".txt")
+
+		t.Run(name, func(t *testing.T) {
+			content, err := os.ReadFile(noteFile)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			invoice, err := ParseSinacorNote(string(content))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			compareGolden(t, noteFile, invoice)
+		})
+	}
+}
+
+// TestParseSinacorNotes parses the exports of testdata/sinacor/multi, with
+// several notes and notes continued on the next page.
+func TestParseSinacorNotes(t *testing.T) {
+	noteFiles, err := filepath.Glob(filepath.Join("testdata", "sinacor", "multi", "*.txt"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(noteFiles) == 0 {
+		t.Fatal("no sample exports found")
+	}
+
+	for _, noteFile := range noteFiles {
+		name := strings.TrimSuffix(filepath.Base(noteFile), ".txt")
+
+		t.Run(name, func(t *testing.T) {
+			content, err := os.ReadFile(noteFile)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			invoices, err := ParseSinacorNotes(string(content))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			compareGolden(t, noteFile, invoices)
+		})
+	}
+}
+
+func TestParseSinacorNotesErrors(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testdata", "sinacor", "multi", "notes.txt"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := string(content)
+	secondPage := strings.Index(text, "\f")
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{
+			name: "missing continued page",
+			text: text[:secondPage],
+		},
+		{
+			name: "continued page of another note",
+			text: strings.Replace(text, "1234580           2", "1234581           2", 1),
+		},
+		{
+			name: "empty text",
+			text: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseSinacorNotes(test.text); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseSinacorNoteErrors(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testdata", "sinacor", "spot.txt"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{
+			name: "missing negotiation line",
+			text: strings.Replace(string(content), "VALE ON NM", "", 1),
+		},
+		{
+			name: "missing header",
+			text: strings.Replace(string(content), "05/01/2024\n", "\n", 1),
+		},
+		{
+			name: "missing financial summary",
+			text: strings.Replace(string(content), "Líquido para", "", 1),
+		},
+		{
+			name: "missing clearing agent",
+			text: strings.Replace(string(content), "Agente de compensação: 99", "", 1),
+		},
+		{
+			name: "empty text",
+			text: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseSinacorNote(test.text); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -6,17 +6,26 @@ import (
 	"time"
 
 	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
 	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
 	"github.com/jarismar/b3c-service-entities/entity"
 )
 
 type ConsoleReport struct {
-	invoice *entity.Invoice
+	invoice               *entity.Invoice
+	fiiTradeBatchStore    *store.FiiTradeBatchStore
+	optionTradeBatchStore *store.OptionTradeBatchStore
 }
 
-func GetConsoleReport(invoice *entity.Invoice) *ConsoleReport {
+func GetConsoleReport(
+	invoice *entity.Invoice,
+	fiiTradeBatchStore *store.FiiTradeBatchStore,
+	optionTradeBatchStore *store.OptionTradeBatchStore,
+) *ConsoleReport {
 	return &ConsoleReport{
-		invoice: invoice,
+		invoice:               invoice,
+		fiiTradeBatchStore:    fiiTradeBatchStore,
+		optionTradeBatchStore: optionTradeBatchStore,
 	}
 }
 
@@ -47,18 +56,6 @@ func (report *ConsoleReport) printTaxGroup(taxGroupName string, taxGroup *entity
 	}
 }
 
-func getAssetType(cmp *entity.Company) string {
-	if cmp.BDR {
-		return "BDR"
-	}
-
-	if cmp.ETF {
-		return "ETF"
-	}
-
-	return "SHR"
-}
-
 func (report *ConsoleReport) printInvoiceItems() {
 	invoice := report.invoice
 	fmt.Printf("Invoice.Items .... : %d\n", len(invoice.Items))
@@ -78,7 +75,7 @@ func (report *ConsoleReport) printInvoiceItems() {
 			"%3d %8s %4s %6d %10.2f %6s %7d\n",
 			item.Order,
 			item.Company.Code,
-			getAssetType(item.Company),
+			utils.GetAssetType(item.Company),
 			item.Qty,
 			item.Price,
 			strconv.FormatBool(item.Debit),
@@ -94,7 +91,7 @@ func (report *ConsoleReport) printItemBatch() {
 	itemBatchList := make([]entity.InvoiceItem, 0, len(items))
 
 	for _, item := range items {
-		if item.Debit {
+		if item.Debit && item.ItemBatch != nil {
 			itemBatchList = append(itemBatchList, item)
 		}
 	}
@@ -159,7 +156,7 @@ func (report *ConsoleReport) printTrade() {
 	itemTradeList := make([]entity.InvoiceItem, 0, len(items))
 
 	for _, item := range items {
-		if !item.Debit {
+		if !item.Debit && item.Trade != nil {
 			itemTradeList = append(itemTradeList, item)
 		}
 	}
@@ -288,6 +285,45 @@ func (report *ConsoleReport) printTradeBatch() {
 		irfee,
 		tradeBatch.Id,
 	)
+
+	if report.optionTradeBatchStore.Has(tradeBatch.Id) {
+		optData := report.optionTradeBatchStore.Get(tradeBatch.Id).Data
+
+		// the option IR is part of the IRFEE of the rows above
+		fmt.Printf(
+			"%4s %8s %10.2f %10.2f %10.2f %8.2f %8s %8d\n",
+			"OPT",
+			tradeBatch.StartDate.Format("2006-01"),
+			optData.AccLoss,
+			optData.Results,
+			optData.TotalTrade,
+			optData.TotalTax,
+			"-",
+			tradeBatch.Id,
+		)
+	}
+
+	if !report.fiiTradeBatchStore.Has(tradeBatch.Id) {
+		return
+	}
+
+	fiiData := report.fiiTradeBatchStore.Get(tradeBatch.Id).Data
+	irFiiFee := utils.GetTaxValueByGroup(
+		tradeBatch.TaxGroup,
+		constants.TaxTypes.IRFIIFEE,
+	)
+
+	fmt.Printf(
+		"%4s %8s %10.2f %10.2f %10.2f %8.2f %8.2f %8d\n",
+		"FII",
+		tradeBatch.StartDate.Format("2006-01"),
+		fiiData.AccLoss,
+		fiiData.Results,
+		fiiData.TotalTrade,
+		fiiData.TotalTax,
+		irFiiFee,
+		tradeBatch.Id,
+	)
 }
 
 func (report *ConsoleReport) Run() error {
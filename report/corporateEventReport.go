@@ -0,0 +1,60 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type CorporateEventReport struct {
+	corporateEvents []*model.CorporateEvent
+}
+
+func GetCorporateEventReport(corporateEvents []*model.CorporateEvent) *CorporateEventReport {
+	return &CorporateEventReport{
+		corporateEvents: corporateEvents,
+	}
+}
+
+func (report *CorporateEventReport) Run() error {
+	fmt.Println("===== Corporate Events =====")
+	fmt.Printf("Events ........... : %d\n", len(report.corporateEvents))
+	fmt.Printf(
+		"%10s %-14s %8s %8s %9s %8s %8s %12s %12s %8s %10s %10s\n",
+		"Date",
+		"Type",
+		"From",
+		"To",
+		"Ratio",
+		"Qty",
+		"New Qty",
+		"Cost Moved",
+		"Paid",
+		"Factor",
+		"Fraction",
+		"Cash",
+	)
+
+	for _, corporateEvent := range report.corporateEvents {
+		fmt.Printf(
+			"%10s %-14s %8s %8s %4d:%-4d %8d %8d %12.2f %12.2f %8.6f %10.4f %10.2f\n",
+			corporateEvent.MarketDate.Format("2006-01-02"),
+			corporateEvent.Type,
+			corporateEvent.Company.Code,
+			corporateEvent.Target.Code,
+			corporateEvent.FromQty,
+			corporateEvent.ToQty,
+			corporateEvent.SourceQty,
+			corporateEvent.TargetQty,
+			corporateEvent.CostMoved,
+			corporateEvent.Cash,
+			corporateEvent.CostRate,
+			corporateEvent.FractionQty,
+			corporateEvent.FractionCash,
+		)
+	}
+
+	fmt.Println("============================")
+
+	return nil
+}
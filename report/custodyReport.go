@@ -0,0 +1,55 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type CustodyReport struct {
+	custodyTransfers []*model.CustodyTransfer
+}
+
+func GetCustodyReport(custodyTransfers []*model.CustodyTransfer) *CustodyReport {
+	return &CustodyReport{
+		custodyTransfers: custodyTransfers,
+	}
+}
+
+func (report *CustodyReport) Run() error {
+	fmt.Println("===== Custody Transfer =====")
+	fmt.Printf("Transfers ........ : %d\n", len(report.custodyTransfers))
+	fmt.Printf(
+		"%10s %8s %8s %12s %8s %8s %10s %7s %7s\n",
+		"Date",
+		"Tag",
+		"Qty",
+		"Total",
+		"Origin",
+		"Broker",
+		"Avg",
+		"BatchId",
+		"Id",
+	)
+
+	for _, custodyTransfer := range report.custodyTransfers {
+		companyBatch := custodyTransfer.CompanyBatch
+
+		fmt.Printf(
+			"%10s %8s %8d %12.2f %8s %8s %10.4f %7d %7d\n",
+			custodyTransfer.MarketDate.Format("2006-01-02"),
+			custodyTransfer.Company.Code,
+			custodyTransfer.Qty,
+			custodyTransfer.TotalPrice,
+			custodyTransfer.OriginBroker,
+			custodyTransfer.AgentId,
+			companyBatch.AvgPrice,
+			companyBatch.Id,
+			custodyTransfer.Id,
+		)
+	}
+
+	fmt.Println("============================")
+
+	return nil
+}
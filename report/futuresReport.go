@@ -0,0 +1,66 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+)
+
+type FuturesReport struct {
+	futuresNote *model.FuturesNote
+}
+
+func GetFuturesReport(futuresNote *model.FuturesNote) *FuturesReport {
+	return &FuturesReport{
+		futuresNote: futuresNote,
+	}
+}
+
+func (report *FuturesReport) Run() error {
+	futuresNote := report.futuresNote
+	futuresBatch := futuresNote.FuturesBatch
+
+	fmt.Println("===== Futures Note =====")
+	fmt.Printf("File ............. : %s\n", futuresNote.FileName)
+	fmt.Printf("Market date ...... : %s\n", futuresNote.MarketDate.Format("2006-01-02"))
+	fmt.Printf("Day trade ajuste . : %12.2f\n", futuresNote.DayTradeAdjustment)
+	fmt.Printf("Position ajuste .. : %12.2f\n", futuresNote.Adjustment)
+	fmt.Printf("Fees ............. : %12.2f\n", futuresNote.Fees)
+	fmt.Printf("IRRF ............. : %12.2f\n", utils.GetTaxValueByGroup(futuresNote.TaxGroup, constants.TaxTypes.IRRFFEE))
+	fmt.Printf("Net value ........ : %12.2f\n", futuresNote.NetValue)
+
+	fmt.Println("===== Futures Batch =====")
+	fmt.Printf("Month ............ : %s\n", futuresBatch.StartDate.Format("2006-01"))
+	fmt.Printf(
+		"%8s %12s %12s %12s %12s\n",
+		"Type",
+		"Results",
+		"Fees",
+		"AccLoss",
+		"IR",
+	)
+
+	fmt.Printf(
+		"%8s %12.2f %12.2f %12.2f %12.2f\n",
+		"DT",
+		futuresBatch.DayTrade.Results,
+		futuresBatch.DayTrade.TotalTax,
+		futuresBatch.DayTrade.AccLoss,
+		utils.GetTaxValueByGroup(futuresBatch.TaxGroup, constants.TaxTypes.IRDTFEE),
+	)
+
+	fmt.Printf(
+		"%8s %12.2f %12.2f %12.2f %12.2f\n",
+		"COMMON",
+		futuresBatch.Common.Results,
+		futuresBatch.Common.TotalTax,
+		futuresBatch.Common.AccLoss,
+		utils.GetTaxValueByGroup(futuresBatch.TaxGroup, constants.TaxTypes.IRFEE),
+	)
+
+	fmt.Println("=========================")
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type IngestionReport struct {
+	ingestion *model.Ingestion
+}
+
+func GetIngestionReport(ingestion *model.Ingestion) *IngestionReport {
+	return &IngestionReport{
+		ingestion: ingestion,
+	}
+}
+
+func (report *IngestionReport) Run() error {
+	ingestion := report.ingestion
+	countByStatus := make(map[string]int)
+
+	for _, result := range ingestion.Results {
+		countByStatus[result.Status]++
+	}
+
+	ingestionStatuses := constants.IngestionStatuses
+
+	fmt.Println("===== Ingestion =====")
+	fmt.Printf("Source ........... : %s\n", ingestion.Source)
+	fmt.Printf("Invoices ......... : %d\n", len(ingestion.Results))
+	fmt.Printf("Processed ........ : %d\n", countByStatus[ingestionStatuses.PROCESSED])
+	fmt.Printf("Skipped .......... : %d\n", countByStatus[ingestionStatuses.SKIPPED])
+	fmt.Printf("Failed ........... : %d\n", countByStatus[ingestionStatuses.FAILED])
+	fmt.Printf("Not processed .... : %d\n", countByStatus[ingestionStatuses.NOT_PROCESSED])
+	fmt.Printf("%-32s %13s  %s\n", "File", "Status", "Error")
+
+	for _, result := range ingestion.Results {
+		errorMessage := ""
+
+		if result.Err != nil {
+			errorMessage = result.Err.Error()
+		}
+
+		fmt.Printf("%-32s %13s  %s\n", result.FileName, result.Status, errorMessage)
+	}
+
+	fmt.Println("=====================")
+
+	return nil
+}
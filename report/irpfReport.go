@@ -0,0 +1,214 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type IrpfReport struct {
+	worksheet *model.IrpfWorksheet
+	format    string
+	out       io.Writer
+}
+
+func GetIrpfReport(
+	worksheet *model.IrpfWorksheet,
+	format string,
+	out io.Writer,
+) *IrpfReport {
+	return &IrpfReport{
+		worksheet: worksheet,
+		format:    format,
+		out:       out,
+	}
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+func (report *IrpfReport) runCSV() error {
+	ws := report.worksheet
+	writer := csv.NewWriter(report.out)
+
+	records := [][]string{
+		{"section", "group", "code", "ticker", "name", "type", "prevQty", "prevTotal", "qty", "total", "description"},
+	}
+
+	for _, asset := range ws.Assets {
+		records = append(records, []string{
+			"BENS_DIREITOS",
+			asset.Group,
+			asset.Code,
+			asset.Ticker,
+			asset.Name,
+			asset.AssetType,
+			strconv.FormatInt(asset.PrevQty, 10),
+			formatValue(asset.PrevTotal),
+			strconv.FormatInt(asset.Qty, 10),
+			formatValue(asset.Total),
+			asset.Description,
+		})
+	}
+
+	records = append(records, []string{
+		"section", "month", "commonResults", "commonAccLoss", "commonIRDue", "dayTradeResults", "dayTradeAccLoss", "dayTradeIRDue",
+		"fiiResults", "fiiAccLoss", "fiiIRPaid",
+	})
+
+	for _, month := range ws.Months {
+		records = append(records, []string{
+			"RENDA_VARIAVEL",
+			strconv.Itoa(month.Month),
+			formatValue(month.CommonResults),
+			formatValue(month.CommonAccLoss),
+			formatValue(month.CommonIRDue),
+			formatValue(month.DayTradeResults),
+			formatValue(month.DayTradeAccLoss),
+			formatValue(month.DayTradeIRDue),
+			formatValue(month.FiiResults),
+			formatValue(month.FiiAccLoss),
+			formatValue(month.FiiIRPaid),
+		})
+	}
+
+	records = append(records, []string{"section", "month", "totalSold", "gain"})
+
+	for _, exemptGain := range ws.ExemptGains {
+		records = append(records, []string{
+			"ISENTOS",
+			strconv.Itoa(exemptGain.Month),
+			formatValue(exemptGain.TotalSold),
+			formatValue(exemptGain.Gain),
+		})
+	}
+
+	records = append(records, []string{
+		"ISENTOS_TOTAL",
+		"",
+		"",
+		formatValue(ws.TotalExemptGain),
+	})
+
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+
+	return writer.Error()
+}
+
+func (report *IrpfReport) runJSON() error {
+	encoder := json.NewEncoder(report.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report.worksheet)
+}
+
+func (report *IrpfReport) runConsole() error {
+	ws := report.worksheet
+	out := report.out
+
+	fmt.Fprintln(out, "===== IRPF Report =====")
+	fmt.Fprintf(out, "User.name ........ : %s\n", ws.UserName)
+	fmt.Fprintf(out, "User.UUID ........ : %s\n", ws.UserUUID)
+	fmt.Fprintf(out, "Year ............. : %d\n", ws.Year)
+	fmt.Fprintf(out, "Bens e Direitos .. : %d\n", len(ws.Assets))
+	fmt.Fprintf(
+		out,
+		"%2s %2s %8s %4s %8s %12s %8s %12s\n",
+		"Gr",
+		"Cd",
+		"Tag",
+		"Type",
+		"PrevQty",
+		"PrevTotal",
+		"Qty",
+		"Total",
+	)
+
+	for _, asset := range ws.Assets {
+		fmt.Fprintf(
+			out,
+			"%2s %2s %8s %4s %8d %12.2f %8d %12.2f\n",
+			asset.Group,
+			asset.Code,
+			asset.Ticker,
+			asset.AssetType,
+			asset.PrevQty,
+			asset.PrevTotal,
+			asset.Qty,
+			asset.Total,
+		)
+	}
+
+	fmt.Fprintf(out, "Renda Variavel ... : \n")
+	fmt.Fprintf(
+		out,
+		"%3s %10s %10s %8s %10s %10s %8s %10s %10s %8s\n",
+		"Mon",
+		"Results",
+		"AccLoss",
+		"IRDue",
+		"DTResults",
+		"DTAccLoss",
+		"DTIRDue",
+		"FIIResults",
+		"FIIAccLoss",
+		"FIIIRPaid",
+	)
+
+	for _, month := range ws.Months {
+		fmt.Fprintf(
+			out,
+			"%3d %10.2f %10.2f %8.2f %10.2f %10.2f %8.2f %10.2f %10.2f %8.2f\n",
+			month.Month,
+			month.CommonResults,
+			month.CommonAccLoss,
+			month.CommonIRDue,
+			month.DayTradeResults,
+			month.DayTradeAccLoss,
+			month.DayTradeIRDue,
+			month.FiiResults,
+			month.FiiAccLoss,
+			month.FiiIRPaid,
+		)
+	}
+
+	fmt.Fprintf(out, "Isentos .......... : %d\n", len(ws.ExemptGains))
+	fmt.Fprintf(out, "%3s %12s %10s\n", "Mon", "Sold", "Gain")
+
+	for _, exemptGain := range ws.ExemptGains {
+		fmt.Fprintf(
+			out,
+			"%3d %12.2f %10.2f\n",
+			exemptGain.Month,
+			exemptGain.TotalSold,
+			exemptGain.Gain,
+		)
+	}
+
+	fmt.Fprintf(out, "Isentos.Total .... : %.2f\n", ws.TotalExemptGain)
+	fmt.Fprintln(out, "=======================")
+
+	return nil
+}
+
+func (report *IrpfReport) Run() error {
+	formats := constants.ReportFormats
+
+	switch report.format {
+	case formats.CSV:
+		return report.runCSV()
+	case formats.JSON:
+		return report.runJSON()
+	case formats.CONSOLE:
+		return report.runConsole()
+	}
+
+	return fmt.Errorf("report.IrpfReport.Run: error: unknown format %s", report.format)
+}
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type LendingReport struct {
+	lendingContracts []*model.LendingContract
+}
+
+func GetLendingReport(lendingContracts []*model.LendingContract) *LendingReport {
+	return &LendingReport{
+		lendingContracts: lendingContracts,
+	}
+}
+
+func (report *LendingReport) Run() error {
+	fmt.Println("===== Lending Contracts =====")
+	fmt.Printf("Contracts ........ : %d\n", len(report.lendingContracts))
+	fmt.Printf(
+		"%12s %8s %8s %10s %10s %8s %10s %10s %10s\n",
+		"Contract",
+		"Side",
+		"Tag",
+		"Start",
+		"End",
+		"Qty",
+		"Value",
+		"IRRF",
+		"Fees",
+	)
+
+	for _, lendingContract := range report.lendingContracts {
+		fmt.Printf(
+			"%12s %8s %8s %10s %10s %8d %10.2f %10.2f %10.2f\n",
+			lendingContract.Contract,
+			lendingContract.Side,
+			lendingContract.Company.Code,
+			lendingContract.StartDate.Format("2006-01-02"),
+			lendingContract.EndDate.Format("2006-01-02"),
+			lendingContract.Qty,
+			lendingContract.Value,
+			lendingContract.Irrf,
+			lendingContract.Fees,
+		)
+	}
+
+	fmt.Println("=============================")
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type PositionReport struct {
+	user      *entity.User
+	positions []*model.Position
+}
+
+func GetPositionReport(user *entity.User, positions []*model.Position) *PositionReport {
+	return &PositionReport{
+		user:      user,
+		positions: positions,
+	}
+}
+
+func (report *PositionReport) Run() error {
+	user := report.user
+
+	fmt.Println("===== Positions =====")
+	fmt.Printf("User.name ........ : %s\n", user.UserName)
+	fmt.Printf("User.UUID ........ : %s\n", user.UUID)
+	fmt.Printf("Positions ........ : %d\n", len(report.positions))
+	fmt.Printf(
+		"%8s %4s %8s %8s %10s %12s %8s %2s\n",
+		"Tag",
+		"Type",
+		"Broker",
+		"Qty",
+		"Avg",
+		"Total",
+		"Lent",
+		"!",
+	)
+
+	for _, position := range report.positions {
+		companyBatch := position.CompanyBatch
+
+		var brokerQty int64
+
+		for _, brokerBatch := range position.BrokerBatches {
+			brokerQty = brokerQty + brokerBatch.Qty
+		}
+
+		// custody by broker that doesn't add up to the consolidated position
+		mismatch := ""
+
+		if brokerQty != companyBatch.Qty {
+			mismatch = "*"
+		}
+
+		// lent shares stay in the position
+		lent := ""
+
+		if position.LentQty > 0 {
+			lent = fmt.Sprintf("%d", position.LentQty)
+		}
+
+		fmt.Printf(
+			"%8s %4s %8s %8d %10.4f %12.2f %8s %2s\n",
+			companyBatch.Company.Code,
+			utils.GetAssetType(companyBatch.Company),
+			"ALL",
+			companyBatch.Qty,
+			companyBatch.AvgPrice,
+			companyBatch.TotalPrice,
+			lent,
+			mismatch,
+		)
+
+		for _, brokerBatch := range position.BrokerBatches {
+			fmt.Printf(
+				"%8s %4s %8s %8d %10.4f %12.2f %8s %2s\n",
+				"",
+				"",
+				brokerBatch.AgentId,
+				brokerBatch.Qty,
+				brokerBatch.AvgPrice,
+				brokerBatch.TotalPrice,
+				"",
+				"",
+			)
+		}
+	}
+
+	fmt.Println("=====================")
+
+	return nil
+}
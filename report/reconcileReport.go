@@ -0,0 +1,60 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type ReconcileReport struct {
+	reconciliation *model.Reconciliation
+}
+
+func GetReconcileReport(reconciliation *model.Reconciliation) *ReconcileReport {
+	return &ReconcileReport{
+		reconciliation: reconciliation,
+	}
+}
+
+func (report *ReconcileReport) Run() error {
+	reconciliation := report.reconciliation
+
+	fmt.Println("===== Reconciliation =====")
+	fmt.Printf("User.name ........ : %s\n", reconciliation.User.UserName)
+	fmt.Printf("Statement ........ : %s\n", reconciliation.FileName)
+	fmt.Printf("Date ............. : %s\n", reconciliation.Date.Format("2006-01-02"))
+	fmt.Printf("By broker ........ : %t\n", reconciliation.ByBroker)
+	fmt.Printf("Matched .......... : %d\n", reconciliation.Matched)
+	fmt.Printf("Discrepancies .... : %d\n", len(reconciliation.Discrepancies))
+	fmt.Printf(
+		"%8s %8s %10s %10s %14s  %s\n",
+		"Tag",
+		"Broker",
+		"B3",
+		"Stored",
+		"Status",
+		"Hint",
+	)
+
+	for _, discrepancy := range reconciliation.Discrepancies {
+		broker := discrepancy.AgentId
+
+		if broker == "" {
+			broker = "ALL"
+		}
+
+		fmt.Printf(
+			"%8s %8s %10d %10d %14s  %s\n",
+			discrepancy.Code,
+			broker,
+			discrepancy.StatementQty,
+			discrepancy.StoredQty,
+			discrepancy.Status,
+			discrepancy.Hint,
+		)
+	}
+
+	fmt.Println("==========================")
+
+	return nil
+}
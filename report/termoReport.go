@@ -0,0 +1,54 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type TermoReport struct {
+	termoSettlements []*model.TermoSettlement
+}
+
+func GetTermoReport(termoSettlements []*model.TermoSettlement) *TermoReport {
+	return &TermoReport{
+		termoSettlements: termoSettlements,
+	}
+}
+
+func (report *TermoReport) Run() error {
+	fmt.Println("===== Termo Settlement =====")
+	fmt.Printf("Settlements ...... : %d\n", len(report.termoSettlements))
+	fmt.Printf(
+		"%10s %10s %8s %8s %12s %6s %10s %7s\n",
+		"Date",
+		"Due",
+		"Tag",
+		"Qty",
+		"Cost",
+		"Early",
+		"Avg",
+		"BatchId",
+	)
+
+	for _, termoSettlement := range report.termoSettlements {
+		termoPosition := termoSettlement.TermoPosition
+		companyBatch := termoSettlement.CompanyBatch
+
+		fmt.Printf(
+			"%10s %10s %8s %8d %12.2f %6t %10.4f %7d\n",
+			termoSettlement.SettlementDate.Format("2006-01-02"),
+			termoPosition.DueDate.Format("2006-01-02"),
+			termoPosition.Company.Code,
+			termoSettlement.Qty,
+			termoSettlement.TotalCost,
+			termoSettlement.Early,
+			companyBatch.AvgPrice,
+			companyBatch.Id,
+		)
+	}
+
+	fmt.Println("============================")
+
+	return nil
+}
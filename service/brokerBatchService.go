@@ -0,0 +1,243 @@
+package service
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type BrokerBatchService struct {
+	tx               *sql.Tx
+	companyBatch     *entity.CompanyBatch
+	agentId          string
+	brokerBatchStore *store.BrokerBatchStore
+}
+
+func GetBrokerBatchService(
+	tx *sql.Tx,
+	companyBatch *entity.CompanyBatch,
+	agentId string,
+	brokerBatchStore *store.BrokerBatchStore,
+) *BrokerBatchService {
+	return &BrokerBatchService{
+		tx:               tx,
+		companyBatch:     companyBatch,
+		agentId:          agentId,
+		brokerBatchStore: brokerBatchStore,
+	}
+}
+
+func (bbsvc *BrokerBatchService) getBrokerBatch() (*model.BrokerBatch, error) {
+	companyBatch := bbsvc.companyBatch
+	store := bbsvc.brokerBatchStore
+
+	brokerBatch := &model.BrokerBatch{
+		CompanyBatch: companyBatch,
+		User:         companyBatch.User,
+		Company:      companyBatch.Company,
+		AgentId:      bbsvc.agentId,
+	}
+
+	if store.Has(brokerBatch) {
+		return store.Get(brokerBatch), nil
+	}
+
+	brokerBatchDAO := db.GetBrokerBatchDAO(bbsvc.tx, brokerBatch)
+	brokerBatchRec, err := brokerBatchDAO.GetBrokerBatch()
+
+	if err != nil || brokerBatchRec == nil {
+		return nil, err
+	}
+
+	// the company was sold out and bought again, the broker row follows the
+	// new company batch
+	if brokerBatchRec.CompanyBatch.Id != companyBatch.Id {
+		brokerBatchDAO = db.GetBrokerBatchDAO(bbsvc.tx, brokerBatchRec)
+		brokerBatchRec, err = brokerBatchDAO.MoveBrokerBatch(companyBatch)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return store.Put(brokerBatchRec), nil
+}
+
+func (bbsvc *BrokerBatchService) saveBrokerBatch(
+	brokerBatch *model.BrokerBatch,
+	isNew bool,
+) (*model.BrokerBatch, error) {
+	if brokerBatch.Qty != 0 {
+		brokerBatch.AvgPrice = brokerBatch.TotalPrice / float64(brokerBatch.Qty)
+	}
+
+	brokerBatchDAO := db.GetBrokerBatchDAO(bbsvc.tx, brokerBatch)
+
+	var brokerBatchRec *model.BrokerBatch
+	var err error
+
+	if isNew {
+		brokerBatchRec, err = brokerBatchDAO.CreateBrokerBatch()
+	} else {
+		brokerBatchRec, err = brokerBatchDAO.UpdateBrokerBatch()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	bbsvc.brokerBatchStore.Put(brokerBatchRec)
+
+	return brokerBatchRec, nil
+}
+
+// AddQty adds an acquisition to the broker custody.
+func (bbsvc *BrokerBatchService) AddQty(
+	qty int64,
+	totalPrice float64,
+	marketDate time.Time,
+) (*model.BrokerBatch, error) {
+	brokerBatch, err := bbsvc.getBrokerBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if brokerBatch == nil {
+		companyBatch := bbsvc.companyBatch
+
+		return bbsvc.saveBrokerBatch(&model.BrokerBatch{
+			CompanyBatch: companyBatch,
+			User:         companyBatch.User,
+			Company:      companyBatch.Company,
+			AgentId:      bbsvc.agentId,
+			StartDate:    marketDate,
+			Qty:          qty,
+			TotalPrice:   totalPrice,
+		}, true)
+	}
+
+	brokerBatch.Qty = brokerBatch.Qty + qty
+	brokerBatch.TotalPrice = brokerBatch.TotalPrice + totalPrice
+
+	return bbsvc.saveBrokerBatch(brokerBatch, false)
+}
+
+// removeUntrackedQty takes up to qty out of the custody held before brokers
+// were tracked, kept under an empty agent, and returns the quantity taken.
+func (bbsvc *BrokerBatchService) removeUntrackedQty(qty int64) (int64, error) {
+	untrackedService := GetBrokerBatchService(
+		bbsvc.tx,
+		bbsvc.companyBatch,
+		"",
+		bbsvc.brokerBatchStore,
+	)
+
+	untrackedBatch, err := untrackedService.getBrokerBatch()
+
+	if err != nil || untrackedBatch == nil || untrackedBatch.Qty <= 0 {
+		return 0, err
+	}
+
+	if untrackedBatch.Qty < qty {
+		qty = untrackedBatch.Qty
+	}
+
+	log.Printf(
+		"BrokerBatchService.removeUntrackedQty: WARNING: %d %s sold at broker %s taken from untracked custody",
+		qty,
+		bbsvc.companyBatch.Company.Code,
+		bbsvc.agentId,
+	)
+
+	untrackedBatch.Qty = untrackedBatch.Qty - qty
+	untrackedBatch.TotalPrice = untrackedBatch.AvgPrice * float64(untrackedBatch.Qty)
+
+	if _, err := untrackedService.saveBrokerBatch(untrackedBatch, false); err != nil {
+		return 0, err
+	}
+
+	return qty, nil
+}
+
+// RemoveQty takes a sale out of the broker custody at the broker's own average
+// price, the part above it comes from the untracked custody and sales without
+// any known custody leave a negative balance to reconcile.
+func (bbsvc *BrokerBatchService) RemoveQty(
+	qty int64,
+	marketDate time.Time,
+) (*model.BrokerBatch, error) {
+	brokerBatch, err := bbsvc.getBrokerBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var heldQty int64
+
+	if brokerBatch != nil {
+		heldQty = brokerBatch.Qty
+	}
+
+	if qty > heldQty && bbsvc.agentId != "" {
+		untrackedQty, err := bbsvc.removeUntrackedQty(qty - heldQty)
+
+		if err != nil {
+			return nil, err
+		}
+
+		qty = qty - untrackedQty
+
+		if qty == 0 {
+			return brokerBatch, nil
+		}
+	}
+
+	if brokerBatch == nil {
+		companyBatch := bbsvc.companyBatch
+
+		log.Printf(
+			"BrokerBatchService.RemoveQty: WARNING: no custody of %s at broker %s",
+			companyBatch.Company.Code,
+			bbsvc.agentId,
+		)
+
+		return bbsvc.saveBrokerBatch(&model.BrokerBatch{
+			CompanyBatch: companyBatch,
+			User:         companyBatch.User,
+			Company:      companyBatch.Company,
+			AgentId:      bbsvc.agentId,
+			StartDate:    marketDate,
+			Qty:          -qty,
+			TotalPrice:   -(companyBatch.AvgPrice * float64(qty)),
+		}, true)
+	}
+
+	brokerBatch.Qty = brokerBatch.Qty - qty
+	brokerBatch.TotalPrice = brokerBatch.AvgPrice * float64(brokerBatch.Qty)
+
+	return bbsvc.saveBrokerBatch(brokerBatch, false)
+}
+
+// AdjustCost adds delta to the broker cost basis keeping the quantity, used by
+// spin-offs moving part of the cost to another company.
+func (bbsvc *BrokerBatchService) AdjustCost(delta float64) (*model.BrokerBatch, error) {
+	brokerBatch, err := bbsvc.getBrokerBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if brokerBatch == nil {
+		return nil, nil
+	}
+
+	brokerBatch.TotalPrice = brokerBatch.TotalPrice + delta
+
+	return bbsvc.saveBrokerBatch(brokerBatch, false)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type BrokerageService struct {
+	broker         *model.Broker
+	marketDate     utils.B3Date
+	brokerTaxStore *store.BrokerTaxStore
+	taxRateStore   *store.TaxRateStore
+}
+
+func GetBrokerageService(
+	broker *model.Broker,
+	marketDate utils.B3Date,
+	brokerTaxStore *store.BrokerTaxStore,
+	taxRateStore *store.TaxRateStore,
+) *BrokerageService {
+	return &BrokerageService{
+		broker:         broker,
+		marketDate:     marketDate,
+		brokerTaxStore: brokerTaxStore,
+		taxRateStore:   taxRateStore,
+	}
+}
+
+func (bsvc *BrokerageService) getFlatFee() float64 {
+	if bsvc.broker.FlatFee > 0 {
+		return bsvc.broker.FlatFee
+	}
+
+	return bsvc.taxRateStore.Get(constants.RateCodes.BRKFEE, bsvc.marketDate)
+}
+
+// GetIssRate the ISS rate of the broker, the rate table one when the broker
+// has none set; a zero rate set on the broker is kept.
+func (bsvc *BrokerageService) GetIssRate() float64 {
+	if bsvc.broker.IssRate != nil {
+		return *bsvc.broker.IssRate
+	}
+
+	return bsvc.taxRateStore.Get(constants.RateCodes.ISSSPFEE, bsvc.marketDate)
+}
+
+func (bsvc *BrokerageService) getTieredFee(value float64) float64 {
+	for _, tier := range bsvc.broker.Tiers {
+		if tier.UpTo == 0 || value <= tier.UpTo {
+			return tier.Fixed + (value * tier.Rate)
+		}
+	}
+
+	return 0
+}
+
+// getBrokerage returns the brokerage of one item, ignoring fees already charged.
+func (bsvc *BrokerageService) getBrokerage(item *entity.InvoiceItem) float64 {
+	brokerageModels := constants.BrokerageModels
+
+	switch bsvc.broker.BrokerageModel {
+	case brokerageModels.FLAT_ORDER, brokerageModels.PER_COMPANY:
+		return bsvc.getFlatFee()
+	case brokerageModels.TIERED:
+		return bsvc.getTieredFee(item.Price * float64(item.Qty))
+	}
+
+	return 0
+}
+
+// isCharged tells if the item pays taxCode, per company brokerage is charged
+// once per company and side on each invoice.
+func (bsvc *BrokerageService) isCharged(item *entity.InvoiceItem, taxCode string) bool {
+	if bsvc.broker.BrokerageModel != constants.BrokerageModels.PER_COMPANY {
+		return true
+	}
+
+	if bsvc.brokerTaxStore.Has(item, taxCode) {
+		return false
+	}
+
+	bsvc.brokerTaxStore.Put(item, taxCode)
+
+	return true
+}
+
+// GetTaxValue returns the BRKFEE or ISSSPFEE value of an invoice item.
+func (bsvc *BrokerageService) GetTaxValue(item *entity.InvoiceItem, taxCode string) float64 {
+	if !bsvc.isCharged(item, taxCode) {
+		return 0
+	}
+
+	brokerage := bsvc.getBrokerage(item)
+
+	if taxCode == constants.TaxTypes.BRKFEE {
+		return brokerage
+	}
+
+	// t = (b / (1 - i)) - c
+	return (brokerage / (1 - bsvc.GetIssRate())) - brokerage
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// CompanyBatchService changes the open company batch of a user outside of an
+// invoice (custody transfers, corporate events).
+type CompanyBatchService struct {
+	tx                *sql.Tx
+	user              *entity.User
+	company           *entity.Company
+	companyBatchStore *store.CompanyBatchStore
+}
+
+func GetCompanyBatchService(
+	tx *sql.Tx,
+	user *entity.User,
+	company *entity.Company,
+	companyBatchStore *store.CompanyBatchStore,
+) *CompanyBatchService {
+	return &CompanyBatchService{
+		tx:                tx,
+		user:              user,
+		company:           company,
+		companyBatchStore: companyBatchStore,
+	}
+}
+
+// GetCompanyBatch returns the open company batch, nil when there is none.
+func (cbsvc *CompanyBatchService) GetCompanyBatch() (*entity.CompanyBatch, error) {
+	companyBatch := &entity.CompanyBatch{
+		User:    cbsvc.user,
+		Company: cbsvc.company,
+	}
+
+	if cbsvc.companyBatchStore.Has(companyBatch) {
+		return cbsvc.companyBatchStore.Get(companyBatch), nil
+	}
+
+	companyBatchDAO := db.GetCompanyBatchDAO(cbsvc.tx, companyBatch)
+	return companyBatchDAO.GetCompanyBatch()
+}
+
+// UpdateCompanyBatch saves qty and total price, the average price is derived.
+func (cbsvc *CompanyBatchService) UpdateCompanyBatch(
+	companyBatch *entity.CompanyBatch,
+	qty int64,
+	totalPrice float64,
+) (*entity.CompanyBatch, error) {
+	avgPrice := 0.0
+
+	if qty != 0 {
+		avgPrice = totalPrice / float64(qty)
+	}
+
+	companyBatchDAO := db.GetCompanyBatchDAO(cbsvc.tx, &entity.CompanyBatch{
+		Id:         companyBatch.Id,
+		User:       companyBatch.User,
+		Company:    companyBatch.Company,
+		StartDate:  companyBatch.StartDate,
+		Qty:        qty,
+		AvgPrice:   avgPrice,
+		TotalPrice: totalPrice,
+	})
+
+	companyBatchRec, err := companyBatchDAO.UpdateCompanyBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	cbsvc.companyBatchStore.Put(companyBatchRec)
+	return companyBatchRec, nil
+}
+
+// AddQty merges an acquisition into the open company batch, the same way
+// ItemBatchService does for invoice items.
+func (cbsvc *CompanyBatchService) AddQty(
+	qty int64,
+	totalPrice float64,
+	marketDate time.Time,
+) (*entity.CompanyBatch, error) {
+	companyBatchRec, err := cbsvc.GetCompanyBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if companyBatchRec != nil {
+		return cbsvc.UpdateCompanyBatch(
+			companyBatchRec,
+			companyBatchRec.Qty+qty,
+			companyBatchRec.TotalPrice+totalPrice,
+		)
+	}
+
+	companyBatchDAO := db.GetCompanyBatchDAO(cbsvc.tx, &entity.CompanyBatch{
+		User:       cbsvc.user,
+		Company:    cbsvc.company,
+		StartDate:  marketDate,
+		Qty:        qty,
+		AvgPrice:   totalPrice / float64(qty),
+		TotalPrice: totalPrice,
+	})
+
+	newCompanyBatchRec, err := companyBatchDAO.CreateCompanyBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	cbsvc.companyBatchStore.Put(newCompanyBatchRec)
+	return newCompanyBatchRec, nil
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type CompanyResolverService struct {
+	tx                *sql.Tx
+	companyAliasStore *store.CompanyAliasStore
+	tickerStore       *store.TickerStore
+	candidates        map[string][]string
+}
+
+func GetCompanyResolverService(
+	tx *sql.Tx,
+	companyAliasStore *store.CompanyAliasStore,
+	tickerStore *store.TickerStore,
+) *CompanyResolverService {
+	return &CompanyResolverService{
+		tx:                tx,
+		companyAliasStore: companyAliasStore,
+		tickerStore:       tickerStore,
+	}
+}
+
+// LoadCompanyAliases fills the alias store from the company_alias table,
+// then from the file set on B3_COMPANY_ALIAS_FILE.
+func LoadCompanyAliases(tx *sql.Tx, companyAliasStore *store.CompanyAliasStore) (*store.CompanyAliasStore, error) {
+	companyAliasDAO := db.GetCompanyAliasDAO(tx, &model.CompanyAlias{})
+	companyAliases, err := companyAliasDAO.LoadCompanyAliases()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range companyAliases {
+		companyAliasStore.Put(&companyAliases[idx])
+	}
+
+	fileName, ok := os.LookupEnv("B3_COMPANY_ALIAS_FILE")
+
+	if ok && fileName != "" {
+		if err := companyAliasStore.LoadFile(fileName); err != nil {
+			return nil, err
+		}
+	}
+
+	return companyAliasStore, nil
+}
+
+// minIssuerSimilarity is the lowest issuer similarity accepted by the last
+// resolution step.
+const minIssuerSimilarity = 0.75
+
+// getCandidates returns the issuers known for each ticker, from the company
+// table (SINACOR names of earlier notes) and the ticker registry issuers. It
+// is built on the first call and kept for the service life.
+func (crsvc *CompanyResolverService) getCandidates() (map[string][]string, error) {
+	if crsvc.candidates != nil {
+		return crsvc.candidates, nil
+	}
+
+	companyDAO := db.GetCompanyDAO(crsvc.tx, &entity.Company{})
+	companies, err := companyDAO.GetCompanies()
+
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make(map[string][]string)
+
+	addCandidate := func(code string, name string) {
+		issuer, _ := utils.ParseSecurityName(name)
+
+		if issuer != "" {
+			candidates[code] = append(candidates[code], issuer)
+		}
+	}
+
+	for _, company := range companies {
+		addCandidate(company.Code, company.Name)
+	}
+
+	for _, ticker := range crsvc.tickerStore.GetTickers() {
+		addCandidate(ticker.Code, ticker.Issuer)
+	}
+
+	crsvc.candidates = candidates
+
+	return candidates, nil
+}
+
+// matchCodes returns the tickers of class whose issuer satisfies match.
+func matchCodes(
+	candidates map[string][]string,
+	class string,
+	match func(issuer string) bool,
+) []string {
+	codes := make([]string, 0)
+
+	for code, issuers := range candidates {
+		if !utils.HasClassSuffix(code, class) {
+			continue
+		}
+
+		for _, issuer := range issuers {
+			if match(issuer) {
+				codes = append(codes, code)
+				break
+			}
+		}
+	}
+
+	sort.Strings(codes)
+
+	return codes
+}
+
+// matchSimilarCodes returns the tickers of class whose issuer is the most
+// similar to issuer, none when the best similarity is below
+// minIssuerSimilarity.
+func matchSimilarCodes(
+	candidates map[string][]string,
+	class string,
+	issuer string,
+) []string {
+	codes := make([]string, 0)
+	bestSimilarity := minIssuerSimilarity
+
+	for code, issuers := range candidates {
+		if !utils.HasClassSuffix(code, class) {
+			continue
+		}
+
+		similarity := 0.0
+
+		for _, candidate := range issuers {
+			candidateSimilarity := utils.GetIssuerSimilarity(issuer, candidate)
+
+			if candidateSimilarity > similarity {
+				similarity = candidateSimilarity
+			}
+		}
+
+		if similarity > bestSimilarity {
+			bestSimilarity = similarity
+			codes = codes[:0]
+		}
+
+		if similarity == bestSimilarity {
+			codes = append(codes, code)
+		}
+	}
+
+	sort.Strings(codes)
+
+	return codes
+}
+
+// ResolveCode returns the ticker of a security specification, aliases are
+// checked first, then issuer names equal to the one on the note, issuer names
+// starting with it (or the other way around) and last the most similar issuer
+// names. More than one ticker on the first step that finds any is an error.
+func (crsvc *CompanyResolverService) ResolveCode(name string) (string, error) {
+	if crsvc.companyAliasStore.Has(name) {
+		companyAlias := crsvc.companyAliasStore.Get(name)
+
+		log.Printf(
+			"companyResolverService.ResolveCode: alias [%s] resolved to %s",
+			name,
+			companyAlias.Code,
+		)
+
+		return companyAlias.Code, nil
+	}
+
+	issuer, class := utils.ParseSecurityName(name)
+
+	if issuer == "" {
+		details := fmt.Sprintf("[%s]", name)
+		return "", utils.GetError("companyResolverService.ResolveCode", "ERR_CMP_002", details)
+	}
+
+	candidates, err := crsvc.getCandidates()
+
+	if err != nil {
+		return "", err
+	}
+
+	codes := matchCodes(candidates, class, func(candidate string) bool {
+		return candidate == issuer
+	})
+
+	if len(codes) == 0 {
+		codes = matchCodes(candidates, class, func(candidate string) bool {
+			return strings.HasPrefix(candidate, issuer+" ") || strings.HasPrefix(issuer, candidate+" ")
+		})
+	}
+
+	if len(codes) == 0 {
+		codes = matchSimilarCodes(candidates, class, issuer)
+	}
+
+	if len(codes) == 0 {
+		details := fmt.Sprintf("[%s]", name)
+		return "", utils.GetError("companyResolverService.ResolveCode", "ERR_CMP_002", details)
+	}
+
+	if len(codes) > 1 {
+		details := fmt.Sprintf("[%s, candidates = %s]", name, strings.Join(codes, ", "))
+		return "", utils.GetError("companyResolverService.ResolveCode", "ERR_CMP_001", details)
+	}
+
+	log.Printf(
+		"companyResolverService.ResolveCode: [%s] resolved to %s",
+		name,
+		codes[0],
+	)
+
+	return codes[0], nil
+}
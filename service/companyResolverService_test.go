@@ -0,0 +1,50 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestMatchSimilarCodes resolves the SINACOR name "PETROBRAS PN" against the
+// registry issuer "PETROLEO BRASILEIRO S.A. PETROBRAS", while a token shared
+// by two issuers stays ambiguous.
+func TestMatchSimilarCodes(t *testing.T) {
+	candidates := map[string][]string{
+		"PETR3": {"PETROLEO BRASILEIRO S A PETROBRAS"},
+		"PETR4": {"PETROLEO BRASILEIRO S A PETROBRAS"},
+		"BBAS3": {"BANCO DO BRASIL S A"},
+		"BRAP4": {"BRADESPAR S A"},
+		"BRKM5": {"BRASKEM S A"},
+		"SANB4": {"BANCO SANTANDER BRASIL S A"},
+	}
+
+	codes := matchSimilarCodes(candidates, "PN", "PETROBRAS")
+
+	if strings.Join(codes, ", ") != "PETR4" {
+		t.Errorf("PETROBRAS PN = [%s], want [PETR4]", strings.Join(codes, ", "))
+	}
+
+	codes = matchSimilarCodes(candidates, "ON", "BRASIL")
+
+	if strings.Join(codes, ", ") != "BBAS3" {
+		t.Errorf("BRASIL ON = [%s], want [BBAS3]", strings.Join(codes, ", "))
+	}
+
+	codes = matchSimilarCodes(candidates, "PN", "BANCO BRASIL")
+
+	if strings.Join(codes, ", ") != "SANB4" {
+		t.Errorf("BANCO BRASIL PN = [%s], want [SANB4]", strings.Join(codes, ", "))
+	}
+
+	codes = matchSimilarCodes(candidates, "", "BRASIL")
+
+	if len(codes) != 2 {
+		t.Errorf("BRASIL = [%s], want two ambiguous codes", strings.Join(codes, ", "))
+	}
+
+	codes = matchSimilarCodes(candidates, "PN", "VALE")
+
+	if len(codes) != 0 {
+		t.Errorf("VALE PN = [%s], want none", strings.Join(codes, ", "))
+	}
+}
@@ -2,10 +2,13 @@ package service
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
 	"github.com/jarismar/b3c-invoice-reader-lambda/db"
 	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
 	"github.com/jarismar/b3c-service-entities/entity"
 )
 
@@ -13,24 +16,78 @@ type CompanyService struct {
 	tx           *sql.Tx
 	company      *entity.Company
 	companyStore *store.CompanyStore
+	tickerStore  *store.TickerStore
 }
 
 func GetCompanyService(
 	tx *sql.Tx,
 	company *entity.Company,
 	companyStore *store.CompanyStore,
+	tickerStore *store.TickerStore,
 ) *CompanyService {
 	return &CompanyService{
 		tx:           tx,
 		company:      company,
 		companyStore: companyStore,
+		tickerStore:  tickerStore,
 	}
 }
 
+// GetAssetClass returns the registry asset class of company, tickers missing
+// from the registry fall back to the company flags and the ticker suffix.
+func GetAssetClass(tickerStore *store.TickerStore, company *entity.Company) string {
+	assetClasses := constants.AssetClasses
+
+	if tickerStore.Has(company.Code) {
+		return tickerStore.Get(company.Code).AssetClass
+	}
+
+	if utils.IsOption(company.Code) {
+		return assetClasses.OPTION
+	}
+
+	if company.BDR || utils.IsBDR(company) {
+		return assetClasses.BDR
+	}
+
+	if company.ETF {
+		return assetClasses.ETF
+	}
+
+	if utils.IsFII(company) {
+		return assetClasses.FII
+	}
+
+	return assetClasses.STOCK
+}
+
+// classify sets the BDR and ETF flags, it tells if the registry knows the
+// ticker so existing records can be corrected.
+func (csvc *CompanyService) classify(company *entity.Company) bool {
+	assetClasses := constants.AssetClasses
+	assetClass := GetAssetClass(csvc.tickerStore, company)
+
+	company.BDR = (assetClass == assetClasses.BDR)
+	company.ETF = (assetClass == assetClasses.ETF)
+
+	return csvc.tickerStore.Has(company.Code)
+}
+
+// UpsertCompany codes replaced by a ticker change resolve to the successor.
 func (csvc *CompanyService) UpsertCompany() (*entity.Company, error) {
 	companyStore := csvc.companyStore
 	company := csvc.company
 
+	if successor := csvc.tickerStore.GetSuccessor(company.Code); successor != company.Code {
+		log.Printf(
+			"companyService.UpsertCompany: ticker %s replaced by %s",
+			company.Code,
+			successor,
+		)
+
+		company.Code = successor
+	}
+
 	if companyStore.Has(company) {
 		companyRec := companyStore.Get(csvc.company)
 
@@ -42,6 +99,13 @@ func (csvc *CompanyService) UpsertCompany() (*entity.Company, error) {
 		return companyRec, nil
 	}
 
+	if company.Code == "" {
+		details := fmt.Sprintf("missing ticker [%s]", company.Name)
+		return nil, utils.GetError("companyService.UpsertCompany", "ERR_SYS_001", details)
+	}
+
+	registered := csvc.classify(company)
+
 	companyDAO := db.GetCompanyDAO(csvc.tx, company)
 	companyRec, err := companyDAO.GetCompany()
 
@@ -52,6 +116,15 @@ func (csvc *CompanyService) UpsertCompany() (*entity.Company, error) {
 	if companyRec == nil {
 		companyRec, err = companyDAO.CreateCompany()
 
+		if err != nil {
+			return nil, err
+		}
+	} else if registered && (companyRec.BDR != company.BDR || companyRec.ETF != company.ETF) {
+		companyRec.BDR = company.BDR
+		companyRec.ETF = company.ETF
+
+		companyRec, err = db.GetCompanyDAO(csvc.tx, companyRec).UpdateCompanyFlags()
+
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,1118 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type CorporateEventService struct {
+	tx                    *sql.Tx
+	eventInput            *input.CorporateEvents
+	taxStore              *store.TaxStore
+	companyStore          *store.CompanyStore
+	companyBatchStore     *store.CompanyBatchStore
+	taxRateStore          *store.TaxRateStore
+	brokerBatchStore      *store.BrokerBatchStore
+	tickerStore           *store.TickerStore
+	fiiTradeBatchStore    *store.FiiTradeBatchStore
+	optionTradeBatchStore *store.OptionTradeBatchStore
+	companyResolver       *CompanyResolverService
+}
+
+func GetCorporateEventService(
+	tx *sql.Tx,
+	eventInput *input.CorporateEvents,
+	taxStore *store.TaxStore,
+	companyStore *store.CompanyStore,
+	companyBatchStore *store.CompanyBatchStore,
+	taxRateStore *store.TaxRateStore,
+	brokerBatchStore *store.BrokerBatchStore,
+	tickerStore *store.TickerStore,
+	companyAliasStore *store.CompanyAliasStore,
+	fiiTradeBatchStore *store.FiiTradeBatchStore,
+	optionTradeBatchStore *store.OptionTradeBatchStore,
+) *CorporateEventService {
+	return &CorporateEventService{
+		tx:                    tx,
+		eventInput:            eventInput,
+		taxStore:              taxStore,
+		companyStore:          companyStore,
+		companyBatchStore:     companyBatchStore,
+		taxRateStore:          taxRateStore,
+		brokerBatchStore:      brokerBatchStore,
+		tickerStore:           tickerStore,
+		fiiTradeBatchStore:    fiiTradeBatchStore,
+		optionTradeBatchStore: optionTradeBatchStore,
+		companyResolver:       GetCompanyResolverService(tx, companyAliasStore, tickerStore),
+	}
+}
+
+func (cesvc *CorporateEventService) validateEvent(event *input.CorporateEventItem) error {
+	eventTypes := constants.CorporateEventTypes
+
+	valid := event.FromQty > 0 && event.ToQty > 0 && event.FractionPrice >= 0
+
+	switch event.Type {
+	case eventTypes.INCORPORATION, eventTypes.RIGHTS_GRANT:
+	case eventTypes.SPIN_OFF:
+		valid = valid && event.CostRate > 0 && event.CostRate < 1
+	case eventTypes.SUBSCRIPTION:
+		// only whole shares are subscribed
+		valid = valid && event.Qty >= 0 && event.Price > 0 && (event.Qty*event.ToQty)%event.FromQty == 0
+	case eventTypes.UNIT_SPLIT, eventTypes.UNIT_MERGE:
+		// the unit composition replaces the conversion ratio
+		valid = event.Qty >= 0
+	default:
+		valid = false
+	}
+
+	if !valid {
+		details := fmt.Sprintf(
+			"[%s, %s, %s -> %s, %d:%d, qty = %d, rate = %f]",
+			cesvc.eventInput.FileName,
+			event.Type,
+			event.Company.Code,
+			event.Target.Code,
+			event.FromQty,
+			event.ToQty,
+			event.Qty,
+			event.CostRate,
+		)
+		return utils.GetError("corporateEventService.validateEvent", "ERR_SYS_001", details)
+	}
+
+	return nil
+}
+
+// removesSource tells if the event takes the source shares out of the
+// position, spin-offs and rights grants keep them.
+func removesSource(eventType string) bool {
+	eventTypes := constants.CorporateEventTypes
+
+	switch eventType {
+	case eventTypes.INCORPORATION,
+		eventTypes.SUBSCRIPTION,
+		eventTypes.UNIT_SPLIT,
+		eventTypes.UNIT_MERGE:
+		return true
+	}
+
+	return false
+}
+
+func isUnitConversion(eventType string) bool {
+	eventTypes := constants.CorporateEventTypes
+	return eventType == eventTypes.UNIT_SPLIT || eventType == eventTypes.UNIT_MERGE
+}
+
+func (cesvc *CorporateEventService) upsertCompany(inputCompany *input.Company) (*entity.Company, error) {
+	inputCode := inputCompany.Code
+
+	if inputCode == "" {
+		resolvedCode, err := cesvc.companyResolver.ResolveCode(inputCompany.Name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		inputCode = resolvedCode
+	}
+
+	code, _ := utils.NormalizeTicker(inputCode)
+
+	companyService := GetCompanyService(
+		cesvc.tx,
+		&entity.Company{
+			Code: code,
+			Name: inputCompany.Name,
+		},
+		cesvc.companyStore,
+		cesvc.tickerStore,
+	)
+
+	return companyService.UpsertCompany()
+}
+
+// moveBrokerQty applies the event to the custody of each broker, the source
+// shares used are taken from the brokers holding the most first, each broker
+// quantity is converted on its own and the units lost to rounding go to the
+// broker holding the most shares.
+func (cesvc *CorporateEventService) moveBrokerQty(
+	sourceBatch *entity.CompanyBatch,
+	targetBatch *entity.CompanyBatch,
+	corporateEvent *model.CorporateEvent,
+) error {
+	brokerBatchDAO := db.GetBrokerBatchDAO(cesvc.tx, &model.BrokerBatch{
+		CompanyBatch: sourceBatch,
+	})
+
+	brokerBatches, err := brokerBatchDAO.GetBrokerBatchesByCompanyBatch()
+
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(brokerBatches, func(i, j int) bool {
+		return brokerBatches[i].Qty > brokerBatches[j].Qty
+	})
+
+	sourceQtys := make([]int64, len(brokerBatches))
+	targetQtys := make([]int64, len(brokerBatches))
+	leftSourceQty := corporateEvent.SourceQty
+	leftTargetQty := corporateEvent.TargetQty
+
+	for idx, brokerBatch := range brokerBatches {
+		if brokerBatch.Qty <= 0 || leftSourceQty <= 0 {
+			continue
+		}
+
+		sourceQtys[idx] = brokerBatch.Qty
+
+		if sourceQtys[idx] > leftSourceQty {
+			sourceQtys[idx] = leftSourceQty
+		}
+
+		targetQtys[idx] = sourceQtys[idx] * corporateEvent.ToQty / corporateEvent.FromQty
+		leftSourceQty = leftSourceQty - sourceQtys[idx]
+		leftTargetQty = leftTargetQty - targetQtys[idx]
+	}
+
+	if len(brokerBatches) > 0 && leftTargetQty > 0 {
+		targetQtys[0] = targetQtys[0] + leftTargetQty
+	}
+
+	targetCost := corporateEvent.CostMoved + corporateEvent.Cash - corporateEvent.FractionCost
+
+	for idx, brokerBatch := range brokerBatches {
+		if sourceQtys[idx] == 0 {
+			continue
+		}
+
+		sourceService := GetBrokerBatchService(
+			cesvc.tx,
+			sourceBatch,
+			brokerBatch.AgentId,
+			cesvc.brokerBatchStore,
+		)
+
+		brokerShare := float64(sourceQtys[idx]) / float64(corporateEvent.SourceQty)
+
+		if removesSource(corporateEvent.Type) {
+			_, err = sourceService.RemoveQty(sourceQtys[idx], corporateEvent.MarketDate)
+		} else if corporateEvent.CostMoved != 0 {
+			_, err = sourceService.AdjustCost(-(corporateEvent.CostMoved * brokerShare))
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if targetQtys[idx] == 0 || targetBatch == nil {
+			continue
+		}
+
+		targetShare := float64(targetQtys[idx]) / float64(corporateEvent.TargetQty)
+
+		targetService := GetBrokerBatchService(
+			cesvc.tx,
+			targetBatch,
+			brokerBatch.AgentId,
+			cesvc.brokerBatchStore,
+		)
+
+		_, err = targetService.AddQty(targetQtys[idx], targetCost*targetShare, corporateEvent.MarketDate)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createFractionTrade sells the fraction of the target company for cash, the
+// trade has no invoice item and zero quantity as no whole share was held.
+func (cesvc *CorporateEventService) createFractionTrade(
+	user *entity.User,
+	targetBatch *entity.CompanyBatch,
+	corporateEvent *model.CorporateEvent,
+) (*entity.Trade, error) {
+	marketDate := corporateEvent.MarketDate
+
+	groupId, err := utils.GetTaxGroupIdFromTime(marketDate, constants.TaxGroupPrefix.TRADE)
+
+	if err != nil {
+		return nil, err
+	}
+
+	taxGroupService := GetTaxGroupService(cesvc.tx, &entity.TaxGroup{
+		Source:     entity.TRD,
+		ExternalId: groupId,
+		Taxes:      []entity.TaxInstance{},
+	}, cesvc.taxStore)
+
+	taxGroup, err := taxGroupService.CreateTaxGroup()
+
+	if err != nil {
+		return nil, err
+	}
+
+	tradeBatchService := GetTradeBatchService(
+		cesvc.tx,
+		user,
+		nil,
+		cesvc.taxStore,
+		cesvc.taxRateStore,
+		cesvc.tickerStore,
+		cesvc.fiiTradeBatchStore,
+		cesvc.optionTradeBatchStore,
+	)
+	tradeBatch, err := tradeBatchService.FindTradeBatch(marketDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// priced per share, the fraction quantity and cash are on the event
+	fractionPrice := 0.0
+
+	if corporateEvent.FractionQty > 0 {
+		fractionPrice = corporateEvent.FractionCash / corporateEvent.FractionQty
+	}
+
+	trade := &entity.Trade{
+		TaxGroup: taxGroup,
+		Item: &entity.InvoiceItem{
+			Company:    corporateEvent.Target,
+			MarketDate: marketDate,
+			Qty:        0,
+			Price:      fractionPrice,
+		},
+		TradeBatch:   tradeBatch,
+		CompanyBatch: targetBatch,
+		MarketDate:   marketDate,
+		Qty:          0,
+		AvgPrice:     fractionPrice,
+		RawResults:   corporateEvent.FractionCash - corporateEvent.FractionCost,
+		RawPrice:     fractionPrice,
+		TotalTax:     0,
+	}
+
+	tradeRec, err := db.GetTradeDAO(cesvc.tx, trade).CreateTrade()
+
+	if err != nil {
+		return nil, err
+	}
+
+	tradeBatchService = GetTradeBatchService(
+		cesvc.tx,
+		user,
+		tradeBatch,
+		cesvc.taxStore,
+		cesvc.taxRateStore,
+		cesvc.tickerStore,
+		cesvc.fiiTradeBatchStore,
+		cesvc.optionTradeBatchStore,
+	)
+	tradeBatchService.ProcessFraction(tradeRec, corporateEvent.FractionCash)
+
+	if tradeRec.TradeBatch, err = tradeBatchService.SaveTradeBatch(); err != nil {
+		return nil, err
+	}
+
+	return tradeRec, nil
+}
+
+// getGcd returns the greatest common divisor of a and b.
+func getGcd(a int64, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
+
+// getSourceUsage returns the source shares the event applies to and the
+// share of the source cost moved to the target. Subscriptions without qty use
+// the most rights giving whole shares, more rights than held is an error.
+func getSourceUsage(
+	event *input.CorporateEventItem,
+	sourceBatch *entity.CompanyBatch,
+) (int64, float64, error) {
+	eventTypes := constants.CorporateEventTypes
+
+	switch event.Type {
+	case eventTypes.INCORPORATION:
+		return sourceBatch.Qty, 1, nil
+	case eventTypes.SPIN_OFF:
+		return sourceBatch.Qty, event.CostRate, nil
+	case eventTypes.SUBSCRIPTION:
+		qty := event.Qty
+
+		if qty > sourceBatch.Qty {
+			details := fmt.Sprintf(
+				"[%s, qty = %d, held = %d]",
+				sourceBatch.Company.Code,
+				qty,
+				sourceBatch.Qty,
+			)
+			return 0, 0, utils.GetError("corporateEventService.getSourceUsage", "ERR_SYS_001", details)
+		}
+
+		if qty == 0 {
+			// rights come in multiples of FromQty/gcd to give whole shares
+			step := event.FromQty / getGcd(event.FromQty, event.ToQty)
+			qty = sourceBatch.Qty - sourceBatch.Qty%step
+		}
+
+		return qty, float64(qty) / float64(sourceBatch.Qty), nil
+	}
+
+	// rights are received at zero cost
+	return sourceBatch.Qty, 0, nil
+}
+
+// validateEventDate rejects an event dated before the latest movement of an
+// affected batch, its cost split would miss the movements in between.
+func (cesvc *CorporateEventService) validateEventDate(
+	companyBatch *entity.CompanyBatch,
+	marketDate time.Time,
+) error {
+	if companyBatch == nil || companyBatch.Id == 0 {
+		return nil
+	}
+
+	companyBatchDAO := db.GetCompanyBatchDAO(cesvc.tx, companyBatch)
+	lastMarketDate, err := companyBatchDAO.GetLastMarketDate()
+
+	if err != nil {
+		return err
+	}
+
+	if !lastMarketDate.IsZero() && marketDate.Before(lastMarketDate.Time()) {
+		details := fmt.Sprintf(
+			"[%s, %s on %s is before its last movement on %s]",
+			cesvc.eventInput.FileName,
+			companyBatch.Company.Code,
+			utils.B3DateOf(marketDate),
+			lastMarketDate,
+		)
+		return utils.GetError("corporateEventService.validateEventDate", "ERR_SYS_001", details)
+	}
+
+	return nil
+}
+
+func (cesvc *CorporateEventService) processEvent(
+	user *entity.User,
+	event *input.CorporateEventItem,
+) (*model.CorporateEvent, error) {
+	if err := cesvc.validateEvent(event); err != nil {
+		return nil, err
+	}
+
+	marketDate, err := utils.ParseB3Date(event.Date)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sourceCompany, err := cesvc.upsertCompany(&event.Company)
+
+	if err != nil {
+		return nil, err
+	}
+
+	targetCompany, err := cesvc.upsertCompany(&event.Target)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sourceService := GetCompanyBatchService(cesvc.tx, user, sourceCompany, cesvc.companyBatchStore)
+	sourceBatch, err := sourceService.GetCompanyBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if sourceBatch == nil || sourceBatch.Qty <= 0 {
+		details := fmt.Sprintf("[%s, no position on %s]", cesvc.eventInput.FileName, sourceCompany.Code)
+		return nil, utils.GetError("corporateEventService.processEvent", "ERR_SYS_001", details)
+	}
+
+	if err = cesvc.validateEventDate(sourceBatch, marketDate.Time()); err != nil {
+		return nil, err
+	}
+
+	targetService := GetCompanyBatchService(cesvc.tx, user, targetCompany, cesvc.companyBatchStore)
+	targetBatch, err := targetService.GetCompanyBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cesvc.validateEventDate(targetBatch, marketDate.Time()); err != nil {
+		return nil, err
+	}
+
+	eventTypes := constants.CorporateEventTypes
+	usedQty, costRate, err := getSourceUsage(event, sourceBatch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	targetQty := float64(usedQty) * float64(event.ToQty) / float64(event.FromQty)
+	wholeQty := math.Floor(targetQty)
+	costMoved := sourceBatch.TotalPrice * costRate
+	fractionQty := targetQty - wholeQty
+
+	corporateEvent := &model.CorporateEvent{
+		User:        user,
+		Type:        event.Type,
+		FileName:    cesvc.eventInput.FileName,
+		MarketDate:  marketDate.Time(),
+		Company:     sourceCompany,
+		Target:      targetCompany,
+		FromQty:     event.FromQty,
+		ToQty:       event.ToQty,
+		CostRate:    costRate,
+		SourceQty:   usedQty,
+		TargetQty:   int64(wholeQty),
+		CostMoved:   costMoved,
+		FractionQty: fractionQty,
+	}
+
+	if event.Type == eventTypes.SUBSCRIPTION {
+		corporateEvent.Cash = wholeQty * event.Price
+	}
+
+	// fractions of rights are dropped, other fractions are paid in cash
+	if event.Type != eventTypes.RIGHTS_GRANT && targetQty > 0 {
+		corporateEvent.FractionCash = fractionQty * event.FractionPrice
+		corporateEvent.FractionCost = costMoved * fractionQty / targetQty
+	}
+
+	sourceQty := sourceBatch.Qty
+
+	if removesSource(event.Type) {
+		sourceQty = sourceQty - usedQty
+	}
+
+	sourceBatchRec, err := sourceService.UpdateCompanyBatch(
+		sourceBatch,
+		sourceQty,
+		sourceBatch.TotalPrice-costMoved,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if corporateEvent.TargetQty > 0 {
+		targetBatch, err = targetService.AddQty(
+			corporateEvent.TargetQty,
+			costMoved+corporateEvent.Cash-corporateEvent.FractionCost,
+			marketDate.Time(),
+		)
+	} else {
+		targetBatch, err = targetService.GetCompanyBatch()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cesvc.moveBrokerQty(sourceBatchRec, targetBatch, corporateEvent); err != nil {
+		return nil, err
+	}
+
+	if corporateEvent.FractionCash > 0 || corporateEvent.FractionCost > 0 {
+		fractionBatch := targetBatch
+
+		if fractionBatch == nil {
+			// the whole position became a fraction, the trade closes the source batch
+			fractionBatch = sourceBatchRec
+		}
+
+		corporateEvent.Trade, err = cesvc.createFractionTrade(user, fractionBatch, corporateEvent)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	corporateEventDAO := db.GetCorporateEventDAO(cesvc.tx, corporateEvent)
+	return corporateEventDAO.CreateCorporateEvent()
+}
+
+func (cesvc *CorporateEventService) ProcessCorporateEvents() ([]*model.CorporateEvent, error) {
+	eventInput := cesvc.eventInput
+
+	corporateEventDAO := db.GetCorporateEventDAO(cesvc.tx, &model.CorporateEvent{
+		FileName: eventInput.FileName,
+	})
+
+	isNew, err := corporateEventDAO.IsNewEventFile()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !isNew {
+		err = fmt.Errorf(
+			"corporateEventService.ProcessCorporateEvents: error: File %s already imported",
+			eventInput.FileName,
+		)
+		return nil, err
+	}
+
+	userService := GetUserService(cesvc.tx, &entity.User{
+		ExternalUUID: eventInput.Client.Id,
+		UserName:     eventInput.Client.Name,
+	})
+
+	userRec, err := userService.UpsertUser()
+
+	if err != nil {
+		return nil, err
+	}
+
+	corporateEvents := make([]*model.CorporateEvent, 0, len(eventInput.Events))
+
+	for idx := range eventInput.Events {
+		event := &eventInput.Events[idx]
+
+		if isUnitConversion(event.Type) {
+			unitEventRecs, err := cesvc.processUnitConversion(userRec, event)
+
+			if err != nil {
+				return nil, err
+			}
+
+			corporateEvents = append(corporateEvents, unitEventRecs...)
+			continue
+		}
+
+		corporateEventRec, err := cesvc.processEvent(userRec, event)
+
+		if err != nil {
+			return nil, err
+		}
+
+		corporateEvents = append(corporateEvents, corporateEventRec)
+	}
+
+	log.Printf(
+		"corporateEventService.ProcessCorporateEvents: applied %d events from %s",
+		len(corporateEvents),
+		eventInput.FileName,
+	)
+
+	return corporateEvents, nil
+}
+
+// getUnitComponents returns the companies bundled by unit and how many shares
+// of each one unit holds, from the ticker registry composition.
+func (cesvc *CorporateEventService) getUnitComponents(
+	unit *entity.Company,
+) ([]*entity.Company, []int64, error) {
+	tickerStore := cesvc.tickerStore
+
+	if !tickerStore.Has(unit.Code) || len(tickerStore.Get(unit.Code).Composition) == 0 {
+		details := fmt.Sprintf("[%s, %s]", cesvc.eventInput.FileName, unit.Code)
+		return nil, nil, utils.GetError("corporateEventService.getUnitComponents", "ERR_CMP_003", details)
+	}
+
+	composition := tickerStore.Get(unit.Code).Composition
+	components := make([]*entity.Company, 0, len(composition))
+	componentQtys := make([]int64, 0, len(composition))
+
+	for _, component := range composition {
+		name := component.Code
+
+		if tickerStore.Has(component.Code) && tickerStore.Get(component.Code).Issuer != "" {
+			name = tickerStore.Get(component.Code).Issuer
+		}
+
+		company, err := cesvc.upsertCompany(&input.Company{
+			Code: component.Code,
+			Name: name,
+		})
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		components = append(components, company)
+		componentQtys = append(componentQtys, component.Qty)
+	}
+
+	return components, componentQtys, nil
+}
+
+// splitUnitBrokerQty takes the units from the brokers holding the most first,
+// each broker receives the shares of the units it gave.
+func (cesvc *CorporateEventService) splitUnitBrokerQty(
+	unitBatch *entity.CompanyBatch,
+	componentBatches []*entity.CompanyBatch,
+	componentQtys []int64,
+	corporateEvents []*model.CorporateEvent,
+	qty int64,
+	marketDate time.Time,
+) error {
+	brokerBatchDAO := db.GetBrokerBatchDAO(cesvc.tx, &model.BrokerBatch{
+		CompanyBatch: unitBatch,
+	})
+
+	brokerBatches, err := brokerBatchDAO.GetBrokerBatchesByCompanyBatch()
+
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(brokerBatches, func(i, j int) bool {
+		return brokerBatches[i].Qty > brokerBatches[j].Qty
+	})
+
+	leftQty := qty
+
+	for _, brokerBatch := range brokerBatches {
+		if brokerBatch.Qty <= 0 || leftQty <= 0 {
+			continue
+		}
+
+		brokerQty := brokerBatch.Qty
+
+		if brokerQty > leftQty {
+			brokerQty = leftQty
+		}
+
+		leftQty = leftQty - brokerQty
+
+		unitService := GetBrokerBatchService(cesvc.tx, unitBatch, brokerBatch.AgentId, cesvc.brokerBatchStore)
+
+		if _, err = unitService.RemoveQty(brokerQty, marketDate); err != nil {
+			return err
+		}
+
+		brokerShare := float64(brokerQty) / float64(qty)
+
+		for idx, componentBatch := range componentBatches {
+			componentService := GetBrokerBatchService(
+				cesvc.tx,
+				componentBatch,
+				brokerBatch.AgentId,
+				cesvc.brokerBatchStore,
+			)
+
+			_, err = componentService.AddQty(
+				brokerQty*componentQtys[idx],
+				corporateEvents[idx].CostMoved*brokerShare,
+				marketDate,
+			)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if leftQty > 0 {
+		log.Printf(
+			"corporateEventService.splitUnitBrokerQty: WARNING: %d units of %s without broker custody",
+			leftQty,
+			unitBatch.Company.Code,
+		)
+	}
+
+	return nil
+}
+
+// mergeUnitBrokerQty builds the units at each broker from the shares it holds,
+// brokers holding the most shares of the first component go first.
+func (cesvc *CorporateEventService) mergeUnitBrokerQty(
+	unitBatch *entity.CompanyBatch,
+	componentBatches []*entity.CompanyBatch,
+	componentQtys []int64,
+	qty int64,
+	totalPrice float64,
+	marketDate time.Time,
+) error {
+	componentCustody := make([]map[string]int64, len(componentBatches))
+	var agentIds []string
+
+	for idx, componentBatch := range componentBatches {
+		brokerBatchDAO := db.GetBrokerBatchDAO(cesvc.tx, &model.BrokerBatch{
+			CompanyBatch: componentBatch,
+		})
+
+		brokerBatches, err := brokerBatchDAO.GetBrokerBatchesByCompanyBatch()
+
+		if err != nil {
+			return err
+		}
+
+		sort.SliceStable(brokerBatches, func(i, j int) bool {
+			return brokerBatches[i].Qty > brokerBatches[j].Qty
+		})
+
+		componentCustody[idx] = make(map[string]int64)
+
+		for _, brokerBatch := range brokerBatches {
+			componentCustody[idx][brokerBatch.AgentId] = brokerBatch.Qty
+
+			if idx == 0 {
+				agentIds = append(agentIds, brokerBatch.AgentId)
+			}
+		}
+	}
+
+	leftQty := qty
+
+	for _, agentId := range agentIds {
+		brokerQty := leftQty
+
+		for idx, custody := range componentCustody {
+			if available := custody[agentId] / componentQtys[idx]; available < brokerQty {
+				brokerQty = available
+			}
+		}
+
+		if brokerQty <= 0 {
+			continue
+		}
+
+		leftQty = leftQty - brokerQty
+
+		for idx, componentBatch := range componentBatches {
+			componentService := GetBrokerBatchService(cesvc.tx, componentBatch, agentId, cesvc.brokerBatchStore)
+
+			if _, err := componentService.RemoveQty(brokerQty*componentQtys[idx], marketDate); err != nil {
+				return err
+			}
+		}
+
+		unitService := GetBrokerBatchService(cesvc.tx, unitBatch, agentId, cesvc.brokerBatchStore)
+		brokerShare := float64(brokerQty) / float64(qty)
+
+		if _, err := unitService.AddQty(brokerQty, totalPrice*brokerShare, marketDate); err != nil {
+			return err
+		}
+	}
+
+	if leftQty > 0 {
+		log.Printf(
+			"corporateEventService.mergeUnitBrokerQty: WARNING: %d units of %s without broker custody",
+			leftQty,
+			unitBatch.Company.Code,
+		)
+	}
+
+	return nil
+}
+
+// splitUnit converts units into the shares they bundle, the unit cost basis is
+// split by share count. The units leave the position on the first component
+// event only.
+func (cesvc *CorporateEventService) splitUnit(
+	user *entity.User,
+	event *input.CorporateEventItem,
+	marketDate time.Time,
+	unit *entity.Company,
+	components []*entity.Company,
+	componentQtys []int64,
+) ([]*model.CorporateEvent, error) {
+	unitService := GetCompanyBatchService(cesvc.tx, user, unit, cesvc.companyBatchStore)
+	unitBatch, err := unitService.GetCompanyBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if unitBatch == nil || unitBatch.Qty <= 0 {
+		details := fmt.Sprintf("[%s, no position on %s]", cesvc.eventInput.FileName, unit.Code)
+		return nil, utils.GetError("corporateEventService.splitUnit", "ERR_SYS_001", details)
+	}
+
+	if err = cesvc.validateEventDate(unitBatch, marketDate); err != nil {
+		return nil, err
+	}
+
+	qty := event.Qty
+
+	if qty > unitBatch.Qty {
+		details := fmt.Sprintf(
+			"[%s, %s, qty = %d, held = %d]",
+			cesvc.eventInput.FileName,
+			unit.Code,
+			qty,
+			unitBatch.Qty,
+		)
+		return nil, utils.GetError("corporateEventService.splitUnit", "ERR_SYS_001", details)
+	}
+
+	if qty == 0 {
+		qty = unitBatch.Qty
+	}
+
+	var totalShares int64
+
+	for _, componentQty := range componentQtys {
+		totalShares = totalShares + componentQty
+	}
+
+	unitShare := float64(qty) / float64(unitBatch.Qty)
+	corporateEvents := make([]*model.CorporateEvent, 0, len(components))
+	componentBatches := make([]*entity.CompanyBatch, 0, len(components))
+	costMovedTotal := 0.0
+
+	for idx, component := range components {
+		costRate := unitShare * float64(componentQtys[idx]) / float64(totalShares)
+		costMoved := unitBatch.TotalPrice * costRate
+		costMovedTotal = costMovedTotal + costMoved
+
+		corporateEvent := &model.CorporateEvent{
+			User:       user,
+			Type:       event.Type,
+			FileName:   cesvc.eventInput.FileName,
+			MarketDate: marketDate,
+			Company:    unit,
+			Target:     component,
+			FromQty:    1,
+			ToQty:      componentQtys[idx],
+			CostRate:   costRate,
+			TargetQty:  qty * componentQtys[idx],
+			CostMoved:  costMoved,
+		}
+
+		if idx == 0 {
+			corporateEvent.SourceQty = qty
+		}
+
+		componentService := GetCompanyBatchService(cesvc.tx, user, component, cesvc.companyBatchStore)
+		componentBatch, err := componentService.AddQty(corporateEvent.TargetQty, costMoved, marketDate)
+
+		if err != nil {
+			return nil, err
+		}
+
+		corporateEvents = append(corporateEvents, corporateEvent)
+		componentBatches = append(componentBatches, componentBatch)
+	}
+
+	unitBatchRec, err := unitService.UpdateCompanyBatch(
+		unitBatch,
+		unitBatch.Qty-qty,
+		unitBatch.TotalPrice-costMovedTotal,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = cesvc.splitUnitBrokerQty(unitBatchRec, componentBatches, componentQtys, corporateEvents, qty, marketDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return corporateEvents, nil
+}
+
+// mergeUnit builds units from the shares they bundle, each component moves its
+// own cost basis. The units enter the position on the first component event
+// only.
+func (cesvc *CorporateEventService) mergeUnit(
+	user *entity.User,
+	event *input.CorporateEventItem,
+	marketDate time.Time,
+	unit *entity.Company,
+	components []*entity.Company,
+	componentQtys []int64,
+) ([]*model.CorporateEvent, error) {
+	componentServices := make([]*CompanyBatchService, 0, len(components))
+	componentBatches := make([]*entity.CompanyBatch, 0, len(components))
+	var maxQty int64 = -1
+
+	for idx, component := range components {
+		componentService := GetCompanyBatchService(cesvc.tx, user, component, cesvc.companyBatchStore)
+		componentBatch, err := componentService.GetCompanyBatch()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err = cesvc.validateEventDate(componentBatch, marketDate); err != nil {
+			return nil, err
+		}
+
+		var available int64
+
+		if componentBatch != nil && componentBatch.Qty > 0 {
+			available = componentBatch.Qty / componentQtys[idx]
+		}
+
+		if maxQty < 0 || available < maxQty {
+			maxQty = available
+		}
+
+		componentServices = append(componentServices, componentService)
+		componentBatches = append(componentBatches, componentBatch)
+	}
+
+	if maxQty <= 0 {
+		details := fmt.Sprintf("[%s, not enough shares to build %s]", cesvc.eventInput.FileName, unit.Code)
+		return nil, utils.GetError("corporateEventService.mergeUnit", "ERR_SYS_001", details)
+	}
+
+	qty := event.Qty
+
+	if qty > maxQty {
+		details := fmt.Sprintf(
+			"[%s, %s, qty = %d, available = %d]",
+			cesvc.eventInput.FileName,
+			unit.Code,
+			qty,
+			maxQty,
+		)
+		return nil, utils.GetError("corporateEventService.mergeUnit", "ERR_SYS_001", details)
+	}
+
+	if qty == 0 {
+		qty = maxQty
+	}
+
+	corporateEvents := make([]*model.CorporateEvent, 0, len(components))
+	totalPrice := 0.0
+
+	for idx, component := range components {
+		componentBatch := componentBatches[idx]
+		shares := qty * componentQtys[idx]
+		costRate := float64(shares) / float64(componentBatch.Qty)
+		costMoved := componentBatch.TotalPrice * costRate
+		totalPrice = totalPrice + costMoved
+
+		corporateEvent := &model.CorporateEvent{
+			User:       user,
+			Type:       event.Type,
+			FileName:   cesvc.eventInput.FileName,
+			MarketDate: marketDate,
+			Company:    component,
+			Target:     unit,
+			FromQty:    componentQtys[idx],
+			ToQty:      1,
+			CostRate:   costRate,
+			SourceQty:  shares,
+			CostMoved:  costMoved,
+		}
+
+		if idx == 0 {
+			corporateEvent.TargetQty = qty
+		}
+
+		componentBatchRec, err := componentServices[idx].UpdateCompanyBatch(
+			componentBatch,
+			componentBatch.Qty-shares,
+			componentBatch.TotalPrice-costMoved,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		componentBatches[idx] = componentBatchRec
+		corporateEvents = append(corporateEvents, corporateEvent)
+	}
+
+	unitService := GetCompanyBatchService(cesvc.tx, user, unit, cesvc.companyBatchStore)
+	unitBatch, err := unitService.AddQty(qty, totalPrice, marketDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = cesvc.mergeUnitBrokerQty(unitBatch, componentBatches, componentQtys, qty, totalPrice, marketDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return corporateEvents, nil
+}
+
+// processUnitConversion moves quantity and cost basis between a unit and the
+// shares it bundles, no trade is created as conversions are not taxable.
+func (cesvc *CorporateEventService) processUnitConversion(
+	user *entity.User,
+	event *input.CorporateEventItem,
+) ([]*model.CorporateEvent, error) {
+	if err := cesvc.validateEvent(event); err != nil {
+		return nil, err
+	}
+
+	marketDate, err := utils.ParseB3Date(event.Date)
+
+	if err != nil {
+		return nil, err
+	}
+
+	eventTypes := constants.CorporateEventTypes
+	unitInput := &event.Company
+
+	if event.Type == eventTypes.UNIT_MERGE {
+		unitInput = &event.Target
+	}
+
+	unit, err := cesvc.upsertCompany(unitInput)
+
+	if err != nil {
+		return nil, err
+	}
+
+	components, componentQtys, err := cesvc.getUnitComponents(unit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var corporateEvents []*model.CorporateEvent
+
+	if event.Type == eventTypes.UNIT_SPLIT {
+		corporateEvents, err = cesvc.splitUnit(user, event, marketDate.Time(), unit, components, componentQtys)
+	} else {
+		corporateEvents, err = cesvc.mergeUnit(user, event, marketDate.Time(), unit, components, componentQtys)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	corporateEventRecs := make([]*model.CorporateEvent, 0, len(corporateEvents))
+
+	for _, corporateEvent := range corporateEvents {
+		corporateEventDAO := db.GetCorporateEventDAO(cesvc.tx, corporateEvent)
+		corporateEventRec, err := corporateEventDAO.CreateCorporateEvent()
+
+		if err != nil {
+			return nil, err
+		}
+
+		corporateEventRecs = append(corporateEventRecs, corporateEventRec)
+	}
+
+	return corporateEventRecs, nil
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type CustodyService struct {
+	tx                *sql.Tx
+	custodyInput      *input.CustodyTransfer
+	companyStore      *store.CompanyStore
+	companyBatchStore *store.CompanyBatchStore
+	brokerBatchStore  *store.BrokerBatchStore
+	tickerStore       *store.TickerStore
+	companyResolver   *CompanyResolverService
+}
+
+func GetCustodyService(
+	tx *sql.Tx,
+	custodyInput *input.CustodyTransfer,
+	companyStore *store.CompanyStore,
+	companyBatchStore *store.CompanyBatchStore,
+	brokerBatchStore *store.BrokerBatchStore,
+	tickerStore *store.TickerStore,
+	companyAliasStore *store.CompanyAliasStore,
+) *CustodyService {
+	return &CustodyService{
+		tx:                tx,
+		custodyInput:      custodyInput,
+		companyStore:      companyStore,
+		companyBatchStore: companyBatchStore,
+		brokerBatchStore:  brokerBatchStore,
+		tickerStore:       tickerStore,
+		companyResolver:   GetCompanyResolverService(tx, companyAliasStore, tickerStore),
+	}
+}
+
+func (csvc *CustodyService) validateItem(item *input.CustodyItem) error {
+	if (item.Company.Code == "" && item.Company.Name == "") || item.Qty <= 0 || item.TotalCost < 0 {
+		details := fmt.Sprintf(
+			"[%s, %s, qty = %d, cost = %.4f]",
+			csvc.custodyInput.FileName,
+			item.Company.Code,
+			item.Qty,
+			item.TotalCost,
+		)
+		return utils.GetError("custodyService.validateItem", "ERR_SYS_001", details)
+	}
+
+	return nil
+}
+
+func (csvc *CustodyService) upsertCompany(item *input.CustodyItem) (*entity.Company, error) {
+	inputCode := item.Company.Code
+
+	if inputCode == "" {
+		resolvedCode, err := csvc.companyResolver.ResolveCode(item.Company.Name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		inputCode = resolvedCode
+	}
+
+	code, _ := utils.NormalizeTicker(inputCode)
+
+	company := &entity.Company{
+		Code: code,
+		Name: item.Company.Name,
+	}
+
+	companyService := GetCompanyService(
+		csvc.tx,
+		company,
+		csvc.companyStore,
+		csvc.tickerStore,
+	)
+
+	return companyService.UpsertCompany()
+}
+
+// moveBetweenBrokers moves the item qty from the origin broker custody to the
+// target one at the origin average price, the consolidated company batch is
+// left unchanged. Returns nil when the origin broker custody is not tracked.
+func (csvc *CustodyService) moveBetweenBrokers(
+	user *entity.User,
+	companyRec *entity.Company,
+	item *input.CustodyItem,
+	marketDate time.Time,
+) (*model.CustodyTransfer, error) {
+	if item.OriginBroker == "" || item.AgentId == "" || item.OriginBroker == item.AgentId {
+		return nil, nil
+	}
+
+	companyBatchService := GetCompanyBatchService(
+		csvc.tx,
+		user,
+		companyRec,
+		csvc.companyBatchStore,
+	)
+
+	companyBatchRec, err := companyBatchService.GetCompanyBatch()
+
+	if err != nil || companyBatchRec == nil {
+		return nil, err
+	}
+
+	originService := GetBrokerBatchService(
+		csvc.tx,
+		companyBatchRec,
+		item.OriginBroker,
+		csvc.brokerBatchStore,
+	)
+
+	originBatch, err := originService.getBrokerBatch()
+
+	if err != nil || originBatch == nil {
+		return nil, err
+	}
+
+	if originBatch.Qty < item.Qty {
+		details := fmt.Sprintf(
+			"[%s, %s, qty = %d, held at %s = %d]",
+			csvc.custodyInput.FileName,
+			companyRec.Code,
+			item.Qty,
+			item.OriginBroker,
+			originBatch.Qty,
+		)
+		return nil, utils.GetError("custodyService.moveBetweenBrokers", "ERR_SYS_001", details)
+	}
+
+	totalCost := originBatch.AvgPrice * float64(item.Qty)
+
+	if _, err = originService.RemoveQty(item.Qty, marketDate); err != nil {
+		return nil, err
+	}
+
+	targetService := GetBrokerBatchService(
+		csvc.tx,
+		companyBatchRec,
+		item.AgentId,
+		csvc.brokerBatchStore,
+	)
+
+	if _, err = targetService.AddQty(item.Qty, totalCost, marketDate); err != nil {
+		return nil, err
+	}
+
+	custodyTransferDAO := db.GetCustodyTransferDAO(csvc.tx, &model.CustodyTransfer{
+		User:         user,
+		Company:      companyRec,
+		CompanyBatch: companyBatchRec,
+		FileName:     csvc.custodyInput.FileName,
+		MarketDate:   marketDate,
+		Qty:          item.Qty,
+		TotalPrice:   totalCost,
+		OriginBroker: item.OriginBroker,
+		AgentId:      item.AgentId,
+		BrokerMove:   true,
+	})
+
+	return custodyTransferDAO.CreateCustodyTransfer()
+}
+
+func (csvc *CustodyService) processItem(
+	user *entity.User,
+	item *input.CustodyItem,
+) (*model.CustodyTransfer, error) {
+	if err := csvc.validateItem(item); err != nil {
+		return nil, err
+	}
+
+	marketDate, err := utils.ParseB3Date(item.Date)
+
+	if err != nil {
+		return nil, err
+	}
+
+	companyRec, err := csvc.upsertCompany(item)
+
+	if err != nil {
+		return nil, err
+	}
+
+	brokerMove, err := csvc.moveBetweenBrokers(user, companyRec, item, marketDate.Time())
+
+	if err != nil || brokerMove != nil {
+		return brokerMove, err
+	}
+
+	companyBatchService := GetCompanyBatchService(
+		csvc.tx,
+		user,
+		companyRec,
+		csvc.companyBatchStore,
+	)
+
+	companyBatchRec, err := companyBatchService.AddQty(item.Qty, item.TotalCost, marketDate.Time())
+
+	if err != nil {
+		return nil, err
+	}
+
+	if item.AgentId != "" {
+		brokerBatchService := GetBrokerBatchService(
+			csvc.tx,
+			companyBatchRec,
+			item.AgentId,
+			csvc.brokerBatchStore,
+		)
+
+		_, err = brokerBatchService.AddQty(item.Qty, item.TotalCost, marketDate.Time())
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	custodyTransferDAO := db.GetCustodyTransferDAO(csvc.tx, &model.CustodyTransfer{
+		User:         user,
+		Company:      companyRec,
+		CompanyBatch: companyBatchRec,
+		FileName:     csvc.custodyInput.FileName,
+		MarketDate:   marketDate.Time(),
+		Qty:          item.Qty,
+		TotalPrice:   item.TotalCost,
+		OriginBroker: item.OriginBroker,
+		AgentId:      item.AgentId,
+	})
+
+	return custodyTransferDAO.CreateCustodyTransfer()
+}
+
+func (csvc *CustodyService) ProcessCustodyTransfer() ([]*model.CustodyTransfer, error) {
+	custodyInput := csvc.custodyInput
+
+	custodyTransferDAO := db.GetCustodyTransferDAO(csvc.tx, &model.CustodyTransfer{
+		FileName: custodyInput.FileName,
+	})
+
+	isNew, err := custodyTransferDAO.IsNewCustodyFile()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !isNew {
+		err = fmt.Errorf(
+			"custodyService.ProcessCustodyTransfer: error: File %s already imported",
+			custodyInput.FileName,
+		)
+		return nil, err
+	}
+
+	userService := GetUserService(csvc.tx, &entity.User{
+		ExternalUUID: custodyInput.Client.Id,
+		UserName:     custodyInput.Client.Name,
+	})
+
+	userRec, err := userService.UpsertUser()
+
+	if err != nil {
+		return nil, err
+	}
+
+	custodyTransfers := make([]*model.CustodyTransfer, 0, len(custodyInput.Items))
+
+	for _, item := range custodyInput.Items {
+		custodyTransferRec, err := csvc.processItem(userRec, &item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		custodyTransfers = append(custodyTransfers, custodyTransferRec)
+	}
+
+	log.Printf(
+		"custodyService.ProcessCustodyTransfer: imported %d items from %s",
+		len(custodyTransfers),
+		custodyInput.FileName,
+	)
+
+	return custodyTransfers, nil
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// FuturesBatchService keeps the monthly futures results apart from the spot
+// trade batch, day trades pay IRDTFEE and positions IRFEE, both with no
+// exemption limit.
+type FuturesBatchService struct {
+	tx           *sql.Tx
+	user         *entity.User
+	futuresBatch *model.FuturesBatch
+	taxStore     *store.TaxStore
+	taxRateStore *store.TaxRateStore
+}
+
+func GetFuturesBatchService(
+	tx *sql.Tx,
+	user *entity.User,
+	futuresBatch *model.FuturesBatch,
+	taxStore *store.TaxStore,
+	taxRateStore *store.TaxRateStore,
+) *FuturesBatchService {
+	return &FuturesBatchService{
+		tx:           tx,
+		user:         user,
+		futuresBatch: futuresBatch,
+		taxStore:     taxStore,
+		taxRateStore: taxRateStore,
+	}
+}
+
+func (fbsvc *FuturesBatchService) getTaxInstance(marketDate time.Time, code string) entity.TaxInstance {
+	rate := fbsvc.taxRateStore.Get(code, utils.B3DateOf(marketDate))
+
+	return entity.TaxInstance{
+		MarketDate: marketDate,
+		TaxValue:   0,
+		BaseValue:  0,
+		TaxRate:    rate,
+		Tax: &entity.Tax{
+			Code:   code,
+			Source: constants.TaxSources.FUTURES_BATCH,
+			Rate:   rate,
+		},
+	}
+}
+
+func (fbsvc *FuturesBatchService) getTaxGroup(marketDate time.Time) (*entity.TaxGroup, error) {
+	groupId, err := utils.GetTaxGroupIdFromTime(
+		marketDate,
+		constants.TaxGroupPrefix.FUTURES_BATCH,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	taxGroup := &entity.TaxGroup{
+		Source:     constants.TaxSources.FUTURES_BATCH,
+		ExternalId: groupId,
+		Taxes: []entity.TaxInstance{
+			fbsvc.getTaxInstance(marketDate, constants.TaxTypes.IRDTFEE),
+			fbsvc.getTaxInstance(marketDate, constants.TaxTypes.IRFEE),
+		},
+	}
+
+	taxGroupService := GetTaxGroupService(fbsvc.tx, taxGroup, fbsvc.taxStore)
+
+	return taxGroupService.CreateTaxGroup()
+}
+
+func (fbsvc *FuturesBatchService) getNewFuturesData(lastData *entity.TradeBatchData) *entity.TradeBatchData {
+	var futuresData entity.TradeBatchData
+
+	if lastData == nil {
+		return &futuresData
+	}
+
+	accLoss := lastData.AccLoss + lastData.Results - lastData.TotalTax
+
+	if accLoss > 0 {
+		accLoss = 0
+	}
+
+	futuresData.AccLoss = accLoss
+
+	return &futuresData
+}
+
+func (fbsvc *FuturesBatchService) createFuturesBatch(
+	marketDate time.Time,
+	lastFuturesBatch *model.FuturesBatch,
+) (*model.FuturesBatch, error) {
+	taxGroup, err := fbsvc.getTaxGroup(marketDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var lastDayTradeData, lastCommonData *entity.TradeBatchData
+
+	if lastFuturesBatch != nil {
+		lastDayTradeData = lastFuturesBatch.DayTrade
+		lastCommonData = lastFuturesBatch.Common
+	}
+
+	futuresBatchDAO := db.GetFuturesBatchDAO(fbsvc.tx, &model.FuturesBatch{
+		User:      fbsvc.user,
+		TaxGroup:  taxGroup,
+		StartDate: utils.ToFirstDayOfMonth(marketDate),
+		DayTrade:  fbsvc.getNewFuturesData(lastDayTradeData),
+		Common:    fbsvc.getNewFuturesData(lastCommonData),
+	})
+
+	return futuresBatchDAO.CreateFuturesBatch()
+}
+
+// FindFuturesBatch returns the futures batch of the market date month, a new
+// month starts with the losses left by the last one.
+func (fbsvc *FuturesBatchService) FindFuturesBatch(marketDate time.Time) (*model.FuturesBatch, error) {
+	futuresBatchDAO := db.GetFuturesBatchDAO(fbsvc.tx, &model.FuturesBatch{
+		User:      fbsvc.user,
+		StartDate: utils.ToFirstDayOfMonth(marketDate),
+	})
+
+	futuresBatchRec, err := futuresBatchDAO.GetFuturesBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if futuresBatchRec != nil {
+		return futuresBatchRec, nil
+	}
+
+	lastFuturesBatch, err := futuresBatchDAO.GetLastFuturesBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fbsvc.createFuturesBatch(marketDate, lastFuturesBatch)
+}
+
+func (fbsvc *FuturesBatchService) adjustTax(code string, futuresData *entity.TradeBatchData) {
+	taxGroup := fbsvc.futuresBatch.TaxGroup
+
+	for idx, taxInstance := range taxGroup.Taxes {
+		if taxInstance.Tax.Code != code {
+			continue
+		}
+
+		currentResults := futuresData.Results - futuresData.TotalTax
+		baseValue := currentResults + futuresData.AccLoss
+
+		if currentResults <= 0 || baseValue <= 0 {
+			taxInstance.TaxValue = 0.0
+			taxInstance.BaseValue = 0.0
+		} else {
+			taxInstance.TaxValue = baseValue * taxInstance.TaxRate
+			taxInstance.BaseValue = baseValue
+		}
+
+		taxGroup.Taxes[idx] = taxInstance
+	}
+}
+
+// ProcessNote adds the results of a note to the batch, fees are the note
+// costs of each part.
+func (fbsvc *FuturesBatchService) ProcessNote(
+	dayTrade *entity.TradeBatchData,
+	common *entity.TradeBatchData,
+) *model.FuturesBatch {
+	futuresBatch := fbsvc.futuresBatch
+
+	dayTradeData := futuresBatch.DayTrade
+	commonData := futuresBatch.Common
+
+	dayTradeData.Results = dayTradeData.Results + dayTrade.Results
+	dayTradeData.TotalTax = dayTradeData.TotalTax + dayTrade.TotalTax
+	dayTradeData.TotalTrade = dayTradeData.TotalTrade + dayTrade.TotalTrade
+
+	commonData.Results = commonData.Results + common.Results
+	commonData.TotalTax = commonData.TotalTax + common.TotalTax
+	commonData.TotalTrade = commonData.TotalTrade + common.TotalTrade
+
+	fbsvc.adjustTax(constants.TaxTypes.IRDTFEE, futuresBatch.DayTrade)
+	fbsvc.adjustTax(constants.TaxTypes.IRFEE, futuresBatch.Common)
+
+	log.Printf(
+		"FuturesBatchService.ProcessNote: id = %d, dt = %.2f, common = %.2f",
+		futuresBatch.Id,
+		futuresBatch.DayTrade.Results,
+		futuresBatch.Common.Results,
+	)
+
+	return futuresBatch
+}
+
+func (fbsvc *FuturesBatchService) SaveFuturesBatch() (*model.FuturesBatch, error) {
+	taxGroupService := GetTaxGroupService(
+		fbsvc.tx,
+		fbsvc.futuresBatch.TaxGroup,
+		fbsvc.taxStore,
+	)
+
+	if err := taxGroupService.UpdateTaxGroup(); err != nil {
+		return nil, err
+	}
+
+	futuresBatchDAO := db.GetFuturesBatchDAO(fbsvc.tx, fbsvc.futuresBatch)
+
+	if err := futuresBatchDAO.UpdateFuturesBatch(); err != nil {
+		return nil, err
+	}
+
+	return fbsvc.futuresBatch, nil
+}
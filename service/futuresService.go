@@ -0,0 +1,323 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/calendar"
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// FuturesService processes BM&F notes, the net ajuste of each note is the
+// realized result of the day and goes to the futures batch of the month,
+// apart from the spot invoices flow.
+type FuturesService struct {
+	tx           *sql.Tx
+	noteInput    *input.FuturesNote
+	taxStore     *store.TaxStore
+	taxRateStore *store.TaxRateStore
+	b3Calendar   *calendar.B3Calendar
+}
+
+func GetFuturesService(
+	tx *sql.Tx,
+	noteInput *input.FuturesNote,
+	taxStore *store.TaxStore,
+	taxRateStore *store.TaxRateStore,
+	b3Calendar *calendar.B3Calendar,
+) *FuturesService {
+	return &FuturesService{
+		tx:           tx,
+		noteInput:    noteInput,
+		taxStore:     taxStore,
+		taxRateStore: taxRateStore,
+		b3Calendar:   b3Calendar,
+	}
+}
+
+// validateDates the ajuste is settled on the next trading day.
+func (fsvc *FuturesService) validateDates() error {
+	noteInput := fsvc.noteInput
+	b3Calendar := fsvc.b3Calendar
+
+	marketDate, err := utils.ParseB3Date(noteInput.MarketDate)
+
+	if err != nil {
+		return err
+	}
+
+	billingDate, err := utils.ParseB3Date(noteInput.BillingDate)
+
+	if err != nil {
+		return err
+	}
+
+	if !b3Calendar.IsTradingDay(marketDate) {
+		details := fmt.Sprintf(
+			"[%s, marketDate = %s, %s]",
+			noteInput.FileName,
+			marketDate.String(),
+			b3Calendar.GetClosure(marketDate),
+		)
+		return utils.GetError("futuresService.validateDates", "ERR_CAL_001", details)
+	}
+
+	settlementDate := b3Calendar.NextTradingDay(marketDate)
+
+	if !billingDate.Equal(settlementDate) {
+		details := fmt.Sprintf(
+			"[%s, billingDate = %s, expected = %s]",
+			noteInput.FileName,
+			billingDate.String(),
+			settlementDate.String(),
+		)
+		return utils.GetError("futuresService.validateDates", "ERR_CAL_002", details)
+	}
+
+	return nil
+}
+
+// validateItems only mini contracts are supported, the item adjustments
+// must add up to the note net ajuste.
+func (fsvc *FuturesService) validateItems() error {
+	noteInput := fsvc.noteInput
+	adjustment := 0.0
+
+	for _, item := range noteInput.Items {
+		contract, ok := utils.ParseFuturesCode(item.Code)
+
+		if _, supported := constants.FuturesMultipliers[contract]; !ok || !supported {
+			details := fmt.Sprintf("[%s, unsupported futures contract %s]", noteInput.FileName, item.Code)
+			return utils.GetError("futuresService.validateItems", "ERR_SYS_001", details)
+		}
+
+		adjustment = adjustment + item.Adjustment
+	}
+
+	if math.Abs(adjustment-noteInput.Adjustment) > 0.01 {
+		details := fmt.Sprintf(
+			"[%s, adjustment = %.2f, items = %.2f]",
+			noteInput.FileName,
+			noteInput.Adjustment,
+			adjustment,
+		)
+		return utils.GetError("futuresService.validateItems", "ERR_SYS_001", details)
+	}
+
+	return nil
+}
+
+// getTaxGroup note fees are kept as printed, the withheld IR is based on the
+// day trade gains.
+func (fsvc *FuturesService) getTaxGroup(dayTradeAdjustment float64) (*entity.TaxGroup, error) {
+	noteInput := fsvc.noteInput
+
+	groupId, err := utils.GetTaxGroupId(
+		noteInput.MarketDate,
+		constants.TaxGroupPrefix.FUTURES_NOTE,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	marketDate, err := utils.GetDateObject(noteInput.MarketDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	taxInstances := make([]entity.TaxInstance, 0, len(noteInput.Taxes))
+
+	for _, taxInput := range noteInput.Taxes {
+		source := taxInput.Source
+
+		if source == "" {
+			source = constants.TaxSources.FUTURES_NOTE
+		}
+
+		baseValue := taxInput.Value
+
+		if taxInput.Code == constants.TaxTypes.IRRFFEE {
+			baseValue = math.Max(dayTradeAdjustment, 0)
+		}
+
+		taxInstances = append(taxInstances, entity.TaxInstance{
+			Tax: &entity.Tax{
+				Code:   taxInput.Code,
+				Source: source,
+				Rate:   taxInput.Rate,
+			},
+			MarketDate: marketDate,
+			TaxValue:   taxInput.Value,
+			BaseValue:  baseValue,
+			TaxRate:    taxInput.Rate,
+		})
+	}
+
+	taxGroup := &entity.TaxGroup{
+		Source:     constants.TaxSources.FUTURES_NOTE,
+		ExternalId: groupId,
+		Taxes:      taxInstances,
+	}
+
+	taxGroupService := GetTaxGroupService(fsvc.tx, taxGroup, fsvc.taxStore)
+
+	return taxGroupService.CreateTaxGroup()
+}
+
+// getFees returns the note costs, the withheld IR is not a cost.
+func (fsvc *FuturesService) getFees() float64 {
+	fees := 0.0
+
+	for _, taxInput := range fsvc.noteInput.Taxes {
+		if taxInput.Code != constants.TaxTypes.IRRFFEE {
+			fees = fees + taxInput.Value
+		}
+	}
+
+	return fees
+}
+
+// getNoteResults splits the note in day trade and position results, fees are
+// allocated by the contracts traded on each part.
+func (fsvc *FuturesService) getNoteResults() (dayTrade *entity.TradeBatchData, common *entity.TradeBatchData) {
+	dayTrade = &entity.TradeBatchData{}
+	common = &entity.TradeBatchData{}
+
+	var dayTradeQty, commonQty int64
+
+	for _, item := range fsvc.noteInput.Items {
+		contract, _ := utils.ParseFuturesCode(item.Code)
+		itemTrade := item.Price * float64(item.Qty) * constants.FuturesMultipliers[contract]
+
+		if item.DayTrade {
+			dayTrade.Results = dayTrade.Results + item.Adjustment
+			dayTrade.TotalTrade = dayTrade.TotalTrade + itemTrade
+			dayTradeQty = dayTradeQty + item.Qty
+		} else {
+			common.Results = common.Results + item.Adjustment
+			common.TotalTrade = common.TotalTrade + itemTrade
+			commonQty = commonQty + item.Qty
+		}
+	}
+
+	fees := fsvc.getFees()
+
+	if dayTradeQty+commonQty > 0 {
+		dayTrade.TotalTax = fees * float64(dayTradeQty) / float64(dayTradeQty+commonQty)
+		common.TotalTax = fees - dayTrade.TotalTax
+	} else {
+		common.TotalTax = fees
+	}
+
+	return dayTrade, common
+}
+
+func (fsvc *FuturesService) ProcessFuturesNote() (*model.FuturesNote, error) {
+	noteInput := fsvc.noteInput
+
+	futuresNote := &model.FuturesNote{
+		Number:   noteInput.NoteNum,
+		FileName: noteInput.FileName,
+		AgentId:  noteInput.AgentId,
+		NetValue: noteInput.NetValue,
+	}
+
+	futuresNoteDAO := db.GetFuturesNoteDAO(fsvc.tx, futuresNote)
+	isNew, err := futuresNoteDAO.IsNewFuturesNote()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !isNew {
+		err = fmt.Errorf(
+			"futuresService.ProcessFuturesNote: error: note %s already exists on DB",
+			futuresNote.FileName,
+		)
+		return nil, err
+	}
+
+	if err := fsvc.validateDates(); err != nil {
+		return nil, err
+	}
+
+	if err := fsvc.validateItems(); err != nil {
+		return nil, err
+	}
+
+	userService := GetUserService(fsvc.tx, &entity.User{
+		ExternalUUID: noteInput.Client.Id,
+		UserName:     noteInput.Client.Name,
+	})
+
+	userRec, err := userService.UpsertUser()
+
+	if err != nil {
+		return nil, err
+	}
+
+	marketDate, err := utils.GetDateObject(noteInput.MarketDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	billingDate, err := utils.GetDateObject(noteInput.BillingDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dayTrade, common := fsvc.getNoteResults()
+
+	taxGroupRec, err := fsvc.getTaxGroup(dayTrade.Results)
+
+	if err != nil {
+		return nil, err
+	}
+
+	futuresBatchService := GetFuturesBatchService(fsvc.tx, userRec, nil, fsvc.taxStore, fsvc.taxRateStore)
+	futuresBatch, err := futuresBatchService.FindFuturesBatch(marketDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	futuresBatchService = GetFuturesBatchService(fsvc.tx, userRec, futuresBatch, fsvc.taxStore, fsvc.taxRateStore)
+	futuresBatchService.ProcessNote(dayTrade, common)
+
+	futuresBatch, err = futuresBatchService.SaveFuturesBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	futuresNote.User = userRec
+	futuresNote.TaxGroup = taxGroupRec
+	futuresNote.FuturesBatch = futuresBatch
+	futuresNote.MarketDate = marketDate
+	futuresNote.BillingDate = billingDate
+	futuresNote.DayTradeAdjustment = dayTrade.Results
+	futuresNote.Adjustment = common.Results
+	futuresNote.Fees = dayTrade.TotalTax + common.TotalTax
+
+	log.Printf(
+		"futuresService.ProcessFuturesNote: %s dt = %.2f, adjustment = %.2f, fees = %.2f",
+		futuresNote.FileName,
+		futuresNote.DayTradeAdjustment,
+		futuresNote.Adjustment,
+		futuresNote.Fees,
+	)
+
+	return db.GetFuturesNoteDAO(fsvc.tx, futuresNote).CreateFuturesNote()
+}
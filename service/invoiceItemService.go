@@ -8,18 +8,20 @@ import (
 )
 
 type InvoiceItemService struct {
-	tx   *sql.Tx
-	item *entity.InvoiceItem
+	tx            *sql.Tx
+	item          *entity.InvoiceItem
+	marketSegment string
 }
 
-func GetInvoiceItemService(tx *sql.Tx, item *entity.InvoiceItem) *InvoiceItemService {
+func GetInvoiceItemService(tx *sql.Tx, item *entity.InvoiceItem, marketSegment string) *InvoiceItemService {
 	return &InvoiceItemService{
-		tx:   tx,
-		item: item,
+		tx:            tx,
+		item:          item,
+		marketSegment: marketSegment,
 	}
 }
 
 func (iisvc *InvoiceItemService) CreateItem() (*entity.InvoiceItem, error) {
-	invoiceItemDAO := db.GetInvoiceItemDAO(iisvc.tx, iisvc.item)
+	invoiceItemDAO := db.GetInvoiceItemDAO(iisvc.tx, iisvc.item, iisvc.marketSegment)
 	return invoiceItemDAO.CreateItem()
 }
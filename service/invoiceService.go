@@ -4,22 +4,33 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/jarismar/b3c-invoice-reader-lambda/calendar"
 	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
 	"github.com/jarismar/b3c-invoice-reader-lambda/db"
 	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
 	"github.com/jarismar/b3c-invoice-reader-lambda/store"
 	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
 	"github.com/jarismar/b3c-service-entities/entity"
 )
 
 type InvoiceService struct {
-	tx                *sql.Tx
-	invoiceInput      *input.Invoice
-	taxStore          *store.TaxStore
-	companyStore      *store.CompanyStore
-	companyBatchStore *store.CompanyBatchStore
-	brokerTaxStore    *store.BrokerTaxStore
+	tx                    *sql.Tx
+	invoiceInput          *input.Invoice
+	taxStore              *store.TaxStore
+	companyStore          *store.CompanyStore
+	companyBatchStore     *store.CompanyBatchStore
+	brokerTaxStore        *store.BrokerTaxStore
+	b3Calendar            *calendar.B3Calendar
+	taxRateStore          *store.TaxRateStore
+	brokerStore           *store.BrokerStore
+	brokerBatchStore      *store.BrokerBatchStore
+	tickerStore           *store.TickerStore
+	fiiTradeBatchStore    *store.FiiTradeBatchStore
+	optionTradeBatchStore *store.OptionTradeBatchStore
+	companyResolver       *CompanyResolverService
 }
 
 func GetInvoiceService(
@@ -29,17 +40,128 @@ func GetInvoiceService(
 	companyStore *store.CompanyStore,
 	companyBatchStore *store.CompanyBatchStore,
 	brokerTaxStore *store.BrokerTaxStore,
+	b3Calendar *calendar.B3Calendar,
+	taxRateStore *store.TaxRateStore,
+	brokerStore *store.BrokerStore,
+	brokerBatchStore *store.BrokerBatchStore,
+	tickerStore *store.TickerStore,
+	companyAliasStore *store.CompanyAliasStore,
+	fiiTradeBatchStore *store.FiiTradeBatchStore,
+	optionTradeBatchStore *store.OptionTradeBatchStore,
 ) *InvoiceService {
 	return &InvoiceService{
-		tx:                tx,
-		invoiceInput:      invoiceInput,
-		taxStore:          taxStore,
-		companyStore:      companyStore,
-		companyBatchStore: companyBatchStore,
-		brokerTaxStore:    brokerTaxStore,
+		tx:                    tx,
+		invoiceInput:          invoiceInput,
+		taxStore:              taxStore,
+		companyStore:          companyStore,
+		companyBatchStore:     companyBatchStore,
+		brokerTaxStore:        brokerTaxStore,
+		b3Calendar:            b3Calendar,
+		taxRateStore:          taxRateStore,
+		brokerStore:           brokerStore,
+		brokerBatchStore:      brokerBatchStore,
+		tickerStore:           tickerStore,
+		fiiTradeBatchStore:    fiiTradeBatchStore,
+		optionTradeBatchStore: optionTradeBatchStore,
+		companyResolver:       GetCompanyResolverService(tx, companyAliasStore, tickerStore),
 	}
 }
 
+// getRate looks up the rate valid on the invoice market date, the date was
+// already checked by validateDates.
+func (isvc *InvoiceService) getRate(code string) float64 {
+	marketDate, _ := utils.ParseB3Date(isvc.invoiceInput.MarketDate)
+	return isvc.taxRateStore.Get(code, marketDate)
+}
+
+func (isvc *InvoiceService) getBroker() *model.Broker {
+	return isvc.brokerStore.Get(isvc.invoiceInput.AgentId)
+}
+
+func (isvc *InvoiceService) getIssRate() float64 {
+	marketDate, _ := utils.ParseB3Date(isvc.invoiceInput.MarketDate)
+
+	brokerageService := GetBrokerageService(
+		isvc.getBroker(),
+		marketDate,
+		isvc.brokerTaxStore,
+		isvc.taxRateStore,
+	)
+
+	return brokerageService.GetIssRate()
+}
+
+// getInvoiceSettlementDate returns the settlement date of an invoice, the
+// latest one of its item markets (options D+1, spot D+2).
+func getInvoiceSettlementDate(
+	b3Calendar *calendar.B3Calendar,
+	invoiceInput *input.Invoice,
+	marketDate utils.B3Date,
+) utils.B3Date {
+	settlementDate := utils.B3Date{}
+
+	for _, item := range invoiceInput.Items {
+		market := item.Market
+
+		if market == "" {
+			market = invoiceInput.Market
+		}
+
+		itemSettlementDate := b3Calendar.GetSettlementDate(marketDate, market)
+
+		if itemSettlementDate.After(settlementDate) {
+			settlementDate = itemSettlementDate
+		}
+	}
+
+	if settlementDate.IsZero() {
+		settlementDate = b3Calendar.GetSettlementDate(marketDate, invoiceInput.Market)
+	}
+
+	return settlementDate
+}
+
+func (isvc *InvoiceService) validateDates() error {
+	invoiceInput := isvc.invoiceInput
+	b3Calendar := isvc.b3Calendar
+
+	marketDate, err := utils.ParseB3Date(invoiceInput.MarketDate)
+
+	if err != nil {
+		return err
+	}
+
+	billingDate, err := utils.ParseB3Date(invoiceInput.BillingDate)
+
+	if err != nil {
+		return err
+	}
+
+	if !b3Calendar.IsTradingDay(marketDate) {
+		details := fmt.Sprintf(
+			"[%s, marketDate = %s, %s]",
+			invoiceInput.FileName,
+			marketDate.String(),
+			b3Calendar.GetClosure(marketDate),
+		)
+		return utils.GetError("invoiceService.validateDates", "ERR_CAL_001", details)
+	}
+
+	settlementDate := getInvoiceSettlementDate(b3Calendar, invoiceInput, marketDate)
+
+	if !billingDate.Equal(settlementDate) {
+		details := fmt.Sprintf(
+			"[%s, billingDate = %s, expected = %s]",
+			invoiceInput.FileName,
+			billingDate.String(),
+			settlementDate.String(),
+		)
+		return utils.GetError("invoiceService.validateDates", "ERR_CAL_002", details)
+	}
+
+	return nil
+}
+
 func (isvc *InvoiceService) getTaxBaseValue(inputTax *input.Tax) (float64, error) {
 	invoice := isvc.invoiceInput
 	taxTypes := constants.TaxTypes
@@ -70,19 +192,19 @@ func (isvc *InvoiceService) getTaxBaseValue(inputTax *input.Tax) (float64, error
 func (isvc *InvoiceService) getTaxValue(baseValue float64, taxInput *input.Tax) (float64, error) {
 	invoice := isvc.invoiceInput
 	taxTypes := constants.TaxTypes
-	taxRates := constants.TaxRates
+	rateCodes := constants.RateCodes
 
 	switch taxInput.Code {
 	case taxTypes.SETFEE:
-		return (baseValue * taxRates.SETFEE), nil
+		return (baseValue * isvc.getRate(rateCodes.SETFEE)), nil
 	case taxTypes.EMLFEE:
 		return (taxInput.Value), nil
 	case taxTypes.BRKFEE:
 		return baseValue, nil
 	case taxTypes.ISSSPFEE:
-		return ((baseValue / 0.95) - baseValue), nil
+		return ((baseValue / (1 - isvc.getIssRate())) - baseValue), nil
 	case taxTypes.IRRFFEE:
-		return (invoice.TotalSold * taxRates.IRRFFEE), nil
+		return (invoice.TotalSold * isvc.getRate(rateCodes.IRRFFEE)), nil
 	}
 
 	error := fmt.Errorf("invoiceTaxService::GetInvoiceTaxValue: Unknown tax code %s", taxInput.Code)
@@ -95,17 +217,12 @@ func (isvc *InvoiceService) getTaxRate(taxInput *input.Tax) float64 {
 	}
 
 	taxTypes := constants.TaxTypes
-	taxRates := constants.TaxRates
 
 	switch taxInput.Code {
-	case taxTypes.SETFEE:
-		return taxRates.SETFEE
-	case taxTypes.EMLFEE:
-		return taxRates.EMLFEE
+	case taxTypes.SETFEE, taxTypes.EMLFEE, taxTypes.IRRFFEE:
+		return isvc.getRate(taxInput.Code)
 	case taxTypes.ISSSPFEE:
-		return taxRates.ISSSPFEE
-	case taxTypes.IRRFFEE:
-		return taxRates.IRRFFEE
+		return isvc.getIssRate()
 	default:
 		return 0.0
 	}
@@ -192,25 +309,40 @@ func (isvc *InvoiceService) upsertUser() (*entity.User, error) {
 	return userService.UpsertUser()
 }
 
-func (isvc *InvoiceService) getInvoiceItem(invoice *entity.Invoice, itemInput *input.Item) (*entity.InvoiceItem, error) {
+// getInvoiceItem fractional market tickers (PETR4F) share the company of the
+// standard ticker, the segment is kept on the invoice item. Items without a
+// ticker are resolved from the security name.
+func (isvc *InvoiceService) getInvoiceItem(invoice *entity.Invoice, itemInput *input.Item) (*entity.InvoiceItem, string, error) {
+	inputCode := itemInput.Company.Code
+
+	if inputCode == "" {
+		resolvedCode, err := isvc.companyResolver.ResolveCode(itemInput.Company.Name)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		inputCode = resolvedCode
+	}
+
+	code, marketSegment := utils.NormalizeTicker(inputCode)
+
 	company := &entity.Company{
-		Code: itemInput.Company.Code,
+		Code: code,
 		Name: itemInput.Company.Name,
 	}
 
-	company.BDR = utils.IsBDR(company)
-	company.ETF = false // TODO implement support for ETFs
-
 	companyService := GetCompanyService(
 		isvc.tx,
 		company,
 		isvc.companyStore,
+		isvc.tickerStore,
 	)
 
 	companyRec, err := companyService.UpsertCompany()
 
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	invoiceItem := &entity.InvoiceItem{
@@ -223,7 +355,178 @@ func (isvc *InvoiceService) getInvoiceItem(invoice *entity.Invoice, itemInput *i
 		Order:      itemInput.Order,
 	}
 
-	return invoiceItem, nil
+	return invoiceItem, marketSegment, nil
+}
+
+func (isvc *InvoiceService) getTradeBatchService(
+	user *entity.User,
+	tradeBatch *entity.TradeBatch,
+) *TradeBatchService {
+	return GetTradeBatchService(
+		isvc.tx,
+		user,
+		tradeBatch,
+		isvc.taxStore,
+		isvc.taxRateStore,
+		isvc.tickerStore,
+		isvc.fiiTradeBatchStore,
+		isvc.optionTradeBatchStore,
+	)
+}
+
+// isOptionPremium option items carry the option market, notes without it
+// are told by the series ticker.
+func (isvc *InvoiceService) isOptionPremium(itemInput *input.Item, company *entity.Company) bool {
+	markets := constants.Markets
+
+	switch itemInput.Market {
+	case markets.CALL, markets.PUT:
+		return true
+	case "":
+		return GetAssetClass(isvc.tickerStore, company) == constants.AssetClasses.OPTION
+	default:
+		return false
+	}
+}
+
+func isOptionExercise(itemInput *input.Item) bool {
+	markets := constants.Markets
+	return itemInput.Market == markets.CALL_EXERCISE || itemInput.Market == markets.PUT_EXERCISE
+}
+
+// expireOptions closes the option batches expired before the invoice, each
+// result goes to the trade batch of its expiry date.
+func (isvc *InvoiceService) expireOptions(
+	user *entity.User,
+	optionService *OptionService,
+	marketDate time.Time,
+) error {
+	optionBatches, err := optionService.GetExpiredOptionBatches(marketDate)
+
+	if err != nil {
+		return err
+	}
+
+	for _, optionBatch := range optionBatches {
+		result, err := optionService.ExpireOption(optionBatch)
+
+		if err != nil {
+			return err
+		}
+
+		tradeBatch, err := isvc.getTradeBatchService(user, nil).FindTradeBatch(optionBatch.ExpiryDate)
+
+		if err != nil {
+			return err
+		}
+
+		tradeBatchService := isvc.getTradeBatchService(user, tradeBatch)
+		tradeBatchService.ProcessOptionResult(result, 0)
+
+		if _, err := tradeBatchService.SaveTradeBatch(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// processOptionExercise moves the premium of the exercised options to the
+// underlying, premiums of acquired shares go to their cost (paid on calls,
+// received on puts), premiums of sold shares are realized on the trade batch.
+func (isvc *InvoiceService) processOptionExercise(
+	user *entity.User,
+	optionService *OptionService,
+	series *entity.Company,
+	item *entity.InvoiceItem,
+	tradeBatch *entity.TradeBatch,
+) (*entity.TradeBatch, error) {
+	premium, written, err := optionService.ExerciseOption(series, item.Qty)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if premium == 0 {
+		return tradeBatch, nil
+	}
+
+	if !item.Debit {
+		result := -premium
+
+		if written {
+			result = premium
+		}
+
+		tradeBatchService := isvc.getTradeBatchService(user, tradeBatch)
+		return tradeBatchService.ProcessOptionResult(result, 0), nil
+	}
+
+	delta := premium
+
+	if written {
+		delta = -premium
+	}
+
+	companyBatchService := GetCompanyBatchService(isvc.tx, user, item.Company, isvc.companyBatchStore)
+	companyBatch, err := companyBatchService.GetCompanyBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if companyBatch == nil {
+		return tradeBatch, nil
+	}
+
+	companyBatchRec, err := companyBatchService.UpdateCompanyBatch(
+		companyBatch,
+		companyBatch.Qty,
+		companyBatch.TotalPrice+delta,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	brokerBatchService := GetBrokerBatchService(
+		isvc.tx,
+		companyBatchRec,
+		isvc.invoiceInput.AgentId,
+		isvc.brokerBatchStore,
+	)
+
+	if _, err := brokerBatchService.AdjustCost(delta); err != nil {
+		return nil, err
+	}
+
+	log.Printf(
+		"invoiceService.processOptionExercise: %s exercised into %s, cost delta = %.2f",
+		series.Code,
+		item.Company.Code,
+		delta,
+	)
+
+	return tradeBatch, nil
+}
+
+func (isvc *InvoiceService) getItemBatchService(
+	user *entity.User,
+	invoice *entity.Invoice,
+	item *entity.InvoiceItem,
+) *ItemBatchService {
+	return GetItemBatchService(
+		isvc.tx,
+		user,
+		invoice,
+		item,
+		isvc.taxStore,
+		isvc.brokerTaxStore,
+		isvc.companyBatchStore,
+		isvc.taxRateStore,
+		isvc.getBroker(),
+		isvc.brokerBatchStore,
+	)
 }
 
 func (isvc *InvoiceService) ProcessInvoice() (*entity.Invoice, error) {
@@ -238,7 +541,11 @@ func (isvc *InvoiceService) ProcessInvoice() (*entity.Invoice, error) {
 		NetValue:      invoiceInput.NetValue,
 	}
 
-	invoiceDAO := db.GetInvoiceDAO(isvc.tx, invoice)
+	if err := isvc.validateDates(); err != nil {
+		return nil, err
+	}
+
+	invoiceDAO := db.GetInvoiceDAO(isvc.tx, invoice, invoiceInput.AgentId)
 	isNew, err := invoiceDAO.IsNewInvoice()
 
 	if err != nil {
@@ -300,37 +607,89 @@ func (isvc *InvoiceService) ProcessInvoice() (*entity.Invoice, error) {
 		return nil, err
 	}
 
+	// handle options expired since the last invoice
+	optionService := GetOptionService(isvc.tx, userRec, isvc.companyStore, isvc.tickerStore, isvc.b3Calendar)
+
+	if err := isvc.expireOptions(userRec, optionService, invoiceRec.MarketDate); err != nil {
+		return nil, err
+	}
+
+	// termo positions due until the invoice settle before its sales
+	termoService := GetTermoService(isvc.tx, userRec, isvc.companyBatchStore, isvc.brokerBatchStore)
+
+	if _, err := termoService.SettleDuePositions(invoiceRec.MarketDate); err != nil {
+		return nil, err
+	}
+
 	//handle items
 	var tradeBatch *entity.TradeBatch = nil
 
 	items := make([]entity.InvoiceItem, 0, len(invoiceInput.Items))
 	for _, item := range invoiceInput.Items {
-		invoiceItem, err := isvc.getInvoiceItem(invoiceRec, &item)
+		invoiceItem, marketSegment, err := isvc.getInvoiceItem(invoiceRec, &item)
 
 		if err != nil {
 			return nil, err
 		}
 
-		invoiceItemService := GetInvoiceItemService(isvc.tx, invoiceItem)
+		// exercises trade the underlying at the strike price
+		var series *entity.Company = nil
+		isExercise := isOptionExercise(&item)
+
+		if isExercise {
+			series = invoiceItem.Company
+			invoiceItem.Company, err = optionService.GetUnderlying(series, item.Company.Name)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		isPremium := !isExercise && isvc.isOptionPremium(&item, invoiceItem.Company)
+
+		invoiceItemService := GetInvoiceItemService(isvc.tx, invoiceItem, marketSegment)
 		itemRec, err := invoiceItemService.CreateItem()
 
 		if err != nil {
 			return nil, err
 		}
 
-		if item.Debit {
-			// item batch
-			itemBatchService := GetItemBatchService(
-				isvc.tx,
-				userRec,
-				invoiceRec,
-				itemRec,
-				isvc.taxStore,
-				isvc.brokerTaxStore,
-				isvc.companyBatchStore,
-			)
+		if isPremium {
+			// option premiums have no item batch nor trade
+			if tradeBatch == nil {
+				tradeBatch, err = isvc.getTradeBatchService(userRec, nil).FindTradeBatch(invoiceRec.MarketDate)
+
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			result, closed, err := optionService.ProcessPremium(itemRec, &item)
+
+			if err != nil {
+				return nil, err
+			}
 
-			itemBatchRec, err := itemBatchService.CreateItemBatch()
+			if closed {
+				premium := itemRec.Price * float64(itemRec.Qty)
+				tradeBatch = isvc.getTradeBatchService(userRec, tradeBatch).ProcessOptionResult(result, premium)
+			}
+		} else if item.Market == constants.Markets.TERMO {
+			// termo purchases reach the company batch on settlement
+			taxGroup, err := isvc.getItemBatchService(userRec, invoiceRec, itemRec).CreateTaxGroup()
+
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = termoService.OpenPosition(itemRec, taxGroup, isvc.invoiceInput.AgentId, item.DueDate)
+
+			if err != nil {
+				return nil, err
+			}
+		} else if item.Debit {
+			// item batch
+			itemBatchRec, err := isvc.getItemBatchService(userRec, invoiceRec, itemRec).CreateItemBatch()
 
 			if err != nil {
 				return nil, err
@@ -340,14 +699,7 @@ func (isvc *InvoiceService) ProcessInvoice() (*entity.Invoice, error) {
 		} else {
 			// trade batch
 			if tradeBatch == nil {
-				tradeBatchService := GetTradeBatchService(
-					isvc.tx,
-					userRec,
-					tradeBatch,
-					isvc.taxStore,
-				)
-
-				tradeBatch, err = tradeBatchService.FindTradeBatch(invoiceRec.MarketDate)
+				tradeBatch, err = isvc.getTradeBatchService(userRec, nil).FindTradeBatch(invoiceRec.MarketDate)
 
 				if err != nil {
 					return nil, err
@@ -363,6 +715,9 @@ func (isvc *InvoiceService) ProcessInvoice() (*entity.Invoice, error) {
 				isvc.taxStore,
 				isvc.brokerTaxStore,
 				isvc.companyBatchStore,
+				isvc.taxRateStore,
+				isvc.getBroker(),
+				isvc.brokerBatchStore,
 			)
 
 			tradeRec, err := tradeService.ProcessTrade()
@@ -371,38 +726,32 @@ func (isvc *InvoiceService) ProcessInvoice() (*entity.Invoice, error) {
 				return nil, err
 			}
 
-			tradeBatchService := GetTradeBatchService(
-				isvc.tx,
-				userRec,
-				tradeBatch,
-				isvc.taxStore,
-			)
-
-			tradeBatch = tradeBatchService.ProcessTrade(tradeRec)
+			tradeBatch = isvc.getTradeBatchService(userRec, tradeBatch).ProcessTrade(tradeRec)
 
 			tradeRec.TradeBatch = tradeBatch
 			itemRec.Trade = tradeRec
 		}
 
+		if isExercise {
+			tradeBatch, err = isvc.processOptionExercise(userRec, optionService, series, itemRec, tradeBatch)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		items = append(items, *itemRec)
 	}
 
 	if tradeBatch != nil {
-		tradeBatchService := GetTradeBatchService(
-			isvc.tx,
-			userRec,
-			tradeBatch,
-			isvc.taxStore,
-		)
-
-		tradeBatch, err = tradeBatchService.SaveTradeBatch()
+		tradeBatch, err = isvc.getTradeBatchService(userRec, tradeBatch).SaveTradeBatch()
 
 		if err != nil {
 			return nil, err
 		}
 
 		for _, item := range items {
-			if !item.Debit {
+			if item.Trade != nil {
 				item.Trade.TradeBatch = tradeBatch
 			}
 		}
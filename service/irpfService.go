@@ -0,0 +1,558 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type IrpfService struct {
+	tx           *sql.Tx
+	user         *entity.User
+	year         int
+	taxRateStore *store.TaxRateStore
+	tickerStore  *store.TickerStore
+}
+
+type irpfPosition struct {
+	company    *entity.Company
+	qty        int64
+	totalPrice float64
+}
+
+func GetIrpfService(
+	tx *sql.Tx,
+	user *entity.User,
+	year int,
+	taxRateStore *store.TaxRateStore,
+	tickerStore *store.TickerStore,
+) *IrpfService {
+	return &IrpfService{
+		tx:           tx,
+		user:         user,
+		year:         year,
+		taxRateStore: taxRateStore,
+		tickerStore:  tickerStore,
+	}
+}
+
+// getPositions replays the position events up to the given date with the
+// day trade rules of the monthly results, day trades are left out of the
+// average cost.
+func (isvc *IrpfService) getPositions(until utils.B3Date) (map[string]*irpfPosition, error) {
+	positionDAO := db.GetPositionDAO(isvc.tx, isvc.user)
+	events, err := positionDAO.GetPositionEvents(until.Time())
+
+	if err != nil {
+		return nil, err
+	}
+
+	positions, _ := replayIrpfPositions(events, func(*entity.Company) bool {
+		return false
+	})
+
+	return positions, nil
+}
+
+func (isvc *IrpfService) getAssetCode(company *entity.Company) constants.IrpfAssetCode {
+	assetClasses := constants.AssetClasses
+
+	switch GetAssetClass(isvc.tickerStore, company) {
+	case assetClasses.BDR:
+		return constants.IrpfAssetCodes.BDR
+	case assetClasses.ETF:
+		return constants.IrpfAssetCodes.ETF
+	case assetClasses.FII:
+		return constants.IrpfAssetCodes.FII
+	}
+
+	return constants.IrpfAssetCodes.SHR
+}
+
+func (isvc *IrpfService) getAssets() ([]model.IrpfAsset, error) {
+	prevYearEnd := utils.NewB3Date(isvc.year-1, time.December, 31)
+	yearEnd := utils.NewB3Date(isvc.year, time.December, 31)
+
+	prevPositions, err := isvc.getPositions(prevYearEnd)
+
+	if err != nil {
+		return nil, err
+	}
+
+	positions, err := isvc.getPositions(yearEnd)
+
+	if err != nil {
+		return nil, err
+	}
+
+	assets := make([]model.IrpfAsset, 0, len(positions))
+
+	for code, position := range positions {
+		var prevQty int64
+		var prevTotal float64
+
+		if prevPosition, ok := prevPositions[code]; ok {
+			prevQty = prevPosition.qty
+			prevTotal = prevPosition.totalPrice
+		}
+
+		if position.qty == 0 && prevQty == 0 {
+			continue
+		}
+
+		company := position.company
+		assetCode := isvc.getAssetCode(company)
+
+		avgPrice := 0.0
+
+		if position.qty > 0 {
+			avgPrice = position.totalPrice / float64(position.qty)
+		}
+
+		description := fmt.Sprintf(
+			"%d %s %s (%s) - CUSTO MEDIO R$ %.2f",
+			position.qty,
+			assetCode.Description,
+			company.Name,
+			company.Code,
+			avgPrice,
+		)
+
+		assets = append(assets, model.IrpfAsset{
+			Group:       assetCode.Group,
+			Code:        assetCode.Code,
+			Ticker:      company.Code,
+			Name:        company.Name,
+			AssetType:   utils.GetAssetType(company),
+			PrevQty:     prevQty,
+			PrevTotal:   prevTotal,
+			Qty:         position.qty,
+			Total:       position.totalPrice,
+			Description: description,
+		})
+	}
+
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i].Ticker < assets[j].Ticker
+	})
+
+	return assets, nil
+}
+
+// irpfMonthTrades the invoice trades of one month, day trades apart from
+// the common operations, by asset type (SHR, BDR, ETF).
+type irpfMonthTrades struct {
+	dayTradeResults float64
+	commonResults   map[string]float64
+	commonSold      map[string]float64
+	saleTaxes       map[string]float64
+}
+
+// irpfDayTrades the events of one company on one market date.
+type irpfDayTrades struct {
+	company *entity.Company
+	events  []model.PositionEvent
+	buyQty  int64
+	buyCost float64
+	sellQty int64
+	sellRaw float64
+	sellTax float64
+}
+
+// getIrpfMonthTrades the trades of the month starting on monthStart.
+func getIrpfMonthTrades(monthTrades map[string]*irpfMonthTrades, monthStart utils.B3Date) *irpfMonthTrades {
+	key := monthStart.FirstDayOfMonth().String()
+	trades, ok := monthTrades[key]
+
+	if !ok {
+		trades = &irpfMonthTrades{
+			commonResults: make(map[string]float64),
+			commonSold:    make(map[string]float64),
+			saleTaxes:     make(map[string]float64),
+		}
+		monthTrades[key] = trades
+	}
+
+	return trades
+}
+
+// applyIrpfDayTrades moves the position by the events of a day, the quantity
+// bought and sold on invoices of the same day is a day trade valued at the
+// day prices and left out of the position; the rest is a common operation
+// valued at the average cost. Costs carry buy taxes and sales are net of
+// their taxes, the basis of the trade batch results.
+func applyIrpfDayTrades(day *irpfDayTrades, position *irpfPosition, trades *irpfMonthTrades) {
+	dayTradeQty := day.buyQty
+
+	if day.sellQty < dayTradeQty {
+		dayTradeQty = day.sellQty
+	}
+
+	assetType := utils.GetAssetType(day.company)
+
+	for _, event := range day.events {
+		if event.InvoiceItem {
+			continue
+		}
+
+		if event.Debit {
+			position.qty = position.qty + event.Qty
+			position.totalPrice = position.totalPrice + event.TotalPrice
+			continue
+		}
+
+		if position.qty > 0 {
+			avgPrice := position.totalPrice / float64(position.qty)
+			position.qty = position.qty - event.Qty
+			position.totalPrice = avgPrice * float64(position.qty)
+		}
+
+		// fractions paid in cash, their cost already left the position
+		if event.TotalPrice > 0 || event.Results != 0 {
+			trades.commonResults[assetType] = trades.commonResults[assetType] + event.Results
+			trades.commonSold[assetType] = trades.commonSold[assetType] + event.TotalPrice
+		}
+	}
+
+	if day.sellQty > 0 {
+		trades.saleTaxes[assetType] = trades.saleTaxes[assetType] + day.sellTax
+	}
+
+	if dayTradeQty > 0 {
+		buyPrice := day.buyCost / float64(day.buyQty)
+		sellPrice := (day.sellRaw - day.sellTax) / float64(day.sellQty)
+		trades.dayTradeResults = trades.dayTradeResults + float64(dayTradeQty)*(sellPrice-buyPrice)
+	}
+
+	if buyQty := day.buyQty - dayTradeQty; buyQty > 0 {
+		position.qty = position.qty + buyQty
+		position.totalPrice = position.totalPrice + day.buyCost*float64(buyQty)/float64(day.buyQty)
+	}
+
+	sellQty := day.sellQty - dayTradeQty
+
+	if sellQty == 0 || position.qty == 0 {
+		return
+	}
+
+	avgPrice := position.totalPrice / float64(position.qty)
+	position.qty = position.qty - sellQty
+	position.totalPrice = avgPrice * float64(position.qty)
+
+	sellRaw := day.sellRaw * float64(sellQty) / float64(day.sellQty)
+	sellTax := day.sellTax * float64(sellQty) / float64(day.sellQty)
+
+	trades.commonResults[assetType] = trades.commonResults[assetType] + sellRaw - sellTax - avgPrice*float64(sellQty)
+	trades.commonSold[assetType] = trades.commonSold[assetType] + sellRaw
+}
+
+// getShrMonthResults the common share results and sales of the month, costs
+// charged to the share results apart from trades (stock lending fees) are
+// the batch taxes not paid on sales; day trades are never exempt and do not
+// count for the exemption limit.
+func getShrMonthResults(trades *irpfMonthTrades, shrTotalTax float64, irExemptLimit float64) (float64, float64, bool) {
+	shrCosts := shrTotalTax - trades.saleTaxes["SHR"]
+
+	if shrCosts < 0.005 {
+		shrCosts = 0
+	}
+
+	shrResults := trades.commonResults["SHR"] - shrCosts
+	shrSold := trades.commonSold["SHR"]
+
+	return shrResults, shrSold, shrSold <= irExemptLimit
+}
+
+// replayIrpfPositions replays the position events day by day, the source
+// of both the positions and the monthly results so day trades are never
+// counted on the average cost; companies filtered out (FII, taxed apart) are
+// skipped.
+func replayIrpfPositions(
+	events []model.PositionEvent,
+	skipCompany func(*entity.Company) bool,
+) (map[string]*irpfPosition, map[string]*irpfMonthTrades) {
+	monthTrades := make(map[string]*irpfMonthTrades)
+	positions := make(map[string]*irpfPosition)
+
+	for idx := 0; idx < len(events); {
+		marketDate := utils.B3DateOf(events[idx].MarketDate)
+		days := make(map[string]*irpfDayTrades)
+		codes := make([]string, 0)
+
+		for ; idx < len(events) && utils.B3DateOf(events[idx].MarketDate).Equal(marketDate); idx++ {
+			event := events[idx]
+			day, ok := days[event.Company.Code]
+
+			if !ok {
+				day = &irpfDayTrades{company: event.Company}
+				days[event.Company.Code] = day
+				codes = append(codes, event.Company.Code)
+			}
+
+			day.events = append(day.events, event)
+
+			if !event.InvoiceItem {
+				continue
+			}
+
+			if event.Debit {
+				day.buyQty = day.buyQty + event.Qty
+				day.buyCost = day.buyCost + event.TotalPrice
+			} else {
+				day.sellQty = day.sellQty + event.Qty
+				day.sellRaw = day.sellRaw + event.TotalPrice
+				day.sellTax = day.sellTax + event.TotalTax
+			}
+		}
+
+		for _, code := range codes {
+			day := days[code]
+
+			if skipCompany(day.company) {
+				continue
+			}
+
+			position, ok := positions[code]
+
+			if !ok {
+				position = &irpfPosition{company: day.company}
+				positions[code] = position
+			}
+
+			applyIrpfDayTrades(day, position, getIrpfMonthTrades(monthTrades, marketDate))
+		}
+	}
+
+	return positions, monthTrades
+}
+
+// getMonths the monthly results of the year. Share, BDR and ETF results come
+// from the position replay, option and futures results from their batches;
+// accumulated losses and the IR due are carried from the first month with
+// events, trade batch losses and IR (day trades mixed in) are not used.
+func (isvc *IrpfService) getMonths() ([]model.IrpfMonth, []model.IrpfExemptGain, error) {
+	yearStart := utils.NewB3Date(isvc.year, time.January, 1)
+	to := yearStart.AddMonths(12)
+
+	positionDAO := db.GetPositionDAO(isvc.tx, isvc.user)
+	events, err := positionDAO.GetPositionEvents(to.AddDays(-1).Time())
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	from := yearStart
+
+	if len(events) > 0 {
+		firstMonth := utils.B3DateOf(events[0].MarketDate).FirstDayOfMonth()
+
+		if firstMonth.Before(from) {
+			from = firstMonth
+		}
+	}
+
+	tradeBatchDAO := db.GetTradeBatchDAO(isvc.tx, &entity.TradeBatch{
+		User: isvc.user,
+	})
+
+	tradeBatches, err := tradeBatchDAO.GetTradeBatchesByPeriod(from.Time(), to.Time())
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fiiTradeBatchDAO := db.GetFiiTradeBatchDAO(isvc.tx, &model.FiiTradeBatch{
+		TradeBatch: &entity.TradeBatch{
+			User: isvc.user,
+		},
+	})
+
+	fiiTradeBatches, err := fiiTradeBatchDAO.GetFiiTradeBatchesByPeriod(from.Time(), to.Time())
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	optionTradeBatchDAO := db.GetOptionTradeBatchDAO(isvc.tx, &model.OptionTradeBatch{
+		TradeBatch: &entity.TradeBatch{
+			User: isvc.user,
+		},
+	})
+
+	optionTradeBatches, err := optionTradeBatchDAO.GetOptionTradeBatchesByPeriod(from.Time(), to.Time())
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	futuresBatchDAO := db.GetFuturesBatchDAO(isvc.tx, &model.FuturesBatch{
+		User: isvc.user,
+	})
+
+	futuresBatches, err := futuresBatchDAO.GetFuturesBatchesByPeriod(from.Time(), to.Time())
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// FII trades have their own bucket on the trade batch
+	_, monthTrades := replayIrpfPositions(events, func(company *entity.Company) bool {
+		return GetAssetClass(isvc.tickerStore, company) == constants.AssetClasses.FII
+	})
+
+	tradeBatchByMonth := make(map[string]*entity.TradeBatch)
+
+	for _, tradeBatch := range tradeBatches {
+		tradeBatchByMonth[utils.B3DateOf(tradeBatch.StartDate).FirstDayOfMonth().String()] = tradeBatch
+	}
+
+	futuresBatchByMonth := make(map[string]*model.FuturesBatch)
+
+	for _, futuresBatch := range futuresBatches {
+		futuresBatchByMonth[utils.B3DateOf(futuresBatch.StartDate).FirstDayOfMonth().String()] = futuresBatch
+	}
+
+	months := make([]model.IrpfMonth, 0, 12)
+	exemptGains := make([]model.IrpfExemptGain, 0)
+	dayTradeAccLoss := 0.0
+	commonAccLoss := 0.0
+
+	for monthStart := from; monthStart.Before(to); monthStart = monthStart.AddMonths(1) {
+		month := monthStart.Month()
+		key := monthStart.String()
+		irExemptLimit := isvc.taxRateStore.Get(constants.RateCodes.IR_EXPT_LIMIT, monthStart)
+		irFeeRate := isvc.taxRateStore.Get(constants.RateCodes.IRFEE, monthStart)
+		irDayTradeRate := isvc.taxRateStore.Get(constants.RateCodes.IRDTFEE, monthStart)
+
+		trades := getIrpfMonthTrades(monthTrades, monthStart)
+
+		irpfMonth := model.IrpfMonth{
+			Month:           int(month),
+			DayTradeResults: trades.dayTradeResults,
+			DayTradeAccLoss: dayTradeAccLoss,
+			CommonAccLoss:   commonAccLoss,
+		}
+
+		// share sale taxes are on the replay, the rest of the batch taxes
+		// are costs such as stock lending fees
+		shrTotalTax := trades.saleTaxes["SHR"]
+		tradeBatch, hasTradeBatch := tradeBatchByMonth[key]
+
+		if hasTradeBatch {
+			shrTotalTax = tradeBatch.Shr.TotalTax
+		}
+
+		shrResults, shrSold, shrExempt := getShrMonthResults(trades, shrTotalTax, irExemptLimit)
+		commonResults := trades.commonResults["BDR"] + trades.commonResults["ETF"]
+
+		if shrExempt && shrResults > 0 {
+			if monthStart.Year() == isvc.year {
+				exemptGains = append(exemptGains, model.IrpfExemptGain{
+					Month:     int(month),
+					TotalSold: shrSold,
+					Gain:      shrResults,
+				})
+			}
+		} else {
+			commonResults = commonResults + shrResults
+		}
+
+		if hasTradeBatch {
+			// option results share the common operations loss
+			if optionTradeBatch, ok := optionTradeBatches[tradeBatch.Id]; ok {
+				opt := optionTradeBatch.Data
+				commonResults = commonResults + (opt.Results - opt.TotalTax)
+			}
+
+			// FII results have their own loss and no exemption
+			if fiiTradeBatch, ok := fiiTradeBatches[tradeBatch.Id]; ok {
+				fii := fiiTradeBatch.Data
+
+				irpfMonth.FiiResults = fii.Results - fii.TotalTax
+				irpfMonth.FiiAccLoss = fii.AccLoss
+				irpfMonth.FiiIRPaid = utils.GetTaxValueByGroup(
+					tradeBatch.TaxGroup,
+					constants.TaxTypes.IRFIIFEE,
+				)
+			}
+		}
+
+		// futures day trades join the day trade results, positions the
+		// common operations
+		if futuresBatch, ok := futuresBatchByMonth[key]; ok {
+			dayTrade := futuresBatch.DayTrade
+			common := futuresBatch.Common
+
+			irpfMonth.DayTradeResults = irpfMonth.DayTradeResults + (dayTrade.Results - dayTrade.TotalTax)
+			commonResults = commonResults + (common.Results - common.TotalTax)
+		}
+
+		irpfMonth.CommonResults = commonResults
+
+		if commonBase := commonResults + commonAccLoss; commonBase > 0 {
+			irpfMonth.CommonIRDue = commonBase * irFeeRate
+			commonAccLoss = 0
+		} else {
+			commonAccLoss = commonBase
+		}
+
+		if dayTradeBase := irpfMonth.DayTradeResults + dayTradeAccLoss; dayTradeBase > 0 {
+			irpfMonth.DayTradeIRDue = dayTradeBase * irDayTradeRate
+			dayTradeAccLoss = 0
+		} else {
+			dayTradeAccLoss = dayTradeBase
+		}
+
+		if monthStart.Year() == isvc.year {
+			months = append(months, irpfMonth)
+		}
+	}
+
+	return months, exemptGains, nil
+}
+
+func (isvc *IrpfService) GetWorksheet() (*model.IrpfWorksheet, error) {
+	assets, err := isvc.getAssets()
+
+	if err != nil {
+		return nil, err
+	}
+
+	months, exemptGains, err := isvc.getMonths()
+
+	if err != nil {
+		return nil, err
+	}
+
+	totalExemptGain := 0.0
+
+	for _, exemptGain := range exemptGains {
+		totalExemptGain = totalExemptGain + exemptGain.Gain
+	}
+
+	log.Printf(
+		"IrpfService.GetWorksheet: built worksheet [usr = %d, year = %d, assets = %d]",
+		isvc.user.Id,
+		isvc.year,
+		len(assets),
+	)
+
+	return &model.IrpfWorksheet{
+		UserName:        isvc.user.UserName,
+		UserUUID:        isvc.user.UUID.String(),
+		Year:            isvc.year,
+		Assets:          assets,
+		Months:          months,
+		ExemptGains:     exemptGains,
+		TotalExemptGain: totalExemptGain,
+	}, nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+func getTestEvent(
+	date utils.B3Date,
+	company *entity.Company,
+	debit bool,
+	qty int64,
+	totalPrice float64,
+	totalTax float64,
+) model.PositionEvent {
+	return model.PositionEvent{
+		MarketDate:  date.Time(),
+		Company:     company,
+		Debit:       debit,
+		Qty:         qty,
+		TotalPrice:  totalPrice,
+		TotalTax:    totalTax,
+		InvoiceItem: true,
+	}
+}
+
+func assertValue(t *testing.T, name string, got float64, want float64) {
+	t.Helper()
+
+	if math.Abs(got-want) > 0.001 {
+		t.Errorf("%s = %.4f, want %.4f", name, got, want)
+	}
+}
+
+// TestMonthMixingExemptSwingTradesAndDayTrades sells R$ 15.000,00 of PETR4
+// held since January and day trades R$ 10.000,00 of VALE3 in February; the
+// month sold R$ 25.000,00 but only the swing trade counts for the exemption
+// limit, and the VALE3 day trade keeps its average cost untouched on both the
+// March results and the year-end position.
+func TestMonthMixingExemptSwingTradesAndDayTrades(t *testing.T) {
+	petr := &entity.Company{Id: 1, Code: "PETR4"}
+	vale := &entity.Company{Id: 2, Code: "VALE3"}
+
+	january := utils.NewB3Date(2024, time.January, 10)
+	february := utils.NewB3Date(2024, time.February, 6)
+	march := utils.NewB3Date(2024, time.March, 1)
+
+	events := []model.PositionEvent{
+		getTestEvent(january, petr, true, 500, 12000.00, 0),
+		getTestEvent(january, vale, true, 100, 6000.00, 0),
+		getTestEvent(february, petr, false, 500, 15000.00, 1.50),
+		getTestEvent(february, vale, true, 150, 9000.30, 0),
+		getTestEvent(february, vale, false, 150, 10000.00, 0.70),
+		getTestEvent(march, vale, false, 50, 3500.00, 0),
+	}
+
+	positions, monthTrades := replayIrpfPositions(events, func(*entity.Company) bool {
+		return false
+	})
+
+	trades := getIrpfMonthTrades(monthTrades, february)
+
+	assertValue(t, "day trade results", trades.dayTradeResults, (10000.00-0.70)-9000.30)
+	assertValue(t, "SHR common results", trades.commonResults["SHR"], (15000.00-1.50)-12000.00)
+	assertValue(t, "SHR common sold", trades.commonSold["SHR"], 15000.00)
+
+	shrResults, shrSold, shrExempt := getShrMonthResults(trades, 1.50+0.70, 20000.00)
+
+	if !shrExempt {
+		t.Errorf("swing trades of %.2f should be exempt", shrSold)
+	}
+
+	assertValue(t, "SHR exempt gain", shrResults, 2998.50)
+
+	// VALE3 still at the January average cost
+	trades = getIrpfMonthTrades(monthTrades, march)
+
+	assertValue(t, "March common results", trades.commonResults["SHR"], 3500.00-50*60.00)
+	assertValue(t, "March day trade results", trades.dayTradeResults, 0)
+
+	// the year-end position keeps the same cost basis
+	if positions["VALE3"].qty != 50 {
+		t.Errorf("VALE3 qty = %d, want 50", positions["VALE3"].qty)
+	}
+
+	assertValue(t, "VALE3 total", positions["VALE3"].totalPrice, 50*60.00)
+}
+
+// TestShrMonthResultsAboveLimit charges lending fees to the share results
+// and taxes the month whose swing trades pass the exemption limit.
+func TestShrMonthResultsAboveLimit(t *testing.T) {
+	trades := &irpfMonthTrades{
+		commonResults: map[string]float64{"SHR": 1000.00},
+		commonSold:    map[string]float64{"SHR": 25000.00},
+		saleTaxes:     map[string]float64{"SHR": 5.00},
+	}
+
+	shrResults, _, shrExempt := getShrMonthResults(trades, 5.00+12.00, 20000.00)
+
+	if shrExempt {
+		t.Error("swing trades above the limit should not be exempt")
+	}
+
+	assertValue(t, "SHR results", shrResults, 988.00)
+}
+
+// TestFractionPaidInCash sells the 0,5 share fraction of an incorporation
+// for R$ 20,00 whose cost already left the target position, the result joins
+// the common results without moving the position.
+func TestFractionPaidInCash(t *testing.T) {
+	vale := &entity.Company{Id: 2, Code: "VALE3"}
+
+	march := utils.NewB3Date(2024, time.March, 1)
+	april := utils.NewB3Date(2024, time.April, 2)
+
+	events := []model.PositionEvent{
+		getTestEvent(march, vale, true, 10, 600.00, 0),
+		{
+			MarketDate: april.Time(),
+			Company:    vale,
+			TotalPrice: 20.00,
+			Results:    20.00 - 15.00,
+		},
+	}
+
+	positions, monthTrades := replayIrpfPositions(events, func(*entity.Company) bool {
+		return false
+	})
+
+	trades := getIrpfMonthTrades(monthTrades, april)
+
+	assertValue(t, "fraction results", trades.commonResults["SHR"], 5.00)
+	assertValue(t, "fraction sold", trades.commonSold["SHR"], 20.00)
+	assertValue(t, "VALE3 total", positions["VALE3"].totalPrice, 600.00)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
 	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
 	"github.com/jarismar/b3c-invoice-reader-lambda/store"
 	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
 	"github.com/jarismar/b3c-service-entities/entity"
@@ -19,6 +20,9 @@ type ItemBatchService struct {
 	taxStore          *store.TaxStore
 	brokerTaxStore    *store.BrokerTaxStore
 	companyBatchStore *store.CompanyBatchStore
+	taxRateStore      *store.TaxRateStore
+	broker            *model.Broker
+	brokerBatchStore  *store.BrokerBatchStore
 }
 
 func GetItemBatchService(
@@ -29,6 +33,9 @@ func GetItemBatchService(
 	taxStore *store.TaxStore,
 	brokerTaxStore *store.BrokerTaxStore,
 	companyBatchStore *store.CompanyBatchStore,
+	taxRateStore *store.TaxRateStore,
+	broker *model.Broker,
+	brokerBatchStore *store.BrokerBatchStore,
 ) *ItemBatchService {
 	return &ItemBatchService{
 		tx:                tx,
@@ -38,6 +45,9 @@ func GetItemBatchService(
 		taxStore:          taxStore,
 		brokerTaxStore:    brokerTaxStore,
 		companyBatchStore: companyBatchStore,
+		taxRateStore:      taxRateStore,
+		broker:            broker,
+		brokerBatchStore:  brokerBatchStore,
 	}
 }
 
@@ -159,36 +169,22 @@ func (ibsvc *ItemBatchService) getTaxValue(
 ) float64 {
 	taxCode := invoiceTaxInstance.Tax.Code
 	taxTypes := constants.TaxTypes
-	taxRates := constants.TaxRates
 	item := ibsvc.invoiceItem
+	marketDate := utils.B3DateOf(ibsvc.invoice.MarketDate)
 
-	if taxCode == taxTypes.BRKFEE {
-		if ibsvc.brokerTaxStore.Has(item, taxCode) {
-			return 0
-		}
-
-		if invoiceTaxInstance.TaxValue == 0 {
-			return 0
-		}
-
-		ibsvc.brokerTaxStore.Put(item, taxCode)
-
-		return taxRates.BRKFEE
-	}
-
-	if taxCode == taxTypes.ISSSPFEE {
-		if ibsvc.brokerTaxStore.Has(item, taxCode) {
-			return 0
-		}
-
+	if taxCode == taxTypes.BRKFEE || taxCode == taxTypes.ISSSPFEE {
 		if invoiceTaxInstance.TaxValue == 0 {
 			return 0
 		}
 
-		ibsvc.brokerTaxStore.Put(item, taxCode)
+		brokerageService := GetBrokerageService(
+			ibsvc.broker,
+			marketDate,
+			ibsvc.brokerTaxStore,
+			ibsvc.taxRateStore,
+		)
 
-		// t = (b / (1 - i)) - c
-		return (taxRates.BRKFEE / (1 - taxRates.ISSSPFEE)) - taxRates.BRKFEE
+		return brokerageService.GetTaxValue(item, taxCode)
 	}
 
 	if taxCode == taxTypes.IRRFFEE {
@@ -250,6 +246,12 @@ func (ibsvc *ItemBatchService) getTaxGroup() (*entity.TaxGroup, error) {
 	return taxGroupService.CreateTaxGroup()
 }
 
+// CreateTaxGroup allocates the invoice fees to the item, used by purchases
+// kept out of the company batch (termo).
+func (ibsvc *ItemBatchService) CreateTaxGroup() (*entity.TaxGroup, error) {
+	return ibsvc.getTaxGroup()
+}
+
 func (ibsvc *ItemBatchService) getItemBatch() (*entity.ItemBatch, error) {
 	item := ibsvc.invoiceItem
 
@@ -269,6 +271,19 @@ func (ibsvc *ItemBatchService) getItemBatch() (*entity.ItemBatch, error) {
 	rawPrice := item.Price * float64(item.Qty)
 	avgPrice := (rawPrice + totalTaxes) / float64(item.Qty)
 
+	brokerBatchService := GetBrokerBatchService(
+		ibsvc.tx,
+		companyBatch,
+		ibsvc.broker.AgentId,
+		ibsvc.brokerBatchStore,
+	)
+
+	_, err = brokerBatchService.AddQty(item.Qty, rawPrice+totalTaxes, item.MarketDate)
+
+	if err != nil {
+		return nil, err
+	}
+
 	itemBatch := &entity.ItemBatch{
 		Item:         item,
 		TaxGroup:     taxGroup,
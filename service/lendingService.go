@@ -0,0 +1,299 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// LendingService records stock lending (BTC) contracts, lent shares stay in
+// the company batch of the lender.
+type LendingService struct {
+	tx                    *sql.Tx
+	lendingInput          *input.LendingContracts
+	taxStore              *store.TaxStore
+	companyStore          *store.CompanyStore
+	taxRateStore          *store.TaxRateStore
+	tickerStore           *store.TickerStore
+	fiiTradeBatchStore    *store.FiiTradeBatchStore
+	optionTradeBatchStore *store.OptionTradeBatchStore
+}
+
+func GetLendingService(
+	tx *sql.Tx,
+	lendingInput *input.LendingContracts,
+	taxStore *store.TaxStore,
+	companyStore *store.CompanyStore,
+	taxRateStore *store.TaxRateStore,
+	tickerStore *store.TickerStore,
+	fiiTradeBatchStore *store.FiiTradeBatchStore,
+	optionTradeBatchStore *store.OptionTradeBatchStore,
+) *LendingService {
+	return &LendingService{
+		tx:                    tx,
+		lendingInput:          lendingInput,
+		taxStore:              taxStore,
+		companyStore:          companyStore,
+		taxRateStore:          taxRateStore,
+		tickerStore:           tickerStore,
+		fiiTradeBatchStore:    fiiTradeBatchStore,
+		optionTradeBatchStore: optionTradeBatchStore,
+	}
+}
+
+func (lsvc *LendingService) validateContract(contractInput *input.LendingContract) error {
+	lendingSides := constants.LendingSides
+
+	if contractInput.Side != lendingSides.LENDER && contractInput.Side != lendingSides.BORROWER {
+		details := fmt.Sprintf("[%s, unknown side %s]", contractInput.Contract, contractInput.Side)
+		return utils.GetError("lendingService.validateContract", "ERR_SYS_001", details)
+	}
+
+	if contractInput.Contract == "" || contractInput.Qty <= 0 {
+		details := fmt.Sprintf("[%s, missing contract number or quantity]", contractInput.Company.Code)
+		return utils.GetError("lendingService.validateContract", "ERR_SYS_001", details)
+	}
+
+	if contractInput.Side == lendingSides.BORROWER && contractInput.Irrf != 0 {
+		details := fmt.Sprintf("[%s, borrowers have no withheld IR]", contractInput.Contract)
+		return utils.GetError("lendingService.validateContract", "ERR_SYS_001", details)
+	}
+
+	return nil
+}
+
+// getTaxGroup lender income is an earning with its withheld IR, borrowers get
+// the lending fee; the contract fees go with both.
+func (lsvc *LendingService) getTaxGroup(lendingContract *model.LendingContract) (*entity.TaxGroup, error) {
+	taxTypes := constants.TaxTypes
+	taxSources := constants.TaxSources
+
+	groupId, err := utils.GetTaxGroupIdFromTime(
+		lendingContract.EndDate,
+		constants.TaxGroupPrefix.EARNING,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	taxInstances := make([]entity.TaxInstance, 0, 2)
+
+	if lendingContract.Side == constants.LendingSides.LENDER {
+		taxInstances = append(taxInstances, entity.TaxInstance{
+			Tax: &entity.Tax{
+				Code:   taxTypes.IRRFFEE,
+				Source: taxSources.EARNING,
+				Rate:   lsvc.lendingIrrfRate(lendingContract),
+			},
+			MarketDate: lendingContract.EndDate,
+			TaxValue:   lendingContract.Irrf,
+			BaseValue:  lendingContract.Value,
+			TaxRate:    lsvc.lendingIrrfRate(lendingContract),
+		})
+	} else {
+		taxInstances = append(taxInstances, entity.TaxInstance{
+			Tax: &entity.Tax{
+				Code:   taxTypes.BTCFEE,
+				Source: taxSources.EARNING,
+				Rate:   lendingContract.Rate,
+			},
+			MarketDate: lendingContract.EndDate,
+			TaxValue:   lendingContract.Value,
+			BaseValue:  0,
+			TaxRate:    lendingContract.Rate,
+		})
+	}
+
+	if lendingContract.Fees > 0 {
+		taxInstances = append(taxInstances, entity.TaxInstance{
+			Tax: &entity.Tax{
+				Code:   taxTypes.BRKFEE,
+				Source: taxSources.EARNING,
+			},
+			MarketDate: lendingContract.EndDate,
+			TaxValue:   lendingContract.Fees,
+			BaseValue:  lendingContract.Fees,
+		})
+	}
+
+	taxGroup := &entity.TaxGroup{
+		Source:     entity.EAR,
+		ExternalId: groupId,
+		Taxes:      taxInstances,
+	}
+
+	taxGroupService := GetTaxGroupService(lsvc.tx, taxGroup, lsvc.taxStore)
+
+	return taxGroupService.CreateTaxGroup()
+}
+
+func (lsvc *LendingService) lendingIrrfRate(lendingContract *model.LendingContract) float64 {
+	if lendingContract.Value == 0 {
+		return 0
+	}
+
+	return lendingContract.Irrf / lendingContract.Value
+}
+
+// addBorrowerCost the fee and costs paid by borrowers reduce the share
+// results of the contract end month.
+func (lsvc *LendingService) addBorrowerCost(lendingContract *model.LendingContract) error {
+	tradeBatchService := GetTradeBatchService(
+		lsvc.tx,
+		lendingContract.User,
+		nil,
+		lsvc.taxStore,
+		lsvc.taxRateStore,
+		lsvc.tickerStore,
+		lsvc.fiiTradeBatchStore,
+		lsvc.optionTradeBatchStore,
+	)
+
+	tradeBatch, err := tradeBatchService.FindTradeBatch(lendingContract.EndDate)
+
+	if err != nil {
+		return err
+	}
+
+	tradeBatchService = GetTradeBatchService(
+		lsvc.tx,
+		lendingContract.User,
+		tradeBatch,
+		lsvc.taxStore,
+		lsvc.taxRateStore,
+		lsvc.tickerStore,
+		lsvc.fiiTradeBatchStore,
+		lsvc.optionTradeBatchStore,
+	)
+
+	tradeBatchService.ProcessCost(lendingContract.Value + lendingContract.Fees)
+
+	_, err = tradeBatchService.SaveTradeBatch()
+
+	return err
+}
+
+func (lsvc *LendingService) processContract(
+	user *entity.User,
+	contractInput *input.LendingContract,
+) (*model.LendingContract, error) {
+	if err := lsvc.validateContract(contractInput); err != nil {
+		return nil, err
+	}
+
+	startDate, err := utils.GetDateObject(contractInput.StartDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	endDate, err := utils.GetDateObject(contractInput.EndDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	companyService := GetCompanyService(
+		lsvc.tx,
+		&entity.Company{
+			Code: contractInput.Company.Code,
+			Name: contractInput.Company.Name,
+		},
+		lsvc.companyStore,
+		lsvc.tickerStore,
+	)
+
+	companyRec, err := companyService.UpsertCompany()
+
+	if err != nil {
+		return nil, err
+	}
+
+	lendingContract := &model.LendingContract{
+		User:      user,
+		Company:   companyRec,
+		Contract:  contractInput.Contract,
+		Side:      contractInput.Side,
+		AgentId:   contractInput.AgentId,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Qty:       contractInput.Qty,
+		Rate:      contractInput.Rate,
+		Value:     contractInput.Value,
+		Irrf:      contractInput.Irrf,
+		Fees:      contractInput.Fees,
+	}
+
+	lendingContractDAO := db.GetLendingContractDAO(lsvc.tx, lendingContract)
+	isNew, err := lendingContractDAO.IsNewLendingContract()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !isNew {
+		err = fmt.Errorf(
+			"lendingService.processContract: error: contract %s already exists on DB",
+			lendingContract.Contract,
+		)
+		return nil, err
+	}
+
+	if lendingContract.TaxGroup, err = lsvc.getTaxGroup(lendingContract); err != nil {
+		return nil, err
+	}
+
+	if lendingContract.Side == constants.LendingSides.BORROWER {
+		if err := lsvc.addBorrowerCost(lendingContract); err != nil {
+			return nil, err
+		}
+	}
+
+	return lendingContractDAO.CreateLendingContract()
+}
+
+func (lsvc *LendingService) ProcessLendingContracts() ([]*model.LendingContract, error) {
+	lendingInput := lsvc.lendingInput
+
+	userService := GetUserService(lsvc.tx, &entity.User{
+		ExternalUUID: lendingInput.Client.Id,
+		UserName:     lendingInput.Client.Name,
+	})
+
+	userRec, err := userService.UpsertUser()
+
+	if err != nil {
+		return nil, err
+	}
+
+	lendingContracts := make([]*model.LendingContract, 0, len(lendingInput.Contracts))
+
+	for _, contractInput := range lendingInput.Contracts {
+		lendingContract, err := lsvc.processContract(userRec, &contractInput)
+
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf(
+			"lendingService.ProcessLendingContracts: %s %s %s, value = %.2f",
+			lendingContract.Contract,
+			lendingContract.Side,
+			lendingContract.Company.Code,
+			lendingContract.Value,
+		)
+
+		lendingContracts = append(lendingContracts, lendingContract)
+	}
+
+	return lendingContracts, nil
+}
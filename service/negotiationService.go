@@ -0,0 +1,285 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/calendar"
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// institutionAgentId matches institutions exported with their agent code,
+// "3 - XP INVESTIMENTOS CCTVM S/A".
+var institutionAgentId = regexp.MustCompile(`^([0-9]+)\s*-\s*`)
+
+// NegotiationService turns B3's negotiation export into one invoice per
+// broker and market date, the fees are estimated from the rate tables and the
+// broker registry since the export carries none.
+type NegotiationService struct {
+	negotiations *input.Negotiations
+	b3Calendar   *calendar.B3Calendar
+	taxRateStore *store.TaxRateStore
+	brokerStore  *store.BrokerStore
+}
+
+func GetNegotiationService(
+	negotiations *input.Negotiations,
+	b3Calendar *calendar.B3Calendar,
+	taxRateStore *store.TaxRateStore,
+	brokerStore *store.BrokerStore,
+) *NegotiationService {
+	return &NegotiationService{
+		negotiations: negotiations,
+		b3Calendar:   b3Calendar,
+		taxRateStore: taxRateStore,
+		brokerStore:  brokerStore,
+	}
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// getInstitutionAgentId B3 exports name the institution, its agent code comes
+// from the name prefix or from the broker registry names.
+func getInstitutionAgentId(brokerStore *store.BrokerStore, institution string) (string, bool) {
+	if match := institutionAgentId.FindStringSubmatch(institution); match != nil {
+		return match[1], true
+	}
+
+	broker := brokerStore.FindByName(institution)
+
+	if broker == nil {
+		return "", false
+	}
+
+	return broker.AgentId, true
+}
+
+func (nsvc *NegotiationService) getAgentId(institution string) (string, error) {
+	agentId, ok := getInstitutionAgentId(nsvc.brokerStore, institution)
+
+	if !ok {
+		details := fmt.Sprintf("[institution %s not found on the broker registry (B3_BROKER_FILE)]", institution)
+		return "", utils.GetError("negotiationService.getAgentId", "ERR_SYS_001", details)
+	}
+
+	return agentId, nil
+}
+
+// getItemMarket maps the export market to the note market of the item, spot
+// and fractional trades take the invoice market; ok is false for futures,
+// which come on BM&F notes.
+func getItemMarket(market string) (string, bool) {
+	markets := constants.Markets
+	market = utils.NormalizeSecurityName(market)
+
+	switch {
+	case strings.Contains(market, "FUTURO"):
+		return "", false
+	case strings.Contains(market, "EXERC") && strings.Contains(market, "COMPRA"):
+		return markets.CALL_EXERCISE, true
+	case strings.Contains(market, "EXERC") && strings.Contains(market, "VENDA"):
+		return markets.PUT_EXERCISE, true
+	case strings.Contains(market, "OPC") && strings.Contains(market, "COMPRA"):
+		return markets.CALL, true
+	case strings.Contains(market, "OPC") && strings.Contains(market, "VENDA"):
+		return markets.PUT, true
+	case strings.Contains(market, "TERMO"):
+		return markets.TERMO, true
+	}
+
+	return "", true
+}
+
+// getItemCode exercises are exported with the series ticker followed by E
+// (PETRA240E).
+func getItemCode(code string, market string) string {
+	markets := constants.Markets
+
+	if market != markets.CALL_EXERCISE && market != markets.PUT_EXERCISE {
+		return code
+	}
+
+	if series := strings.TrimSuffix(code, "E"); utils.IsOption(series) {
+		return series
+	}
+
+	return code
+}
+
+// getBrokerage estimates the brokerage of the invoice items with the broker
+// model.
+func getBrokerage(brokerageService *BrokerageService, invoice *input.Invoice) float64 {
+	brokerage := 0.0
+
+	for _, item := range invoice.Items {
+		brokerage = brokerage + brokerageService.GetTaxValue(&entity.InvoiceItem{
+			Company: &entity.Company{Code: item.Company.Code},
+			Qty:     item.Qty,
+			Price:   item.Price,
+			Debit:   item.Debit,
+		}, constants.TaxTypes.BRKFEE)
+	}
+
+	return roundCents(brokerage)
+}
+
+// setTaxes fills the invoice fees from the rates valid on the market date.
+func (nsvc *NegotiationService) setTaxes(invoice *input.Invoice, marketDate utils.B3Date) {
+	taxTypes := constants.TaxTypes
+	rateCodes := constants.RateCodes
+	source := constants.TaxSources.INVOICE
+
+	getTax := func(code string, rate float64, value float64) input.Tax {
+		return input.Tax{
+			Code:   code,
+			Source: source,
+			Value:  roundCents(value),
+			Rate:   rate,
+		}
+	}
+
+	setRate := nsvc.taxRateStore.Get(rateCodes.SETFEE, marketDate)
+	emlRate := nsvc.taxRateStore.Get(rateCodes.EMLFEE, marketDate)
+
+	invoice.Taxes = []input.Tax{
+		getTax(taxTypes.SETFEE, setRate, invoice.RawValue*setRate),
+		getTax(taxTypes.EMLFEE, emlRate, invoice.RawValue*emlRate),
+	}
+
+	// charged items are tracked apart from the invoice run
+	brokerageService := GetBrokerageService(
+		nsvc.brokerStore.Get(invoice.AgentId),
+		marketDate,
+		store.GetBrokerTaxStore(),
+		nsvc.taxRateStore,
+	)
+
+	if brokerage := getBrokerage(brokerageService, invoice); brokerage > 0 {
+		issRate := brokerageService.GetIssRate()
+
+		invoice.Taxes = append(
+			invoice.Taxes,
+			getTax(taxTypes.BRKFEE, 0, brokerage),
+			getTax(taxTypes.ISSSPFEE, issRate, (brokerage/(1-issRate))-brokerage),
+		)
+	}
+
+	if invoice.TotalSold > 0 {
+		irrfRate := nsvc.taxRateStore.Get(rateCodes.IRRFFEE, marketDate)
+		invoice.Taxes = append(invoice.Taxes, getTax(taxTypes.IRRFFEE, irrfRate, invoice.TotalSold*irrfRate))
+	}
+
+	fees := 0.0
+
+	for _, tax := range invoice.Taxes {
+		fees = fees + tax.Value
+	}
+
+	invoice.NetValue = roundCents(invoice.TotalSold - invoice.TotalAcquired - fees)
+}
+
+// GetInvoices groups the trades by market date and broker, invoices are
+// returned in market date order and named negociacao_yyyy_mm_dd_<agentId> so
+// importing the same trades twice is caught as a known invoice.
+func (nsvc *NegotiationService) GetInvoices() ([]*input.Invoice, error) {
+	negotiations := nsvc.negotiations
+	invoicesByKey := make(map[string]*input.Invoice)
+	invoices := make([]*input.Invoice, 0)
+
+	for _, negotiation := range negotiations.Items {
+		market, ok := getItemMarket(negotiation.Market)
+
+		if !ok {
+			log.Printf(
+				"negotiationService.GetInvoices: WARNING: skipping %s on %s, futures come on BM&F notes",
+				negotiation.Code,
+				negotiation.Date,
+			)
+			continue
+		}
+
+		if negotiation.Qty <= 0 {
+			details := fmt.Sprintf("[%s, %s, invalid quantity %d]", negotiation.Date, negotiation.Code, negotiation.Qty)
+			return nil, utils.GetError("negotiationService.GetInvoices", "ERR_SYS_001", details)
+		}
+
+		agentId, err := nsvc.getAgentId(negotiation.Institution)
+
+		if err != nil {
+			return nil, err
+		}
+
+		key := negotiation.Date + "_" + agentId
+		invoice, ok := invoicesByKey[key]
+
+		if !ok {
+			marketDate, err := utils.ParseB3Date(negotiation.Date)
+
+			if err != nil {
+				return nil, err
+			}
+
+			invoice = &input.Invoice{
+				Market:     constants.Markets.SPOT,
+				FileName:   fmt.Sprintf("negociacao_%s_%s", marketDate.Format("2006_01_02"), agentId),
+				MarketDate: marketDate.String(),
+				AgentId:    agentId,
+				Client:     negotiations.Client,
+				Items:      make([]input.Item, 0),
+			}
+
+			invoicesByKey[key] = invoice
+			invoices = append(invoices, invoice)
+		}
+
+		invoice.Items = append(invoice.Items, input.Item{
+			Company: input.Company{Code: getItemCode(negotiation.Code, market)},
+			Qty:     negotiation.Qty,
+			Price:   negotiation.Price,
+			Debit:   negotiation.Debit,
+			Order:   int64(len(invoice.Items) + 1),
+			Market:  market,
+			DueDate: negotiation.DueDate,
+		})
+
+		invoice.RawValue = roundCents(invoice.RawValue + negotiation.Value)
+
+		if negotiation.Debit {
+			invoice.TotalAcquired = roundCents(invoice.TotalAcquired + negotiation.Value)
+		} else {
+			invoice.TotalSold = roundCents(invoice.TotalSold + negotiation.Value)
+		}
+	}
+
+	for _, invoice := range invoices {
+		marketDate, _ := utils.ParseB3Date(invoice.MarketDate)
+		invoice.BillingDate = getInvoiceSettlementDate(nsvc.b3Calendar, invoice, marketDate).String()
+		nsvc.setTaxes(invoice, marketDate)
+	}
+
+	sort.SliceStable(invoices, func(i, j int) bool {
+		if invoices[i].MarketDate != invoices[j].MarketDate {
+			return invoices[i].MarketDate < invoices[j].MarketDate
+		}
+
+		return invoices[i].AgentId < invoices[j].AgentId
+	})
+
+	log.Printf(
+		"negotiationService.GetInvoices: %d trades on %d invoices",
+		len(negotiations.Items),
+		len(invoices),
+	)
+
+	return invoices, nil
+}
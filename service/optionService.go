@@ -0,0 +1,373 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/calendar"
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// OptionService keeps the option batches of a user, premiums are taken at
+// their raw value, the note fees stay on the invoice tax group.
+type OptionService struct {
+	tx           *sql.Tx
+	user         *entity.User
+	companyStore *store.CompanyStore
+	tickerStore  *store.TickerStore
+	b3Calendar   *calendar.B3Calendar
+}
+
+func GetOptionService(
+	tx *sql.Tx,
+	user *entity.User,
+	companyStore *store.CompanyStore,
+	tickerStore *store.TickerStore,
+	b3Calendar *calendar.B3Calendar,
+) *OptionService {
+	return &OptionService{
+		tx:           tx,
+		user:         user,
+		companyStore: companyStore,
+		tickerStore:  tickerStore,
+		b3Calendar:   b3Calendar,
+	}
+}
+
+// ParseOptionSeries splits an option ticker, series letters A to L are calls
+// and M to X puts expiring from January to December.
+func ParseOptionSeries(code string) (*model.OptionSeries, bool) {
+	root, series, strikeCode, week, ok := utils.ParseOptionCode(code)
+
+	if !ok {
+		return nil, false
+	}
+
+	optionSeries := &model.OptionSeries{
+		Code:       code,
+		Root:       root,
+		StrikeCode: strikeCode,
+		Week:       week,
+	}
+
+	if series <= 'L' {
+		optionSeries.Type = constants.OptionTypes.CALL
+		optionSeries.ExpiryMonth = time.Month(series-'A') + time.January
+	} else {
+		optionSeries.Type = constants.OptionTypes.PUT
+		optionSeries.ExpiryMonth = time.Month(series-'M') + time.January
+	}
+
+	return optionSeries, true
+}
+
+// getExpiryDate returns the first expiry of the series month not before the
+// market date, expiries falling on a closed day move to the trading day
+// before.
+func (osvc *OptionService) getExpiryDate(optionSeries *model.OptionSeries, marketDate time.Time) time.Time {
+	date := utils.B3DateOf(marketDate)
+
+	getExpiry := func(year int) utils.B3Date {
+		expiryDate := utils.GetOptionExpiry(year, optionSeries.ExpiryMonth, optionSeries.Week)
+
+		if !osvc.b3Calendar.IsTradingDay(expiryDate) {
+			expiryDate = osvc.b3Calendar.PreviousTradingDay(expiryDate)
+		}
+
+		return expiryDate
+	}
+
+	expiryDate := getExpiry(date.Year())
+
+	if expiryDate.Before(date) {
+		expiryDate = getExpiry(date.Year() + 1)
+	}
+
+	return expiryDate.Time()
+}
+
+// getUnderlyingCode the share class printed on the note picks the underlying
+// ticker, without it the registry must hold a single ticker for the root.
+func (osvc *OptionService) getUnderlyingCode(optionSeries *model.OptionSeries, name string) (string, error) {
+	_, class := utils.ParseSecurityName(name)
+	suffixes := utils.GetClassSuffixes(class)
+
+	for _, suffix := range suffixes {
+		if osvc.tickerStore.Has(optionSeries.Root + suffix) {
+			return optionSeries.Root + suffix, nil
+		}
+	}
+
+	if len(suffixes) > 0 {
+		return optionSeries.Root + suffixes[0], nil
+	}
+
+	candidates := make([]string, 0)
+
+	for _, ticker := range osvc.tickerStore.GetTickers() {
+		if strings.HasPrefix(ticker.Code, optionSeries.Root) && !utils.IsOption(ticker.Code) {
+			candidates = append(candidates, ticker.Code)
+		}
+	}
+
+	if len(candidates) != 1 {
+		details := fmt.Sprintf("[%s, underlying not found, %s]", optionSeries.Code, name)
+		return "", utils.GetError("optionService.getUnderlyingCode", "ERR_SYS_001", details)
+	}
+
+	return candidates[0], nil
+}
+
+// GetUnderlying returns the company the option series is written on.
+func (osvc *OptionService) GetUnderlying(series *entity.Company, name string) (*entity.Company, error) {
+	optionSeries, ok := ParseOptionSeries(series.Code)
+
+	if !ok {
+		details := fmt.Sprintf("[%s, not an option series]", series.Code)
+		return nil, utils.GetError("optionService.GetUnderlying", "ERR_SYS_001", details)
+	}
+
+	code, err := osvc.getUnderlyingCode(optionSeries, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	name = code
+
+	if osvc.tickerStore.Has(code) && osvc.tickerStore.Get(code).Issuer != "" {
+		name = osvc.tickerStore.Get(code).Issuer
+	}
+
+	companyService := GetCompanyService(
+		osvc.tx,
+		&entity.Company{
+			Code: code,
+			Name: name,
+		},
+		osvc.companyStore,
+		osvc.tickerStore,
+	)
+
+	return companyService.UpsertCompany()
+}
+
+func (osvc *OptionService) getOptionBatch(series *entity.Company) (*model.OptionBatch, error) {
+	optionBatchDAO := db.GetOptionBatchDAO(osvc.tx, &model.OptionBatch{
+		User:    osvc.user,
+		Company: series,
+	})
+
+	return optionBatchDAO.GetOptionBatch()
+}
+
+func (osvc *OptionService) saveOptionBatch(optionBatch *model.OptionBatch) (*model.OptionBatch, error) {
+	// drop the rounding left on closed positions
+	if optionBatch.Qty == 0 {
+		optionBatch.Premium = 0
+	}
+
+	optionBatchDAO := db.GetOptionBatchDAO(osvc.tx, optionBatch)
+
+	if optionBatch.Id == 0 {
+		return optionBatchDAO.CreateOptionBatch()
+	}
+
+	return optionBatch, optionBatchDAO.UpdateOptionBatch()
+}
+
+func (osvc *OptionService) createOptionBatch(
+	item *entity.InvoiceItem,
+	itemInput *input.Item,
+) (*model.OptionBatch, error) {
+	series := item.Company
+	optionSeries, _ := ParseOptionSeries(series.Code)
+
+	underlying, err := osvc.GetUnderlying(series, itemInput.Company.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	strike := itemInput.Strike
+
+	if strike <= 0 {
+		strike = utils.ParseStrike(itemInput.Company.Name)
+	}
+
+	if strike <= 0 {
+		log.Printf("optionService.createOptionBatch: WARNING: no strike for %s", series.Code)
+	}
+
+	return &model.OptionBatch{
+		User:       osvc.user,
+		Company:    series,
+		Underlying: underlying,
+		Type:       optionSeries.Type,
+		Strike:     strike,
+		ExpiryDate: osvc.getExpiryDate(optionSeries, item.MarketDate),
+		StartDate:  item.MarketDate,
+	}, nil
+}
+
+// ProcessPremium buys or sells option premium, trades against an open
+// position of the other side close it first and realize the difference of
+// premiums; closed tells if a result was realized.
+func (osvc *OptionService) ProcessPremium(
+	item *entity.InvoiceItem,
+	itemInput *input.Item,
+) (result float64, closed bool, err error) {
+	optionBatch, err := osvc.getOptionBatch(item.Company)
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	if optionBatch == nil {
+		optionBatch, err = osvc.createOptionBatch(item, itemInput)
+
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	qty := item.Qty
+	side := int64(1)
+
+	if !item.Debit {
+		side = -1
+	}
+
+	// an open position of the other side is closed first
+	if optionBatch.Qty*side < 0 {
+		openQty := optionBatch.Qty * -side
+		closeQty := qty
+
+		if closeQty > openQty {
+			closeQty = openQty
+		}
+
+		avgPremium := optionBatch.Premium / float64(openQty)
+
+		if item.Debit {
+			result = float64(closeQty) * (avgPremium - item.Price)
+		} else {
+			result = float64(closeQty) * (item.Price - avgPremium)
+		}
+
+		optionBatch.Qty = optionBatch.Qty + closeQty*side
+		optionBatch.Premium = optionBatch.Premium - avgPremium*float64(closeQty)
+		qty = qty - closeQty
+		closed = true
+	}
+
+	if qty > 0 {
+		optionBatch.Qty = optionBatch.Qty + qty*side
+		optionBatch.Premium = optionBatch.Premium + item.Price*float64(qty)
+	}
+
+	if _, err = osvc.saveOptionBatch(optionBatch); err != nil {
+		return 0, false, err
+	}
+
+	log.Printf(
+		"optionService.ProcessPremium: %s qty = %d, result = %.2f",
+		item.Company.Code,
+		optionBatch.Qty,
+		result,
+	)
+
+	return result, closed, nil
+}
+
+// ExerciseOption closes qty options of the series exercised at the strike,
+// it returns the premium of the exercised options and whether they were
+// written (premium received) or held (premium paid).
+func (osvc *OptionService) ExerciseOption(
+	series *entity.Company,
+	qty int64,
+) (premium float64, written bool, err error) {
+	optionBatch, err := osvc.getOptionBatch(series)
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	if optionBatch == nil {
+		log.Printf("optionService.ExerciseOption: WARNING: no open position on %s", series.Code)
+		return 0, false, nil
+	}
+
+	openQty := optionBatch.Qty
+	written = openQty < 0
+
+	if written {
+		openQty = -openQty
+	}
+
+	exercisedQty := qty
+
+	if exercisedQty > openQty {
+		exercisedQty = openQty
+	}
+
+	premium = optionBatch.Premium * float64(exercisedQty) / float64(openQty)
+
+	if written {
+		optionBatch.Qty = optionBatch.Qty + exercisedQty
+	} else {
+		optionBatch.Qty = optionBatch.Qty - exercisedQty
+	}
+
+	optionBatch.Premium = optionBatch.Premium - premium
+
+	if _, err = osvc.saveOptionBatch(optionBatch); err != nil {
+		return 0, false, err
+	}
+
+	return premium, written, nil
+}
+
+func (osvc *OptionService) GetExpiredOptionBatches(until time.Time) ([]*model.OptionBatch, error) {
+	optionBatchDAO := db.GetOptionBatchDAO(osvc.tx, &model.OptionBatch{
+		User: osvc.user,
+	})
+
+	return optionBatchDAO.GetExpiredOptionBatches(until)
+}
+
+// ExpireOption closes an option batch expired worthless, held options lose
+// the premium paid and written ones keep the premium received.
+func (osvc *OptionService) ExpireOption(optionBatch *model.OptionBatch) (float64, error) {
+	result := -optionBatch.Premium
+
+	if optionBatch.Qty < 0 {
+		result = optionBatch.Premium
+	}
+
+	log.Printf(
+		"optionService.ExpireOption: %s expired on %s [qty = %d, result = %.2f]",
+		optionBatch.Company.Code,
+		utils.B3DateOf(optionBatch.ExpiryDate).String(),
+		optionBatch.Qty,
+		result,
+	)
+
+	optionBatch.Qty = 0
+
+	if _, err := osvc.saveOptionBatch(optionBatch); err != nil {
+		return 0, err
+	}
+
+	return math.Round(result*100) / 100, nil
+}
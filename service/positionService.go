@@ -0,0 +1,92 @@
+package service
+
+import (
+	"database/sql"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type PositionService struct {
+	tx   *sql.Tx
+	user *entity.User
+}
+
+func GetPositionService(tx *sql.Tx, user *entity.User) *PositionService {
+	return &PositionService{
+		tx:   tx,
+		user: user,
+	}
+}
+
+// GetPositions returns current positions, broker batches with no open company
+// batch (e.g. sales without custody) are listed under an empty consolidation.
+// Shares out on lending contracts are flagged with LentQty.
+func (psvc *PositionService) GetPositions() ([]*model.Position, error) {
+	companyBatchDAO := db.GetCompanyBatchDAO(psvc.tx, &entity.CompanyBatch{
+		User: psvc.user,
+	})
+
+	companyBatches, err := companyBatchDAO.GetCompanyBatchesByUser()
+
+	if err != nil {
+		return nil, err
+	}
+
+	brokerBatchDAO := db.GetBrokerBatchDAO(psvc.tx, &model.BrokerBatch{
+		User: psvc.user,
+	})
+
+	brokerBatches, err := brokerBatchDAO.GetBrokerBatchesByUser()
+
+	if err != nil {
+		return nil, err
+	}
+
+	lendingContractDAO := db.GetLendingContractDAO(psvc.tx, &model.LendingContract{
+		User: psvc.user,
+	})
+
+	lentQtyByCode, err := lendingContractDAO.GetLentQtyByCode(utils.B3Today().Time())
+
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make([]*model.Position, 0, len(companyBatches))
+	positionByCode := make(map[string]*model.Position)
+
+	for _, companyBatch := range companyBatches {
+		position := &model.Position{
+			CompanyBatch:  companyBatch,
+			BrokerBatches: make([]*model.BrokerBatch, 0),
+			LentQty:       lentQtyByCode[companyBatch.Company.Code],
+		}
+
+		positions = append(positions, position)
+		positionByCode[companyBatch.Company.Code] = position
+	}
+
+	for _, brokerBatch := range brokerBatches {
+		position, ok := positionByCode[brokerBatch.Company.Code]
+
+		if !ok {
+			position = &model.Position{
+				CompanyBatch: &entity.CompanyBatch{
+					User:    psvc.user,
+					Company: brokerBatch.Company,
+				},
+				BrokerBatches: make([]*model.BrokerBatch, 0),
+			}
+
+			positions = append(positions, position)
+			positionByCode[brokerBatch.Company.Code] = position
+		}
+
+		position.BrokerBatches = append(position.BrokerBatches, brokerBatch)
+	}
+
+	return positions, nil
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/input"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// ReconcileService compares B3's custody statement of a date with the
+// positions settled up to that date, custody by broker is only compared when
+// nothing was traded after the statement date since broker batches keep no
+// history.
+type ReconcileService struct {
+	tx          *sql.Tx
+	user        *entity.User
+	statement   *input.CustodyStatement
+	date        utils.B3Date
+	brokerStore *store.BrokerStore
+	tickerStore *store.TickerStore
+}
+
+func GetReconcileService(
+	tx *sql.Tx,
+	user *entity.User,
+	statement *input.CustodyStatement,
+	date utils.B3Date,
+	brokerStore *store.BrokerStore,
+	tickerStore *store.TickerStore,
+) *ReconcileService {
+	return &ReconcileService{
+		tx:          tx,
+		user:        user,
+		statement:   statement,
+		date:        date,
+		brokerStore: brokerStore,
+		tickerStore: tickerStore,
+	}
+}
+
+// getStoredQty replays the position events up to the date, sells of
+// positions not held are ignored as on the IRPF worksheet.
+func (rsvc *ReconcileService) getStoredQty(events []model.PositionEvent) map[string]int64 {
+	storedQty := make(map[string]int64)
+
+	for _, event := range events {
+		qty := storedQty[event.Company.Code]
+
+		if event.Debit {
+			storedQty[event.Company.Code] = qty + event.Qty
+		} else if qty > 0 {
+			storedQty[event.Company.Code] = qty - event.Qty
+		}
+	}
+
+	return storedQty
+}
+
+// getStatementQty sums the statement by ticker and by ticker and broker, old
+// tickers are read as their successors; institutions missing from the broker
+// registry are kept by name.
+func (rsvc *ReconcileService) getStatementQty() (map[string]int64, map[string]map[string]int64) {
+	statementQty := make(map[string]int64)
+	statementBrokerQty := make(map[string]map[string]int64)
+
+	for _, item := range rsvc.statement.Items {
+		code := rsvc.tickerStore.GetSuccessor(item.Code)
+		agentId, ok := getInstitutionAgentId(rsvc.brokerStore, item.Institution)
+
+		if !ok {
+			log.Printf(
+				"reconcileService.getStatementQty: WARNING: institution %s not found on the broker registry",
+				item.Institution,
+			)
+			agentId = item.Institution
+		}
+
+		if _, ok := statementBrokerQty[code]; !ok {
+			statementBrokerQty[code] = make(map[string]int64)
+		}
+
+		statementQty[code] = statementQty[code] + item.Qty
+		statementBrokerQty[code][agentId] = statementBrokerQty[code][agentId] + item.Qty
+	}
+
+	return statementQty, statementBrokerQty
+}
+
+// getSameRoot finds a ticker of the same issuer (PETR3, PETR4) held only on
+// the other side, the likely result of a wrong ticker.
+func getSameRoot(code string, qtyByCode map[string]int64, otherQtyByCode map[string]int64) string {
+	for otherCode, qty := range qtyByCode {
+		if qty == 0 || otherCode == code || len(otherCode) < 4 || len(code) < 4 {
+			continue
+		}
+
+		if otherCode[:4] == code[:4] && otherQtyByCode[otherCode] == 0 {
+			return otherCode
+		}
+	}
+
+	return ""
+}
+
+func getDiscrepancy(
+	code string,
+	statementQty map[string]int64,
+	storedQty map[string]int64,
+) *model.Discrepancy {
+	reconcileStatuses := constants.ReconcileStatuses
+
+	discrepancy := &model.Discrepancy{
+		Code:         code,
+		StatementQty: statementQty[code],
+		StoredQty:    storedQty[code],
+	}
+
+	switch {
+	case discrepancy.StoredQty == 0:
+		discrepancy.Status = reconcileStatuses.MISSING_ON_DB
+		discrepancy.Hint = "missing invoice or custody transfer"
+
+		if otherCode := getSameRoot(code, storedQty, statementQty); otherCode != "" {
+			discrepancy.Hint = fmt.Sprintf("wrong ticker, stored as %s", otherCode)
+		}
+	case discrepancy.StatementQty == 0:
+		discrepancy.Status = reconcileStatuses.MISSING_ON_B3
+		discrepancy.Hint = "missing sale, corporate event or ticker change"
+
+		if otherCode := getSameRoot(code, statementQty, storedQty); otherCode != "" {
+			discrepancy.Hint = fmt.Sprintf("wrong ticker, held on B3 as %s", otherCode)
+		}
+	default:
+		discrepancy.Status = reconcileStatuses.QTY_MISMATCH
+		discrepancy.Hint = "missing invoice or corporate event"
+
+		big, small := discrepancy.StatementQty, discrepancy.StoredQty
+
+		if big < small {
+			big, small = small, big
+		}
+
+		if small > 0 && big%small == 0 {
+			discrepancy.Hint = "split, reverse split or bonus shares not applied"
+		}
+	}
+
+	return discrepancy
+}
+
+// getSortedKeys returns the tickers (or agents) found on any of the maps.
+func getSortedKeys(qtyMaps ...map[string]int64) []string {
+	keys := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, qtyMap := range qtyMaps {
+		for key := range qtyMap {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// getBrokerDiscrepancies compares custody by broker of the tickers whose
+// consolidated quantity matched, lent shares are not split by broker.
+func (rsvc *ReconcileService) getBrokerDiscrepancies(
+	codes []string,
+	statementBrokerQty map[string]map[string]int64,
+	lentQtyByCode map[string]int64,
+) ([]*model.Discrepancy, error) {
+	brokerBatchDAO := db.GetBrokerBatchDAO(rsvc.tx, &model.BrokerBatch{
+		User: rsvc.user,
+	})
+
+	brokerBatches, err := brokerBatchDAO.GetBrokerBatchesByUser()
+
+	if err != nil {
+		return nil, err
+	}
+
+	storedBrokerQty := make(map[string]map[string]int64)
+
+	for _, brokerBatch := range brokerBatches {
+		code := brokerBatch.Company.Code
+
+		// custody held before brokers were tracked has no statement broker
+		if brokerBatch.AgentId == "" {
+			continue
+		}
+
+		if _, ok := storedBrokerQty[code]; !ok {
+			storedBrokerQty[code] = make(map[string]int64)
+		}
+
+		storedBrokerQty[code][brokerBatch.AgentId] = brokerBatch.Qty
+	}
+
+	discrepancies := make([]*model.Discrepancy, 0)
+
+	for _, code := range codes {
+		if lentQtyByCode[code] > 0 {
+			continue
+		}
+
+		for _, agentId := range getSortedKeys(statementBrokerQty[code], storedBrokerQty[code]) {
+			statementQty := statementBrokerQty[code][agentId]
+			storedQty := storedBrokerQty[code][agentId]
+
+			if statementQty == storedQty {
+				continue
+			}
+
+			discrepancies = append(discrepancies, &model.Discrepancy{
+				Code:         code,
+				AgentId:      agentId,
+				StatementQty: statementQty,
+				StoredQty:    storedQty,
+				Status:       constants.ReconcileStatuses.QTY_MISMATCH,
+				Hint:         "missing custody transfer between brokers or wrong invoice agent",
+			})
+		}
+	}
+
+	return discrepancies, nil
+}
+
+func (rsvc *ReconcileService) Reconcile() (*model.Reconciliation, error) {
+	// B3 custody holds the trades settled until the date
+	positionDAO := db.GetPositionDAO(rsvc.tx, rsvc.user)
+	events, err := positionDAO.GetSettledPositionEvents(rsvc.date.Time())
+
+	if err != nil {
+		return nil, err
+	}
+
+	lendingContractDAO := db.GetLendingContractDAO(rsvc.tx, &model.LendingContract{
+		User: rsvc.user,
+	})
+
+	// lent shares leave the lender custody at B3
+	lentQtyByCode, err := lendingContractDAO.GetLentQtyByCode(rsvc.date.Time())
+
+	if err != nil {
+		return nil, err
+	}
+
+	storedQty := rsvc.getStoredQty(events)
+
+	for code, lentQty := range lentQtyByCode {
+		storedQty[code] = storedQty[code] - lentQty
+	}
+
+	statementQty, statementBrokerQty := rsvc.getStatementQty()
+
+	reconciliation := &model.Reconciliation{
+		User:          rsvc.user,
+		Date:          rsvc.date.Time(),
+		FileName:      rsvc.statement.FileName,
+		Discrepancies: make([]*model.Discrepancy, 0),
+	}
+
+	matchedCodes := make([]string, 0)
+
+	for _, code := range getSortedKeys(statementQty, storedQty) {
+		if statementQty[code] == storedQty[code] {
+			if statementQty[code] != 0 {
+				reconciliation.Matched++
+				matchedCodes = append(matchedCodes, code)
+			}
+			continue
+		}
+
+		reconciliation.Discrepancies = append(
+			reconciliation.Discrepancies,
+			getDiscrepancy(code, statementQty, storedQty),
+		)
+	}
+
+	// trades not settled on the date are already on the broker batches
+	latestEvents, err := positionDAO.GetPositionEvents(utils.B3Today().Time())
+
+	if err != nil {
+		return nil, err
+	}
+
+	reconciliation.ByBroker = len(latestEvents) == len(events)
+
+	if reconciliation.ByBroker {
+		brokerDiscrepancies, err := rsvc.getBrokerDiscrepancies(matchedCodes, statementBrokerQty, lentQtyByCode)
+
+		if err != nil {
+			return nil, err
+		}
+
+		reconciliation.Discrepancies = append(reconciliation.Discrepancies, brokerDiscrepancies...)
+	}
+
+	log.Printf(
+		"reconcileService.Reconcile: %d tickers matched, %d discrepancies",
+		reconciliation.Matched,
+		len(reconciliation.Discrepancies),
+	)
+
+	return reconciliation, nil
+}
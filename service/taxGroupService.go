@@ -26,6 +26,59 @@ func GetTaxGroupService(
 	}
 }
 
+func (tgsvc *TaxGroupService) getTax(tax *entity.Tax) (*entity.Tax, error) {
+	if tgsvc.taxStore.Has(tax) {
+		return tgsvc.taxStore.Get(tax), nil
+	}
+
+	taxDAO := db.GetTaxDAO(tgsvc.tx, tax)
+
+	taxRec, err := taxDAO.LoadTax()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if taxRec == nil {
+		taxRec, err = taxDAO.CreateTax()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tgsvc.taxStore.Put(taxRec), nil
+}
+
+func (tgsvc *TaxGroupService) createTaxInstance(
+	taxGroupId int64,
+	taxInstance *entity.TaxInstance,
+) (*entity.TaxInstance, error) {
+	taxRec, err := tgsvc.getTax(taxInstance.Tax)
+
+	if err != nil {
+		return nil, err
+	}
+
+	taxInstanceDAO := db.GetTaxInstanceDAO(tgsvc.tx, &entity.TaxInstance{
+		TaxGroupId: taxGroupId,
+		Tax:        taxRec,
+		MarketDate: taxInstance.MarketDate,
+		TaxValue:   taxInstance.TaxValue,
+		BaseValue:  taxInstance.BaseValue,
+		TaxRate:    taxInstance.TaxRate,
+	})
+
+	taxInstanceRec, err := taxInstanceDAO.CreateTaxInstance()
+
+	if err != nil {
+		return nil, err
+	}
+
+	taxInstanceRec.TaxGroupId = taxGroupId
+
+	return taxInstanceRec, nil
+}
+
 func (tgsvc *TaxGroupService) CreateTaxGroup() (*entity.TaxGroup, error) {
 	taxGroupDAO := db.GetTaxGroupDAO(
 		tgsvc.tx,
@@ -41,47 +94,13 @@ func (tgsvc *TaxGroupService) CreateTaxGroup() (*entity.TaxGroup, error) {
 	taxInstances := tgsvc.taxGroup.Taxes
 	taxInstanceRecs := make([]entity.TaxInstance, 0, len(taxInstances))
 
-	var taxRec *entity.Tax
-
 	for _, taxInstance := range taxInstances {
-		if tgsvc.taxStore.Has(taxInstance.Tax) {
-			taxRec = tgsvc.taxStore.Get(taxInstance.Tax)
-		} else {
-			taxDAO := db.GetTaxDAO(tgsvc.tx, taxInstance.Tax)
-
-			taxRec, err = taxDAO.LoadTax()
-
-			if err != nil {
-				return nil, err
-			}
-
-			if taxRec == nil {
-				taxRec, err = taxDAO.CreateTax()
-				if err != nil {
-					return nil, err
-				}
-			}
-
-			tgsvc.taxStore.Put(taxRec)
-		}
-
-		taxInstanceDAO := db.GetTaxInstanceDAO(tgsvc.tx, &entity.TaxInstance{
-			TaxGroupId: taxGroupRec.Id,
-			Tax:        taxRec,
-			MarketDate: taxInstance.MarketDate,
-			TaxValue:   taxInstance.TaxValue,
-			BaseValue:  taxInstance.BaseValue,
-			TaxRate:    taxInstance.TaxRate,
-		})
-
-		taxInstanceRec, err := taxInstanceDAO.CreateTaxInstance()
+		taxInstanceRec, err := tgsvc.createTaxInstance(taxGroupRec.Id, &taxInstance)
 
 		if err != nil {
 			return nil, err
 		}
 
-		taxInstanceRec.TaxGroupId = taxGroupRec.Id
-
 		taxInstanceRecs = append(taxInstanceRecs, *taxInstanceRec)
 	}
 
@@ -91,9 +110,22 @@ func (tgsvc *TaxGroupService) CreateTaxGroup() (*entity.TaxGroup, error) {
 }
 
 func (tgsvc *TaxGroupService) UpdateTaxGroup() error {
-	taxInstances := tgsvc.taxGroup.Taxes
+	taxGroup := tgsvc.taxGroup
+	taxInstances := taxGroup.Taxes
+
+	for idx, taxInstance := range taxInstances {
+		// instances added to a group created before their tax existed
+		if taxInstance.Id == 0 {
+			taxInstanceRec, err := tgsvc.createTaxInstance(taxGroup.Id, &taxInstance)
+
+			if err != nil {
+				return err
+			}
+
+			taxInstances[idx] = *taxInstanceRec
+			continue
+		}
 
-	for _, taxInstance := range taxInstances {
 		taxInstanceDAO := db.GetTaxInstanceDAO(tgsvc.tx, &taxInstance)
 		err := taxInstanceDAO.UpdateTaxInstance()
 
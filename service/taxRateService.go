@@ -0,0 +1,47 @@
+package service
+
+import (
+	"database/sql"
+	"os"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+)
+
+type TaxRateService struct {
+	tx           *sql.Tx
+	taxRateStore *store.TaxRateStore
+}
+
+func GetTaxRateService(tx *sql.Tx, taxRateStore *store.TaxRateStore) *TaxRateService {
+	return &TaxRateService{
+		tx:           tx,
+		taxRateStore: taxRateStore,
+	}
+}
+
+// LoadTaxRates fills the store from the tax_rate table, then from the file
+// set on B3_RATE_TABLE_FILE, so the file can override the DB intervals.
+func (trsvc *TaxRateService) LoadTaxRates() (*store.TaxRateStore, error) {
+	taxRateDAO := db.GetTaxRateDAO(trsvc.tx, &model.TaxRate{})
+	taxRates, err := taxRateDAO.LoadTaxRates()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, taxRate := range taxRates {
+		trsvc.taxRateStore.Put(&taxRate)
+	}
+
+	fileName, ok := os.LookupEnv("B3_RATE_TABLE_FILE")
+
+	if ok && fileName != "" {
+		if err := trsvc.taxRateStore.LoadFile(fileName); err != nil {
+			return nil, err
+		}
+	}
+
+	return trsvc.taxRateStore, nil
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+// TermoService keeps forward purchases (termo) out of the company batch until
+// they settle, no result is recognized when the contract is opened.
+type TermoService struct {
+	tx                *sql.Tx
+	user              *entity.User
+	companyBatchStore *store.CompanyBatchStore
+	brokerBatchStore  *store.BrokerBatchStore
+}
+
+func GetTermoService(
+	tx *sql.Tx,
+	user *entity.User,
+	companyBatchStore *store.CompanyBatchStore,
+	brokerBatchStore *store.BrokerBatchStore,
+) *TermoService {
+	return &TermoService{
+		tx:                tx,
+		user:              user,
+		companyBatchStore: companyBatchStore,
+		brokerBatchStore:  brokerBatchStore,
+	}
+}
+
+// OpenPosition records a termo purchase, taxGroup holds the note fees of the
+// item which become part of the contract cost.
+func (tsvc *TermoService) OpenPosition(
+	item *entity.InvoiceItem,
+	taxGroup *entity.TaxGroup,
+	agentId string,
+	dueDate string,
+) (*model.TermoPosition, error) {
+	if !item.Debit {
+		details := fmt.Sprintf("[%s, termo sales are not supported]", item.Company.Code)
+		return nil, utils.GetError("termoService.OpenPosition", "ERR_SYS_001", details)
+	}
+
+	dueDateObj, err := utils.GetDateObject(dueDate)
+
+	if err != nil {
+		details := fmt.Sprintf("[%s, invalid termo due date %q]", item.Company.Code, dueDate)
+		return nil, utils.GetError("termoService.OpenPosition", "ERR_SYS_001", details)
+	}
+
+	if !dueDateObj.After(item.MarketDate) {
+		details := fmt.Sprintf("[%s, termo due date %s not after the market date]", item.Company.Code, dueDate)
+		return nil, utils.GetError("termoService.OpenPosition", "ERR_SYS_001", details)
+	}
+
+	termoPositionDAO := db.GetTermoPositionDAO(tsvc.tx, &model.TermoPosition{
+		User:      tsvc.user,
+		Company:   item.Company,
+		Item:      item,
+		TaxGroup:  taxGroup,
+		AgentId:   agentId,
+		OpenDate:  item.MarketDate,
+		DueDate:   dueDateObj,
+		Qty:       item.Qty,
+		OpenQty:   item.Qty,
+		Price:     item.Price,
+		TotalCost: item.Price*float64(item.Qty) + utils.GetTotalTax(taxGroup),
+	})
+
+	return termoPositionDAO.CreateTermoPosition()
+}
+
+// settle delivers qty shares of the position into the company batch and the
+// broker custody at cost.
+func (tsvc *TermoService) settle(
+	termoPosition *model.TermoPosition,
+	qty int64,
+	cost float64,
+	settlementDate time.Time,
+	early bool,
+) (*model.TermoSettlement, error) {
+	companyBatchService := GetCompanyBatchService(
+		tsvc.tx,
+		tsvc.user,
+		termoPosition.Company,
+		tsvc.companyBatchStore,
+	)
+
+	companyBatchRec, err := companyBatchService.AddQty(qty, cost, settlementDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	brokerBatchService := GetBrokerBatchService(
+		tsvc.tx,
+		companyBatchRec,
+		termoPosition.AgentId,
+		tsvc.brokerBatchStore,
+	)
+
+	if _, err := brokerBatchService.AddQty(qty, cost, settlementDate); err != nil {
+		return nil, err
+	}
+
+	termoPosition.OpenQty = termoPosition.OpenQty - qty
+
+	termoPositionDAO := db.GetTermoPositionDAO(tsvc.tx, termoPosition)
+
+	if err := termoPositionDAO.UpdateTermoPosition(); err != nil {
+		return nil, err
+	}
+
+	termoSettlementDAO := db.GetTermoSettlementDAO(tsvc.tx, &model.TermoSettlement{
+		TermoPosition:  termoPosition,
+		CompanyBatch:   companyBatchRec,
+		SettlementDate: settlementDate,
+		Qty:            qty,
+		TotalCost:      cost,
+		Early:          early,
+	})
+
+	return termoSettlementDAO.CreateTermoSettlement()
+}
+
+// SettleDuePositions settles every open position due until the given date
+// at its full contract cost.
+func (tsvc *TermoService) SettleDuePositions(until time.Time) ([]*model.TermoSettlement, error) {
+	termoPositionDAO := db.GetTermoPositionDAO(tsvc.tx, &model.TermoPosition{
+		User: tsvc.user,
+	})
+
+	termoPositions, err := termoPositionDAO.GetDueTermoPositions(until)
+
+	if err != nil {
+		return nil, err
+	}
+
+	termoSettlements := make([]*model.TermoSettlement, 0, len(termoPositions))
+
+	for _, termoPosition := range termoPositions {
+		cost := termoPosition.TotalCost * float64(termoPosition.OpenQty) / float64(termoPosition.Qty)
+
+		termoSettlement, err := tsvc.settle(
+			termoPosition,
+			termoPosition.OpenQty,
+			cost,
+			termoPosition.DueDate,
+			false,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		termoSettlements = append(termoSettlements, termoSettlement)
+	}
+
+	return termoSettlements, nil
+}
+
+// SettleEarly settles qty shares of the company before the due date, oldest
+// contracts first; a positive price replaces the forward price of the
+// settled shares (discounted financing), the fees stay in the cost.
+func (tsvc *TermoService) SettleEarly(
+	company *entity.Company,
+	qty int64,
+	price float64,
+	settlementDate time.Time,
+) ([]*model.TermoSettlement, error) {
+	termoPositionDAO := db.GetTermoPositionDAO(tsvc.tx, &model.TermoPosition{
+		User:    tsvc.user,
+		Company: company,
+	})
+
+	termoPositions, err := termoPositionDAO.GetOpenTermoPositions()
+
+	if err != nil {
+		return nil, err
+	}
+
+	openQty := int64(0)
+
+	for _, termoPosition := range termoPositions {
+		openQty = openQty + termoPosition.OpenQty
+	}
+
+	if qty <= 0 || qty > openQty {
+		details := fmt.Sprintf("[%s, settling %d of %d termo shares]", company.Code, qty, openQty)
+		return nil, utils.GetError("termoService.SettleEarly", "ERR_SYS_001", details)
+	}
+
+	termoSettlements := make([]*model.TermoSettlement, 0)
+
+	for _, termoPosition := range termoPositions {
+		if qty == 0 {
+			break
+		}
+
+		settledQty := termoPosition.OpenQty
+
+		if settledQty > qty {
+			settledQty = qty
+		}
+
+		share := float64(settledQty) / float64(termoPosition.Qty)
+		cost := termoPosition.TotalCost * share
+
+		if price > 0 {
+			fees := termoPosition.TotalCost - termoPosition.Price*float64(termoPosition.Qty)
+			cost = price*float64(settledQty) + fees*share
+		}
+
+		termoSettlement, err := tsvc.settle(termoPosition, settledQty, cost, settlementDate, true)
+
+		if err != nil {
+			return nil, err
+		}
+
+		termoSettlements = append(termoSettlements, termoSettlement)
+		qty = qty - settledQty
+	}
+
+	log.Printf(
+		"termoService.SettleEarly: %s settled on %d contracts",
+		company.Code,
+		len(termoSettlements),
+	)
+
+	return termoSettlements, nil
+}
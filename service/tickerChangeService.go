@@ -0,0 +1,272 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/store"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+	"github.com/jarismar/b3c-service-entities/entity"
+)
+
+type TickerChangeService struct {
+	tx           *sql.Tx
+	tickerStore  *store.TickerStore
+	companyStore *store.CompanyStore
+}
+
+func GetTickerChangeService(
+	tx *sql.Tx,
+	tickerStore *store.TickerStore,
+	companyStore *store.CompanyStore,
+) *TickerChangeService {
+	return &TickerChangeService{
+		tx:           tx,
+		tickerStore:  tickerStore,
+		companyStore: companyStore,
+	}
+}
+
+// mergeBrokerBatches adds the broker batches of oldBatch to the ones of
+// newBatch and deletes them, agents missing on newBatch get the old broker
+// batch moved.
+func (tcsvc *TickerChangeService) mergeBrokerBatches(
+	oldBatch *entity.CompanyBatch,
+	newBatch *entity.CompanyBatch,
+) error {
+	brokerBatchDAO := db.GetBrokerBatchDAO(tcsvc.tx, &model.BrokerBatch{
+		CompanyBatch: oldBatch,
+	})
+
+	oldBrokerBatches, err := brokerBatchDAO.GetBrokerBatchesByCompanyBatch()
+
+	if err != nil {
+		return err
+	}
+
+	for _, oldBrokerBatch := range oldBrokerBatches {
+		newBrokerBatch, err := db.GetBrokerBatchDAO(tcsvc.tx, &model.BrokerBatch{
+			CompanyBatch: newBatch,
+			User:         newBatch.User,
+			Company:      newBatch.Company,
+			AgentId:      oldBrokerBatch.AgentId,
+		}).GetBrokerBatch()
+
+		if err != nil {
+			return err
+		}
+
+		if newBrokerBatch == nil {
+			_, err = db.GetBrokerBatchDAO(tcsvc.tx, oldBrokerBatch).MoveBrokerBatch(newBatch)
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		newBrokerBatch.Qty += oldBrokerBatch.Qty
+		newBrokerBatch.TotalPrice += oldBrokerBatch.TotalPrice
+		newBrokerBatch.AvgPrice = 0
+
+		if newBrokerBatch.Qty != 0 {
+			newBrokerBatch.AvgPrice = newBrokerBatch.TotalPrice / float64(newBrokerBatch.Qty)
+		}
+
+		if _, err = db.GetBrokerBatchDAO(tcsvc.tx, newBrokerBatch).UpdateBrokerBatch(); err != nil {
+			return err
+		}
+
+		// a broker row left by an older batch of the new company
+		if newBrokerBatch.CompanyBatch.Id != newBatch.Id {
+			_, err = db.GetBrokerBatchDAO(tcsvc.tx, newBrokerBatch).MoveBrokerBatch(newBatch)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		// the old row would clash with the new one once moved to the new company
+		if err = db.GetBrokerBatchDAO(tcsvc.tx, oldBrokerBatch).DeleteBrokerBatch(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mergeCompanyBatches moves the open position of oldBatch into newBatch, the
+// old batch is closed so its item batches and trades keep pointing to it.
+func (tcsvc *TickerChangeService) mergeCompanyBatches(
+	oldBatch *entity.CompanyBatch,
+	newBatch *entity.CompanyBatch,
+) error {
+	if err := tcsvc.mergeBrokerBatches(oldBatch, newBatch); err != nil {
+		return err
+	}
+
+	newBatch.Qty += oldBatch.Qty
+	newBatch.TotalPrice += oldBatch.TotalPrice
+	newBatch.AvgPrice = newBatch.TotalPrice / float64(newBatch.Qty)
+
+	if _, err := db.GetCompanyBatchDAO(tcsvc.tx, newBatch).UpdateCompanyBatch(); err != nil {
+		return err
+	}
+
+	oldBatch.Qty = 0
+	oldBatch.AvgPrice = 0
+	oldBatch.TotalPrice = 0
+
+	_, err := db.GetCompanyBatchDAO(tcsvc.tx, oldBatch).UpdateCompanyBatch()
+
+	return err
+}
+
+func (tcsvc *TickerChangeService) applyTickerChange(tickerChange *model.TickerChange) error {
+	oldCompany, err := db.GetCompanyDAO(tcsvc.tx, &entity.Company{
+		Code: tickerChange.OldCode,
+	}).GetCompany()
+
+	if err != nil {
+		return err
+	}
+
+	if oldCompany != nil {
+		newName := tickerChange.NewName
+
+		if newName == "" {
+			newName = oldCompany.Name
+		}
+
+		companyService := GetCompanyService(
+			tcsvc.tx,
+			&entity.Company{
+				Code: tickerChange.NewCode,
+				Name: newName,
+			},
+			tcsvc.companyStore,
+			tcsvc.tickerStore,
+		)
+
+		newCompany, err := companyService.UpsertCompany()
+
+		if err != nil {
+			return err
+		}
+
+		tickerChangeDAO := db.GetTickerChangeDAO(tcsvc.tx, tickerChange)
+		pairs, err := tickerChangeDAO.GetOverlappingBatches(oldCompany, newCompany)
+
+		if err != nil {
+			return err
+		}
+
+		for _, pair := range pairs {
+			if err := tcsvc.mergeCompanyBatches(pair[0], pair[1]); err != nil {
+				return err
+			}
+		}
+
+		if err := tickerChangeDAO.MoveCompanyReferences(oldCompany, newCompany); err != nil {
+			return err
+		}
+	}
+
+	if err := db.GetTickerChangeDAO(tcsvc.tx, tickerChange).SetApplied(); err != nil {
+		return err
+	}
+
+	tickerChange.Applied = true
+
+	log.Printf(
+		"tickerChangeService.applyTickerChange: applied [%d, %s -> %s, %s]",
+		tickerChange.Id,
+		tickerChange.OldCode,
+		tickerChange.NewCode,
+		tickerChange.EffectiveDate,
+	)
+
+	return nil
+}
+
+// ApplyTickerChanges applies the pending ticker changes effective until the
+// given date and registers the applied ones on the ticker store. It moves the
+// references of every user, so it runs on its own transaction.
+func (tcsvc *TickerChangeService) ApplyTickerChanges(until utils.B3Date) (*store.TickerStore, error) {
+	tickerChangeDAO := db.GetTickerChangeDAO(tcsvc.tx, &model.TickerChange{})
+	tickerChanges, err := tickerChangeDAO.LoadTickerChanges()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range tickerChanges {
+		tickerChange := &tickerChanges[idx]
+
+		if !tickerChange.Applied && !tickerChange.EffectiveDate.After(until) {
+			if err := tcsvc.applyTickerChange(tickerChange); err != nil {
+				return nil, err
+			}
+		}
+
+		if tickerChange.Applied {
+			tcsvc.tickerStore.PutSuccessor(tickerChange.OldCode, tickerChange.NewCode)
+		}
+	}
+
+	return tcsvc.tickerStore, nil
+}
+
+// LoadTickerChanges registers the applied ticker changes on the ticker store,
+// pending changes are left as they are.
+func (tcsvc *TickerChangeService) LoadTickerChanges() (*store.TickerStore, error) {
+	tickerChangeDAO := db.GetTickerChangeDAO(tcsvc.tx, &model.TickerChange{})
+	tickerChanges, err := tickerChangeDAO.LoadTickerChanges()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tickerChange := range tickerChanges {
+		if tickerChange.Applied {
+			tcsvc.tickerStore.PutSuccessor(tickerChange.OldCode, tickerChange.NewCode)
+		}
+	}
+
+	return tcsvc.tickerStore, nil
+}
+
+// CreateTickerChange records a ticker change, it is applied at once when
+// effective until the given date.
+func (tcsvc *TickerChangeService) CreateTickerChange(
+	tickerChange *model.TickerChange,
+	until utils.B3Date,
+) (*model.TickerChange, error) {
+	if tickerChange.OldCode == "" || tickerChange.NewCode == "" || tickerChange.OldCode == tickerChange.NewCode {
+		details := fmt.Sprintf("[%s -> %s]", tickerChange.OldCode, tickerChange.NewCode)
+		return nil, utils.GetError("tickerChangeService.CreateTickerChange", "ERR_SYS_001", details)
+	}
+
+	tickerChangeDAO := db.GetTickerChangeDAO(tcsvc.tx, tickerChange)
+	tickerChangeRec, err := tickerChangeDAO.CreateTickerChange()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tickerChangeRec.EffectiveDate.After(until) {
+		return tickerChangeRec, nil
+	}
+
+	if err := tcsvc.applyTickerChange(tickerChangeRec); err != nil {
+		return nil, err
+	}
+
+	tcsvc.tickerStore.PutSuccessor(tickerChangeRec.OldCode, tickerChangeRec.NewCode)
+
+	return tickerChangeRec, nil
+}
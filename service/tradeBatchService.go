@@ -7,16 +7,21 @@ import (
 
 	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
 	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
 	"github.com/jarismar/b3c-invoice-reader-lambda/store"
 	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
 	"github.com/jarismar/b3c-service-entities/entity"
 )
 
 type TradeBatchService struct {
-	tx         *sql.Tx
-	user       *entity.User
-	tradeBatch *entity.TradeBatch
-	taxStore   *store.TaxStore
+	tx                    *sql.Tx
+	user                  *entity.User
+	tradeBatch            *entity.TradeBatch
+	taxStore              *store.TaxStore
+	taxRateStore          *store.TaxRateStore
+	tickerStore           *store.TickerStore
+	fiiTradeBatchStore    *store.FiiTradeBatchStore
+	optionTradeBatchStore *store.OptionTradeBatchStore
 }
 
 func GetTradeBatchService(
@@ -24,12 +29,39 @@ func GetTradeBatchService(
 	user *entity.User,
 	tradeBatch *entity.TradeBatch,
 	taxStore *store.TaxStore,
+	taxRateStore *store.TaxRateStore,
+	tickerStore *store.TickerStore,
+	fiiTradeBatchStore *store.FiiTradeBatchStore,
+	optionTradeBatchStore *store.OptionTradeBatchStore,
 ) *TradeBatchService {
 	return &TradeBatchService{
-		tx:         tx,
-		user:       user,
-		tradeBatch: tradeBatch,
-		taxStore:   taxStore,
+		tx:                    tx,
+		user:                  user,
+		tradeBatch:            tradeBatch,
+		taxStore:              taxStore,
+		taxRateStore:          taxRateStore,
+		tickerStore:           tickerStore,
+		fiiTradeBatchStore:    fiiTradeBatchStore,
+		optionTradeBatchStore: optionTradeBatchStore,
+	}
+}
+
+func (tbsvc *TradeBatchService) getIRFiiFeeTaxInstance(marketDate time.Time) entity.TaxInstance {
+	irFiiFeeRate := tbsvc.taxRateStore.Get(
+		constants.RateCodes.IRFIIFEE,
+		utils.B3DateOf(marketDate),
+	)
+
+	return entity.TaxInstance{
+		MarketDate: marketDate,
+		TaxValue:   0,
+		BaseValue:  0,
+		TaxRate:    irFiiFeeRate,
+		Tax: &entity.Tax{
+			Code:   constants.TaxTypes.IRFIIFEE,
+			Source: constants.TaxSources.TRADE_BATCH,
+			Rate:   irFiiFeeRate,
+		},
 	}
 }
 
@@ -43,6 +75,11 @@ func (tbsvc *TradeBatchService) getTaxGroup(marketDate time.Time) (*entity.TaxGr
 		return nil, err
 	}
 
+	irFeeRate := tbsvc.taxRateStore.Get(
+		constants.RateCodes.IRFEE,
+		utils.B3DateOf(marketDate),
+	)
+
 	taxes := make([]entity.TaxInstance, 0)
 	taxGroup := &entity.TaxGroup{
 		Source:     entity.TRB,
@@ -53,15 +90,16 @@ func (tbsvc *TradeBatchService) getTaxGroup(marketDate time.Time) (*entity.TaxGr
 		MarketDate: marketDate,
 		TaxValue:   0,
 		BaseValue:  0,
-		TaxRate:    constants.TaxRates.IRFEE,
+		TaxRate:    irFeeRate,
 		Tax: &entity.Tax{
 			Code:   constants.TaxTypes.IRFEE,
 			Source: constants.TaxSources.TRADE_BATCH,
-			Rate:   constants.TaxRates.IRFEE,
+			Rate:   irFeeRate,
 		},
 	}
 
 	taxes = append(taxes, irFeeTaxInstance)
+	taxes = append(taxes, tbsvc.getIRFiiFeeTaxInstance(marketDate))
 	taxGroup.Taxes = taxes
 
 	taxGroupService := GetTaxGroupService(tbsvc.tx, taxGroup, tbsvc.taxStore)
@@ -96,30 +134,41 @@ func (tbsvc *TradeBatchService) getNewTradeData(lastTradeData *entity.TradeBatch
 
 func (tbsvc *TradeBatchService) adjustTradeBatchTaxes() *entity.TradeBatch {
 	tradeBatch := tbsvc.tradeBatch
+	rateCodes := constants.RateCodes
+	startDate := utils.B3DateOf(tradeBatch.StartDate)
+	irFeeRate := tbsvc.taxRateStore.Get(rateCodes.IRFEE, startDate)
+	irExemptLimit := tbsvc.taxRateStore.Get(rateCodes.IR_EXPT_LIMIT, startDate)
 
 	var shrIRFee float64
 	var bdrIRFee float64
 	var etfIRFee float64
 	var irFeeBaseValue float64 = 0.0
 
+	var optIRFee float64
+
 	shrTradeData := tradeBatch.Shr
 	bdrTradeData := tradeBatch.Bdr
 	etfTradeData := tradeBatch.Etf
+	optTradeData := &entity.TradeBatchData{}
 
-	totalAccLoss := shrTradeData.AccLoss + bdrTradeData.AccLoss + etfTradeData.AccLoss
-	totalResults := shrTradeData.Results + bdrTradeData.Results + etfTradeData.Results
-	totalTaxes := shrTradeData.TotalTax + bdrTradeData.TotalTax + etfTradeData.TotalTax
+	if tbsvc.optionTradeBatchStore.Has(tradeBatch.Id) {
+		optTradeData = tbsvc.optionTradeBatchStore.Get(tradeBatch.Id).Data
+	}
+
+	totalAccLoss := shrTradeData.AccLoss + bdrTradeData.AccLoss + etfTradeData.AccLoss + optTradeData.AccLoss
+	totalResults := shrTradeData.Results + bdrTradeData.Results + etfTradeData.Results + optTradeData.Results
+	totalTaxes := shrTradeData.TotalTax + bdrTradeData.TotalTax + etfTradeData.TotalTax + optTradeData.TotalTax
 	irExemptByLoss := (totalResults - totalTaxes) <= totalAccLoss
 
 	// SHR
-	shrIRExcemptByLimit := (shrTradeData.TotalTrade <= constants.TaxRates.IR_EXPT_LIMIT)
+	shrIRExcemptByLimit := (shrTradeData.TotalTrade <= irExemptLimit)
 	shrCurrentResults := shrTradeData.Results - shrTradeData.TotalTax
 	shrIRExcemptByResuls := shrCurrentResults <= 0
 
 	if shrIRExcemptByLimit || shrIRExcemptByResuls || irExemptByLoss {
 		shrIRFee = 0.0
 	} else {
-		shrIRFee = shrCurrentResults * constants.TaxRates.IRFEE
+		shrIRFee = shrCurrentResults * irFeeRate
 		irFeeBaseValue = irFeeBaseValue + shrCurrentResults
 	}
 
@@ -129,7 +178,7 @@ func (tbsvc *TradeBatchService) adjustTradeBatchTaxes() *entity.TradeBatch {
 	if bdrIRExcempByResults || irExemptByLoss {
 		bdrIRFee = 0.0
 	} else {
-		bdrIRFee = bdrCurrentResults * constants.TaxRates.IRFEE
+		bdrIRFee = bdrCurrentResults * irFeeRate
 		irFeeBaseValue = irFeeBaseValue + bdrCurrentResults
 	}
 
@@ -139,16 +188,26 @@ func (tbsvc *TradeBatchService) adjustTradeBatchTaxes() *entity.TradeBatch {
 	if etfIRExcempByResults || irExemptByLoss {
 		etfIRFee = 0.0
 	} else {
-		etfIRFee = etfCurrentResults * constants.TaxRates.IRFEE
+		etfIRFee = etfCurrentResults * irFeeRate
 		irFeeBaseValue = irFeeBaseValue + etfCurrentResults
 	}
 
+	// OPT
+	optCurrentResults := optTradeData.Results - optTradeData.TotalTax
+	optIRExcempByResults := optCurrentResults <= 0
+	if optIRExcempByResults || irExemptByLoss {
+		optIRFee = 0.0
+	} else {
+		optIRFee = optCurrentResults * irFeeRate
+		irFeeBaseValue = irFeeBaseValue + optCurrentResults
+	}
+
 	taxGroup := tradeBatch.TaxGroup
 
 	// find IRFEE from tax group - if not exists then create
 	irFeeInstance := taxGroup.GetTaxInstanceByCode(constants.TaxTypes.IRFEE)
 
-	irFeeInstance.TaxValue = (shrIRFee + bdrIRFee + etfIRFee)
+	irFeeInstance.TaxValue = (shrIRFee + bdrIRFee + etfIRFee + optIRFee)
 	irFeeInstance.BaseValue = irFeeBaseValue
 
 	taxGroup.Taxes[0] = *irFeeInstance // TODO taxGroup.Taxes needs to use ptrs
@@ -158,9 +217,64 @@ func (tbsvc *TradeBatchService) adjustTradeBatchTaxes() *entity.TradeBatch {
 		irFeeInstance.Id,
 	)
 
+	tbsvc.adjustFiiTaxes()
+
 	return tradeBatch
 }
 
+// adjustFiiTaxes FII gains pay IRFIIFEE with no exemption limit, only the
+// FII accumulated loss is deducted.
+func (tbsvc *TradeBatchService) adjustFiiTaxes() {
+	tradeBatch := tbsvc.tradeBatch
+
+	if !tbsvc.fiiTradeBatchStore.Has(tradeBatch.Id) {
+		return
+	}
+
+	fiiTradeData := tbsvc.fiiTradeBatchStore.Get(tradeBatch.Id).Data
+	taxGroup := tradeBatch.TaxGroup
+
+	taxIdx := -1
+
+	for idx, taxInstance := range taxGroup.Taxes {
+		if taxInstance.Tax.Code == constants.TaxTypes.IRFIIFEE {
+			taxIdx = idx
+		}
+	}
+
+	// groups created before FII support have no IRFIIFEE instance, it is
+	// inserted by SaveTradeBatch
+	var irFiiFeeInstance entity.TaxInstance
+
+	if taxIdx < 0 {
+		irFiiFeeInstance = tbsvc.getIRFiiFeeTaxInstance(tradeBatch.StartDate)
+	} else {
+		irFiiFeeInstance = taxGroup.Taxes[taxIdx]
+	}
+
+	fiiCurrentResults := fiiTradeData.Results - fiiTradeData.TotalTax
+	fiiBaseValue := fiiCurrentResults + fiiTradeData.AccLoss
+
+	if fiiCurrentResults <= 0 || fiiBaseValue <= 0 {
+		irFiiFeeInstance.TaxValue = 0.0
+		irFiiFeeInstance.BaseValue = 0.0
+	} else {
+		irFiiFeeInstance.TaxValue = fiiBaseValue * irFiiFeeInstance.TaxRate
+		irFiiFeeInstance.BaseValue = fiiBaseValue
+	}
+
+	if taxIdx < 0 {
+		taxGroup.Taxes = append(taxGroup.Taxes, irFiiFeeInstance)
+	} else {
+		taxGroup.Taxes[taxIdx] = irFiiFeeInstance
+	}
+
+	log.Printf(
+		"TradeBatchService.adjustFiiTaxes: id = %d",
+		irFiiFeeInstance.Id,
+	)
+}
+
 func (tbsvc *TradeBatchService) createTradeBatch(
 	marketDate time.Time,
 	lastTradeBatch *entity.TradeBatch,
@@ -228,44 +342,214 @@ func (tbsvc *TradeBatchService) FindTradeBatch(marketDate time.Time) (*entity.Tr
 			return nil, err
 		}
 
-		return tbsvc.createTradeBatch(marketDate, lastTradeBatch)
+		tradeBatchRec, err = tbsvc.createTradeBatch(marketDate, lastTradeBatch)
+
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		log.Printf(
+			"TradeBatchService.FindTradeBatch: returning tradeBatch [%d, %s]",
+			tradeBatchRec.Id,
+			tradeBatchRec.StartDate.Format(time.RFC3339),
+		)
+	}
+
+	if _, err := tbsvc.findFiiTradeBatch(tradeBatchRec); err != nil {
+		return nil, err
+	}
+
+	if _, err := tbsvc.findOptionTradeBatch(tradeBatchRec); err != nil {
+		return nil, err
+	}
+
+	return tradeBatchRec, nil
+}
+
+// findFiiTradeBatch loads the FII data of tradeBatch, months without it start
+// with the FII loss carried from the last month holding FII data.
+func (tbsvc *TradeBatchService) findFiiTradeBatch(tradeBatch *entity.TradeBatch) (*model.FiiTradeBatch, error) {
+	fiiTradeBatchStore := tbsvc.fiiTradeBatchStore
+
+	if fiiTradeBatchStore.Has(tradeBatch.Id) {
+		return fiiTradeBatchStore.Get(tradeBatch.Id), nil
 	}
 
+	fiiTradeBatchDAO := db.GetFiiTradeBatchDAO(tbsvc.tx, &model.FiiTradeBatch{
+		TradeBatch: tradeBatch,
+	})
+
+	fiiTradeBatchRec, err := fiiTradeBatchDAO.GetFiiTradeBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if fiiTradeBatchRec == nil {
+		lastFiiTradeBatch, err := fiiTradeBatchDAO.GetLastFiiTradeBatch()
+
+		if err != nil {
+			return nil, err
+		}
+
+		var lastFiiData *entity.TradeBatchData
+
+		if lastFiiTradeBatch != nil {
+			lastFiiData = lastFiiTradeBatch.Data
+		}
+
+		fiiTradeBatchDAO = db.GetFiiTradeBatchDAO(tbsvc.tx, &model.FiiTradeBatch{
+			TradeBatch: tradeBatch,
+			Data:       tbsvc.getNewTradeData(lastFiiData),
+		})
+
+		fiiTradeBatchRec, err = fiiTradeBatchDAO.CreateFiiTradeBatch()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fiiTradeBatchStore.Put(fiiTradeBatchRec), nil
+}
+
+// findOptionTradeBatch loads the option data of tradeBatch, months without it
+// start with the option loss carried from the last month holding option data.
+func (tbsvc *TradeBatchService) findOptionTradeBatch(tradeBatch *entity.TradeBatch) (*model.OptionTradeBatch, error) {
+	optionTradeBatchStore := tbsvc.optionTradeBatchStore
+
+	if optionTradeBatchStore.Has(tradeBatch.Id) {
+		return optionTradeBatchStore.Get(tradeBatch.Id), nil
+	}
+
+	optionTradeBatchDAO := db.GetOptionTradeBatchDAO(tbsvc.tx, &model.OptionTradeBatch{
+		TradeBatch: tradeBatch,
+	})
+
+	optionTradeBatchRec, err := optionTradeBatchDAO.GetOptionTradeBatch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if optionTradeBatchRec == nil {
+		lastOptionTradeBatch, err := optionTradeBatchDAO.GetLastOptionTradeBatch()
+
+		if err != nil {
+			return nil, err
+		}
+
+		var lastOptionData *entity.TradeBatchData
+
+		if lastOptionTradeBatch != nil {
+			lastOptionData = lastOptionTradeBatch.Data
+		}
+
+		optionTradeBatchDAO = db.GetOptionTradeBatchDAO(tbsvc.tx, &model.OptionTradeBatch{
+			TradeBatch: tradeBatch,
+			Data:       tbsvc.getNewTradeData(lastOptionData),
+		})
+
+		optionTradeBatchRec, err = optionTradeBatchDAO.CreateOptionTradeBatch()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return optionTradeBatchStore.Put(optionTradeBatchRec), nil
+}
+
+// ProcessOptionResult adds a result realized on options (closed, exercised
+// or expired positions) to the trade batch, premium is the value traded.
+func (tbsvc *TradeBatchService) ProcessOptionResult(result float64, premium float64) *entity.TradeBatch {
+	tradeBatch := tbsvc.tradeBatch
+	optTradeData := tbsvc.optionTradeBatchStore.Get(tradeBatch.Id).Data
+
+	optTradeData.Results = optTradeData.Results + result
+	optTradeData.TotalTrade = optTradeData.TotalTrade + premium
+
 	log.Printf(
-		"TradeBatchService.FindTradeBatch: returning tradeBatch [%d, %s]",
-		tradeBatchRec.Id,
-		tradeBatchRec.StartDate.Format(time.RFC3339),
+		"TradeBatchService.ProcessOptionResult: id = %d, result = %.2f",
+		tradeBatch.Id,
+		result,
 	)
 
-	return tradeBatchRec, nil
+	return tbsvc.adjustTradeBatchTaxes()
 }
 
-func (tbsvc *TradeBatchService) ProcessTrade(trade *entity.Trade) *entity.TradeBatch {
+// ProcessCost adds a cost deductible from the month share results, such as
+// stock lending fees paid by borrowers.
+func (tbsvc *TradeBatchService) ProcessCost(cost float64) *entity.TradeBatch {
+	tradeBatch := tbsvc.tradeBatch
+	tradeBatch.Shr.TotalTax = tradeBatch.Shr.TotalTax + cost
+
+	log.Printf(
+		"TradeBatchService.ProcessCost: id = %d, cost = %.2f",
+		tradeBatch.Id,
+		cost,
+	)
+
+	return tbsvc.adjustTradeBatchTaxes()
+}
+
+// processResult adds a realized result to the bucket of the company,
+// totalTrade is the value sold.
+func (tbsvc *TradeBatchService) processResult(
+	company *entity.Company,
+	rawResults float64,
+	totalTax float64,
+	totalTrade float64,
+) *entity.TradeBatch {
 	tradeBatch := tbsvc.tradeBatch
-	company := trade.Item.Company
 
 	shrTradeData := tradeBatch.Shr
 	bdrTradeData := tradeBatch.Bdr
 
-	if company.BDR {
-		bdrTradeData.Results = bdrTradeData.Results + trade.RawResults
-		bdrTradeData.TotalTax = bdrTradeData.TotalTax + trade.TotalTax
+	if GetAssetClass(tbsvc.tickerStore, company) == constants.AssetClasses.FII {
+		fiiTradeData := tbsvc.fiiTradeBatchStore.Get(tradeBatch.Id).Data
+
+		fiiTradeData.Results = fiiTradeData.Results + rawResults
+		fiiTradeData.TotalTax = fiiTradeData.TotalTax + totalTax
+		fiiTradeData.TotalTrade = fiiTradeData.TotalTrade + totalTrade
+	} else if company.BDR {
+		bdrTradeData.Results = bdrTradeData.Results + rawResults
+		bdrTradeData.TotalTax = bdrTradeData.TotalTax + totalTax
 	} else if company.ETF {
 		// TODO implement support for ETF
 	} else {
-		itemTrade := trade.Item.Price * float64(trade.Item.Qty)
-
-		shrTradeData.Results = shrTradeData.Results + trade.RawResults
-		shrTradeData.TotalTax = shrTradeData.TotalTax + trade.TotalTax
-		shrTradeData.TotalTrade = shrTradeData.TotalTrade + itemTrade
+		shrTradeData.Results = shrTradeData.Results + rawResults
+		shrTradeData.TotalTax = shrTradeData.TotalTax + totalTax
+		shrTradeData.TotalTrade = shrTradeData.TotalTrade + totalTrade
 	}
 
+	return tbsvc.adjustTradeBatchTaxes()
+}
+
+func (tbsvc *TradeBatchService) ProcessTrade(trade *entity.Trade) *entity.TradeBatch {
 	log.Printf(
 		"TradeBatchService.ProcessTrade: id = %d",
-		tradeBatch.Id,
+		tbsvc.tradeBatch.Id,
 	)
 
-	return tbsvc.adjustTradeBatchTaxes()
+	return tbsvc.processResult(
+		trade.Item.Company,
+		trade.RawResults,
+		trade.TotalTax,
+		trade.Item.Price*float64(trade.Item.Qty),
+	)
+}
+
+// ProcessFraction adds a fraction paid in cash by a corporate event, a sale
+// of cash value with no whole share.
+func (tbsvc *TradeBatchService) ProcessFraction(trade *entity.Trade, cash float64) *entity.TradeBatch {
+	log.Printf(
+		"TradeBatchService.ProcessFraction: id = %d, cash = %.2f",
+		tbsvc.tradeBatch.Id,
+		cash,
+	)
+
+	return tbsvc.processResult(trade.Item.Company, trade.RawResults, trade.TotalTax, cash)
 }
 
 func (tbsvc *TradeBatchService) SaveTradeBatch() (*entity.TradeBatch, error) {
@@ -292,5 +576,27 @@ func (tbsvc *TradeBatchService) SaveTradeBatch() (*entity.TradeBatch, error) {
 		return nil, err
 	}
 
+	if tbsvc.fiiTradeBatchStore.Has(tbsvc.tradeBatch.Id) {
+		fiiTradeBatchDAO := db.GetFiiTradeBatchDAO(
+			tbsvc.tx,
+			tbsvc.fiiTradeBatchStore.Get(tbsvc.tradeBatch.Id),
+		)
+
+		if err = fiiTradeBatchDAO.UpdateFiiTradeBatch(); err != nil {
+			return nil, err
+		}
+	}
+
+	if tbsvc.optionTradeBatchStore.Has(tbsvc.tradeBatch.Id) {
+		optionTradeBatchDAO := db.GetOptionTradeBatchDAO(
+			tbsvc.tx,
+			tbsvc.optionTradeBatchStore.Get(tbsvc.tradeBatch.Id),
+		)
+
+		if err = optionTradeBatchDAO.UpdateOptionTradeBatch(); err != nil {
+			return nil, err
+		}
+	}
+
 	return tbsvc.tradeBatch, nil
 }
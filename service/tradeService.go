@@ -6,6 +6,7 @@ import (
 
 	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
 	"github.com/jarismar/b3c-invoice-reader-lambda/db"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
 	"github.com/jarismar/b3c-invoice-reader-lambda/store"
 	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
 	"github.com/jarismar/b3c-service-entities/entity"
@@ -20,6 +21,9 @@ type TradeService struct {
 	taxStore          *store.TaxStore
 	brokerTaxStore    *store.BrokerTaxStore
 	companyBatchStore *store.CompanyBatchStore
+	taxRateStore      *store.TaxRateStore
+	broker            *model.Broker
+	brokerBatchStore  *store.BrokerBatchStore
 }
 
 func GetTradeService(
@@ -31,6 +35,9 @@ func GetTradeService(
 	taxStore *store.TaxStore,
 	brokerTaxStore *store.BrokerTaxStore,
 	companyBatchStore *store.CompanyBatchStore,
+	taxRateStore *store.TaxRateStore,
+	broker *model.Broker,
+	brokerBatchStore *store.BrokerBatchStore,
 ) *TradeService {
 	return &TradeService{
 		tx:                tx,
@@ -41,6 +48,9 @@ func GetTradeService(
 		taxStore:          taxStore,
 		brokerTaxStore:    brokerTaxStore,
 		companyBatchStore: companyBatchStore,
+		taxRateStore:      taxRateStore,
+		broker:            broker,
+		brokerBatchStore:  brokerBatchStore,
 	}
 }
 
@@ -50,41 +60,27 @@ func (tsvc *TradeService) getTaxValue(
 ) float64 {
 	taxCode := invoiceTaxInstance.Tax.Code
 	taxTypes := constants.TaxTypes
-	taxRates := constants.TaxRates
 	item := tsvc.invoiceItem
+	marketDate := utils.B3DateOf(tsvc.invoice.MarketDate)
 
-	if taxCode == taxTypes.BRKFEE {
-		if tsvc.brokerTaxStore.Has(item, taxCode) {
-			return 0
-		}
-
+	if taxCode == taxTypes.BRKFEE || taxCode == taxTypes.ISSSPFEE {
 		if invoiceTaxInstance.TaxValue == 0 {
 			return 0
 		}
 
-		tsvc.brokerTaxStore.Put(item, taxCode)
-
-		return taxRates.BRKFEE
-	}
-
-	if taxCode == taxTypes.ISSSPFEE {
-		if tsvc.brokerTaxStore.Has(item, taxCode) {
-			return 0
-		}
-
-		if invoiceTaxInstance.TaxValue == 0 {
-			return 0
-		}
-
-		tsvc.brokerTaxStore.Put(item, taxCode)
+		brokerageService := GetBrokerageService(
+			tsvc.broker,
+			marketDate,
+			tsvc.brokerTaxStore,
+			tsvc.taxRateStore,
+		)
 
-		// t = (b / (1 - i)) - c
-		return (taxRates.BRKFEE / (1 - taxRates.ISSSPFEE)) - taxRates.BRKFEE
+		return brokerageService.GetTaxValue(item, taxCode)
 	}
 
 	if taxCode == taxTypes.IRRFFEE {
 		irrfFeeBaseValue := item.Price * float64(item.Qty)
-		return irrfFeeBaseValue * taxRates.IRRFFEE
+		return irrfFeeBaseValue * tsvc.taxRateStore.Get(constants.RateCodes.IRRFFEE, marketDate)
 	}
 
 	return invoiceTaxInstance.TaxValue * itemPriceRate
@@ -227,6 +223,19 @@ func (tsvc *TradeService) ProcessTrade() (*entity.Trade, error) {
 		return nil, err
 	}
 
+	brokerBatchService := GetBrokerBatchService(
+		tsvc.tx,
+		companyBatch,
+		tsvc.broker.AgentId,
+		tsvc.brokerBatchStore,
+	)
+
+	_, err = brokerBatchService.RemoveQty(invoiceItem.Qty, invoiceItem.MarketDate)
+
+	if err != nil {
+		return nil, err
+	}
+
 	totalTax := taxGroup.GetTotalTax()
 	aqPrice := companyBatch.AvgPrice * float64(invoiceItem.Qty)
 	slPrice := invoiceItem.Price * float64(invoiceItem.Qty)
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type BrokerBatchStore struct {
+	cache map[string]*model.BrokerBatch
+}
+
+func GetBrokerBatchStore() *BrokerBatchStore {
+	return &BrokerBatchStore{
+		cache: make(map[string]*model.BrokerBatch),
+	}
+}
+
+func (store *BrokerBatchStore) getKey(bb *model.BrokerBatch) string {
+	return fmt.Sprintf("%d-%s", bb.CompanyBatch.Id, bb.AgentId)
+}
+
+func (store *BrokerBatchStore) Has(bb *model.BrokerBatch) bool {
+	_, ok := store.cache[store.getKey(bb)]
+	return ok
+}
+
+func (store *BrokerBatchStore) Put(bb *model.BrokerBatch) *model.BrokerBatch {
+	store.cache[store.getKey(bb)] = bb
+	return bb
+}
+
+func (store *BrokerBatchStore) Get(bb *model.BrokerBatch) *model.BrokerBatch {
+	bbrec, ok := store.cache[store.getKey(bb)]
+
+	if !ok {
+		log.Printf("store.BrokerBatchStore.Get: WARNING: entry %s/%s not found", bb.Company.Code, bb.AgentId)
+	}
+
+	return bbrec
+}
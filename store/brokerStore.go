@@ -0,0 +1,133 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+)
+
+type BrokerStore struct {
+	cache map[string]*model.Broker
+}
+
+type brokerFile struct {
+	Brokers []model.Broker `json:"brokers"`
+}
+
+func GetBrokerStore() *BrokerStore {
+	return &BrokerStore{
+		cache: make(map[string]*model.Broker),
+	}
+}
+
+func (store *BrokerStore) Has(agentId string) bool {
+	_, ok := store.cache[agentId]
+	return ok
+}
+
+func (store *BrokerStore) Put(broker *model.Broker) *model.Broker {
+	store.cache[broker.AgentId] = broker
+	return broker
+}
+
+// Get returns the broker registered for agentId, unknown agents get a per
+// company flat fee using the rate table values (BRKFEE, ISSSPFEE).
+func (store *BrokerStore) Get(agentId string) *model.Broker {
+	broker, ok := store.cache[agentId]
+
+	if !ok {
+		log.Printf("store.BrokerStore.Get: WARNING: entry %s not found, using default broker", agentId)
+
+		return &model.Broker{
+			AgentId:        agentId,
+			BrokerageModel: constants.BrokerageModels.PER_COMPANY,
+		}
+	}
+
+	return broker
+}
+
+// FindByName returns the broker whose registered name starts the institution
+// name printed on B3 exports ("XP" for "XP INVESTIMENTOS CCTVM S/A"), the
+// longest name wins; nil when none matches.
+func (store *BrokerStore) FindByName(institution string) *model.Broker {
+	institution = utils.NormalizeSecurityName(institution)
+
+	var found *model.Broker
+	foundName := ""
+
+	for _, broker := range store.cache {
+		name := utils.NormalizeSecurityName(broker.Name)
+
+		if name == "" || (institution != name && !strings.HasPrefix(institution, name+" ")) {
+			continue
+		}
+
+		if len(name) > len(foundName) {
+			found = broker
+			foundName = name
+		}
+	}
+
+	return found
+}
+
+func (store *BrokerStore) LoadFile(fileName string) error {
+	registryFile, err := os.Open(fileName)
+
+	if err != nil {
+		log.Printf("store.BrokerStore.LoadFile: error opening file: %s", fileName)
+		return err
+	}
+
+	defer registryFile.Close()
+
+	if err = store.Load(registryFile); err != nil {
+		return err
+	}
+
+	log.Printf("store.BrokerStore.LoadFile: success loading: %s", fileName)
+
+	return nil
+}
+
+// Load reads a json document {"brokers": [model.Broker]}.
+func (store *BrokerStore) Load(source io.Reader) error {
+	jsonContent, err := io.ReadAll(source)
+
+	if err != nil {
+		return err
+	}
+
+	var content brokerFile
+
+	if err = json.Unmarshal(jsonContent, &content); err != nil {
+		return err
+	}
+
+	brokerageModels := constants.BrokerageModels
+
+	for idx := range content.Brokers {
+		broker := &content.Brokers[idx]
+
+		switch broker.BrokerageModel {
+		case brokerageModels.FLAT_ORDER,
+			brokerageModels.PER_COMPANY,
+			brokerageModels.TIERED,
+			brokerageModels.ZERO:
+			store.Put(broker)
+		default:
+			details := fmt.Sprintf("broker %s: unknown brokerage model %s", broker.AgentId, broker.BrokerageModel)
+			return utils.GetError("store.BrokerStore.Load", "ERR_SYS_001", details)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+)
+
+type CompanyAliasStore struct {
+	cache map[string]*model.CompanyAlias
+}
+
+type companyAliasFile struct {
+	Aliases []model.CompanyAlias `json:"aliases"`
+}
+
+func GetCompanyAliasStore() *CompanyAliasStore {
+	return &CompanyAliasStore{
+		cache: make(map[string]*model.CompanyAlias),
+	}
+}
+
+// Has names are compared after utils.NormalizeSecurityName.
+func (store *CompanyAliasStore) Has(name string) bool {
+	_, ok := store.cache[utils.NormalizeSecurityName(name)]
+	return ok
+}
+
+func (store *CompanyAliasStore) Put(companyAlias *model.CompanyAlias) *model.CompanyAlias {
+	store.cache[utils.NormalizeSecurityName(companyAlias.Name)] = companyAlias
+	return companyAlias
+}
+
+func (store *CompanyAliasStore) Get(name string) *model.CompanyAlias {
+	entry, ok := store.cache[utils.NormalizeSecurityName(name)]
+
+	if !ok {
+		log.Printf("store.CompanyAliasStore.Get: WARNING: entry %s not found", name)
+	}
+
+	return entry
+}
+
+func (store *CompanyAliasStore) LoadFile(fileName string) error {
+	aliasFile, err := os.Open(fileName)
+
+	if err != nil {
+		log.Printf("store.CompanyAliasStore.LoadFile: error opening file: %s", fileName)
+		return err
+	}
+
+	defer aliasFile.Close()
+
+	if err = store.Load(aliasFile); err != nil {
+		return err
+	}
+
+	log.Printf("store.CompanyAliasStore.LoadFile: success loading: %s", fileName)
+
+	return nil
+}
+
+// Load reads a json document {"aliases": [{name, code}]}.
+func (store *CompanyAliasStore) Load(source io.Reader) error {
+	jsonContent, err := io.ReadAll(source)
+
+	if err != nil {
+		return err
+	}
+
+	var content companyAliasFile
+
+	if err = json.Unmarshal(jsonContent, &content); err != nil {
+		return err
+	}
+
+	for idx := range content.Aliases {
+		store.Put(&content.Aliases[idx])
+	}
+
+	return nil
+}
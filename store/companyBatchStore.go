@@ -1,6 +1,7 @@
 package store
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/jarismar/b3c-service-entities/entity"
@@ -16,18 +17,22 @@ func GetCompanyBatchStore() *CompanyBatchStore {
 	}
 }
 
+func (store *CompanyBatchStore) getKey(cb *entity.CompanyBatch) string {
+	return fmt.Sprintf("%d-%s", cb.User.Id, cb.Company.Code)
+}
+
 func (store *CompanyBatchStore) Has(cb *entity.CompanyBatch) bool {
-	_, ok := store.cache[cb.Company.Code]
+	_, ok := store.cache[store.getKey(cb)]
 	return ok
 }
 
 func (store *CompanyBatchStore) Put(cb *entity.CompanyBatch) *entity.CompanyBatch {
-	store.cache[cb.Company.Code] = cb
+	store.cache[store.getKey(cb)] = cb
 	return cb
 }
 
 func (store *CompanyBatchStore) Get(cb *entity.CompanyBatch) *entity.CompanyBatch {
-	cbrec, ok := store.cache[cb.Company.Code]
+	cbrec, ok := store.cache[store.getKey(cb)]
 
 	if !ok {
 		log.Printf("store.CompanyBatchStore.Get: WARNING: entry %s not found", cb.Company.Code)
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type FiiTradeBatchStore struct {
+	cache map[int64]*model.FiiTradeBatch
+}
+
+func GetFiiTradeBatchStore() *FiiTradeBatchStore {
+	return &FiiTradeBatchStore{
+		cache: make(map[int64]*model.FiiTradeBatch),
+	}
+}
+
+func (store *FiiTradeBatchStore) Has(tradeBatchId int64) bool {
+	_, ok := store.cache[tradeBatchId]
+	return ok
+}
+
+func (store *FiiTradeBatchStore) Put(ftb *model.FiiTradeBatch) *model.FiiTradeBatch {
+	store.cache[ftb.TradeBatch.Id] = ftb
+	return ftb
+}
+
+func (store *FiiTradeBatchStore) Get(tradeBatchId int64) *model.FiiTradeBatch {
+	ftbrec, ok := store.cache[tradeBatchId]
+
+	if !ok {
+		log.Printf("store.FiiTradeBatchStore.Get: WARNING: entry %d not found", tradeBatchId)
+	}
+
+	return ftbrec
+}
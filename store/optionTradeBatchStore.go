@@ -0,0 +1,37 @@
+package store
+
+import (
+	"log"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+)
+
+type OptionTradeBatchStore struct {
+	cache map[int64]*model.OptionTradeBatch
+}
+
+func GetOptionTradeBatchStore() *OptionTradeBatchStore {
+	return &OptionTradeBatchStore{
+		cache: make(map[int64]*model.OptionTradeBatch),
+	}
+}
+
+func (store *OptionTradeBatchStore) Has(tradeBatchId int64) bool {
+	_, ok := store.cache[tradeBatchId]
+	return ok
+}
+
+func (store *OptionTradeBatchStore) Put(otb *model.OptionTradeBatch) *model.OptionTradeBatch {
+	store.cache[otb.TradeBatch.Id] = otb
+	return otb
+}
+
+func (store *OptionTradeBatchStore) Get(tradeBatchId int64) *model.OptionTradeBatch {
+	otbrec, ok := store.cache[tradeBatchId]
+
+	if !ok {
+		log.Printf("store.OptionTradeBatchStore.Get: WARNING: entry %d not found", tradeBatchId)
+	}
+
+	return otbrec
+}
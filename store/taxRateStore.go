@@ -0,0 +1,137 @@
+package store
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+)
+
+type TaxRateStore struct {
+	cache    map[string][]model.TaxRate
+	defaults map[string]float64
+}
+
+type taxRateFile struct {
+	Rates []taxRateFileEntry `json:"rates"`
+}
+
+type taxRateFileEntry struct {
+	Code      string  `json:"code"`
+	StartDate string  `json:"startDate"`
+	EndDate   string  `json:"endDate"`
+	Rate      float64 `json:"rate"`
+}
+
+// GetTaxRateStore returns a store falling back to constants.TaxRates when no
+// interval covers the requested date.
+func GetTaxRateStore() *TaxRateStore {
+	rateCodes := constants.RateCodes
+	taxRates := constants.TaxRates
+
+	return &TaxRateStore{
+		cache: make(map[string][]model.TaxRate),
+		defaults: map[string]float64{
+			rateCodes.SETFEE:        taxRates.SETFEE,
+			rateCodes.EMLFEE:        taxRates.EMLFEE,
+			rateCodes.ISSSPFEE:      taxRates.ISSSPFEE,
+			rateCodes.IRRFFEE:       taxRates.IRRFFEE,
+			rateCodes.IR_EXPT_LIMIT: taxRates.IR_EXPT_LIMIT,
+			rateCodes.IRFEE:         taxRates.IRFEE,
+			rateCodes.IRDTFEE:       taxRates.IRDTFEE,
+			rateCodes.IRFIIFEE:      taxRates.IRFIIFEE,
+			rateCodes.BRKFEE:        taxRates.BRKFEE,
+		},
+	}
+}
+
+// Put adds a rate interval, when intervals overlap the latest start date wins
+// and ties go to the last one added.
+func (store *TaxRateStore) Put(rate *model.TaxRate) *model.TaxRate {
+	rates := append([]model.TaxRate{*rate}, store.cache[rate.Code]...)
+
+	sort.SliceStable(rates, func(i, j int) bool {
+		return rates[i].StartDate.After(rates[j].StartDate)
+	})
+
+	store.cache[rate.Code] = rates
+	return rate
+}
+
+func (store *TaxRateStore) Get(code string, date utils.B3Date) float64 {
+	for _, rate := range store.cache[code] {
+		if rate.IsValidOn(date) {
+			return rate.Rate
+		}
+	}
+
+	value, ok := store.defaults[code]
+
+	if !ok {
+		log.Printf("store.TaxRateStore.Get: WARNING: entry %s not found", code)
+	}
+
+	return value
+}
+
+func (store *TaxRateStore) LoadFile(fileName string) error {
+	rateFile, err := os.Open(fileName)
+
+	if err != nil {
+		log.Printf("store.TaxRateStore.LoadFile: error opening file: %s", fileName)
+		return err
+	}
+
+	defer rateFile.Close()
+
+	if err = store.Load(rateFile); err != nil {
+		return err
+	}
+
+	log.Printf("store.TaxRateStore.LoadFile: success loading: %s", fileName)
+
+	return nil
+}
+
+// Load reads a json document {"rates": [{code, startDate, endDate, rate}]}.
+func (store *TaxRateStore) Load(source io.Reader) error {
+	jsonContent, err := io.ReadAll(source)
+
+	if err != nil {
+		return err
+	}
+
+	var content taxRateFile
+
+	if err = json.Unmarshal(jsonContent, &content); err != nil {
+		return err
+	}
+
+	for _, entry := range content.Rates {
+		rate := model.TaxRate{
+			Code: entry.Code,
+			Rate: entry.Rate,
+		}
+
+		if entry.StartDate != "" {
+			if rate.StartDate, err = utils.ParseB3Date(entry.StartDate); err != nil {
+				return err
+			}
+		}
+
+		if entry.EndDate != "" {
+			if rate.EndDate, err = utils.ParseB3Date(entry.EndDate); err != nil {
+				return err
+			}
+		}
+
+		store.Put(&rate)
+	}
+
+	return nil
+}
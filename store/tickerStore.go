@@ -0,0 +1,278 @@
+package store
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
+	"github.com/jarismar/b3c-invoice-reader-lambda/model"
+	"github.com/jarismar/b3c-invoice-reader-lambda/utils"
+)
+
+type TickerStore struct {
+	cache      map[string]*model.Ticker
+	successors map[string]string
+}
+
+// tickerColumns lists the accepted header names of each column, the first
+// one is the short layout, the others come from B3's instrument list.
+var tickerColumns = map[string][]string{
+	"code":        {"code", "TckrSymb"},
+	"assetClass":  {"assetClass", "SctyCtgyNm"},
+	"isin":        {"isin", "ISIN"},
+	"issuer":      {"issuer", "CrpnNm"},
+	"lotSize":     {"lotSize", "MinOrdrQty"},
+	"composition": {"composition"},
+}
+
+// b3Categories maps B3's security category names to asset classes.
+var b3Categories = map[string]string{
+	"SHARES":       constants.AssetClasses.STOCK,
+	"BDR":          constants.AssetClasses.BDR,
+	"ETF EQUITIES": constants.AssetClasses.ETF,
+	"ETF FOREIGN":  constants.AssetClasses.ETF,
+	"FUNDS":        constants.AssetClasses.FII,
+	"UNIT":         constants.AssetClasses.UNIT,
+}
+
+func GetTickerStore() *TickerStore {
+	return &TickerStore{
+		cache:      make(map[string]*model.Ticker),
+		successors: make(map[string]string),
+	}
+}
+
+func (store *TickerStore) Has(code string) bool {
+	_, ok := store.cache[code]
+	return ok
+}
+
+func (store *TickerStore) Put(ticker *model.Ticker) *model.Ticker {
+	store.cache[ticker.Code] = ticker
+	return ticker
+}
+
+// Get returns nil when the ticker is not registered.
+func (store *TickerStore) Get(code string) *model.Ticker {
+	entry, ok := store.cache[code]
+
+	if !ok {
+		log.Printf("store.TickerStore.Get: WARNING: entry %s not found", code)
+	}
+
+	return entry
+}
+
+func (store *TickerStore) PutSuccessor(oldCode string, newCode string) {
+	store.successors[oldCode] = newCode
+}
+
+// GetSuccessor follows the ticker changes of code, codes never changed are
+// returned as is.
+func (store *TickerStore) GetSuccessor(code string) string {
+	visited := make(map[string]bool)
+
+	for !visited[code] {
+		visited[code] = true
+		newCode, ok := store.successors[code]
+
+		if !ok {
+			return code
+		}
+
+		code = newCode
+	}
+
+	log.Printf("store.TickerStore.GetSuccessor: WARNING: ticker change cycle on %s", code)
+
+	return code
+}
+
+func (store *TickerStore) GetTickers() []*model.Ticker {
+	tickers := make([]*model.Ticker, 0, len(store.cache))
+
+	for _, ticker := range store.cache {
+		tickers = append(tickers, ticker)
+	}
+
+	return tickers
+}
+
+func (store *TickerStore) LoadFile(fileName string) error {
+	registryFile, err := os.Open(fileName)
+
+	if err != nil {
+		log.Printf("store.TickerStore.LoadFile: error opening file: %s", fileName)
+		return err
+	}
+
+	defer registryFile.Close()
+
+	if err = store.Load(registryFile); err != nil {
+		return err
+	}
+
+	log.Printf("store.TickerStore.LoadFile: success loading: %s", fileName)
+
+	return nil
+}
+
+func getAssetClass(value string) (string, bool) {
+	assetClasses := constants.AssetClasses
+	value = strings.ToUpper(strings.TrimSpace(value))
+
+	switch value {
+	case assetClasses.STOCK,
+		assetClasses.BDR,
+		assetClasses.ETF,
+		assetClasses.FII,
+		assetClasses.UNIT:
+		return value, true
+	}
+
+	assetClass, ok := b3Categories[value]
+	return assetClass, ok
+}
+
+// getComposition parses the unit composition column, components are
+// separated by "|" and written as code:qty ("TAEE3:1|TAEE4:2").
+func getComposition(value string) ([]model.UnitComponent, bool) {
+	components := make([]model.UnitComponent, 0)
+
+	for _, entry := range strings.Split(value, "|") {
+		code, qtyStr, ok := strings.Cut(strings.TrimSpace(entry), ":")
+
+		if !ok {
+			return nil, false
+		}
+
+		qty, err := strconv.ParseInt(strings.TrimSpace(qtyStr), 10, 64)
+
+		if err != nil || qty <= 0 || strings.TrimSpace(code) == "" {
+			return nil, false
+		}
+
+		components = append(components, model.UnitComponent{
+			Code: strings.ToUpper(strings.TrimSpace(code)),
+			Qty:  qty,
+		})
+	}
+
+	return components, true
+}
+
+func getColumnIndexes(header []string) (map[string]int, error) {
+	indexes := make(map[string]int)
+
+	for idx, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+
+		for column, aliases := range tickerColumns {
+			for _, alias := range aliases {
+				if strings.EqualFold(name, alias) {
+					indexes[column] = idx
+				}
+			}
+		}
+	}
+
+	for _, column := range []string{"code", "assetClass"} {
+		if _, ok := indexes[column]; !ok {
+			details := fmt.Sprintf("ticker registry: missing column %s", column)
+			return nil, utils.GetError("store.TickerStore.Load", "ERR_SYS_001", details)
+		}
+	}
+
+	return indexes, nil
+}
+
+// Load reads a csv with a header line, either comma or semicolon separated,
+// rows of categories without an asset class (options, futures) are skipped.
+func (store *TickerStore) Load(source io.Reader) error {
+	content, err := io.ReadAll(source)
+
+	if err != nil {
+		return err
+	}
+
+	csvReader := csv.NewReader(strings.NewReader(string(content)))
+	csvReader.FieldsPerRecord = -1
+
+	firstLine, _, _ := strings.Cut(string(content), "\n")
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		csvReader.Comma = ';'
+	}
+
+	records, err := csvReader.ReadAll()
+
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	indexes, err := getColumnIndexes(records[0])
+
+	if err != nil {
+		return err
+	}
+
+	getField := func(record []string, column string) string {
+		idx, ok := indexes[column]
+
+		if !ok || idx >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[idx])
+	}
+
+	for lineNum, record := range records[1:] {
+		assetClass, ok := getAssetClass(getField(record, "assetClass"))
+
+		if !ok || getField(record, "code") == "" {
+			continue
+		}
+
+		ticker := &model.Ticker{
+			Code:       strings.ToUpper(getField(record, "code")),
+			AssetClass: assetClass,
+			Isin:       getField(record, "isin"),
+			Issuer:     getField(record, "issuer"),
+			LotSize:    1,
+		}
+
+		if lotSize := getField(record, "lotSize"); lotSize != "" {
+			value, err := strconv.ParseFloat(strings.Replace(lotSize, ",", ".", 1), 64)
+
+			if err != nil {
+				details := fmt.Sprintf("ticker registry line %d: invalid lot size %s", lineNum+2, lotSize)
+				return utils.GetError("store.TickerStore.Load", "ERR_SYS_001", details)
+			}
+
+			ticker.LotSize = int64(value)
+		}
+
+		if composition := getField(record, "composition"); composition != "" {
+			components, ok := getComposition(composition)
+
+			if !ok {
+				details := fmt.Sprintf("ticker registry line %d: invalid composition %s", lineNum+2, composition)
+				return utils.GetError("store.TickerStore.Load", "ERR_SYS_001", details)
+			}
+
+			ticker.Composition = components
+		}
+
+		store.Put(ticker)
+	}
+
+	return nil
+}
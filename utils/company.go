@@ -1,11 +1,60 @@
 package utils
 
 import (
+	"regexp"
 	"strings"
 
+	"github.com/jarismar/b3c-invoice-reader-lambda/constants"
 	"github.com/jarismar/b3c-service-entities/entity"
 )
 
+// bdrCode matches the BDR ticker suffixes, 31 to 33 sponsored levels I to III,
+// 34 and 35 non-sponsored and 39 ETF BDRs.
+var bdrCode = regexp.MustCompile(`^[A-Z0-9]{4}3[1-59]$`)
+
+// fractionalCode matches fractional market tickers, the standard ticker
+// followed by F (PETR4F, TAEE11F).
+var fractionalCode = regexp.MustCompile(`^([A-Z0-9]{4}[0-9]{1,2})F$`)
+
+// NormalizeTicker returns the standard market ticker of code and the market
+// segment the code was traded on.
+func NormalizeTicker(code string) (string, string) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	match := fractionalCode.FindStringSubmatch(code)
+
+	if match == nil {
+		return code, constants.MarketSegments.STANDARD
+	}
+
+	return match[1], constants.MarketSegments.FRACTIONAL
+}
+
+// IsBDR guesses BDRs from the ticker, used only for tickers missing from the
+// ticker registry.
 func IsBDR(cmp *entity.Company) bool {
-	return strings.HasSuffix(cmp.Code, "34") || strings.HasSuffix(cmp.Name, "DRN")
+	return bdrCode.MatchString(cmp.Code) || strings.HasSuffix(cmp.Name, "DRN")
+}
+
+// IsFII guesses real estate funds from the security name, SINACOR prints them
+// as "FII <fund> CI", used only for tickers missing from the ticker registry.
+func IsFII(cmp *entity.Company) bool {
+	for _, token := range strings.Fields(NormalizeSecurityName(cmp.Name)) {
+		if token == "FII" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func GetAssetType(cmp *entity.Company) string {
+	if cmp.BDR {
+		return "BDR"
+	}
+
+	if cmp.ETF {
+		return "ETF"
+	}
+
+	return "SHR"
 }
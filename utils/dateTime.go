@@ -1,11 +1,146 @@
 package utils
 
-import "time"
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	_ "time/tzdata" // lambda images don't ship zoneinfo
+)
+
+const B3DateLayout = "2006-01-02"
+
+// BRDateLayout dates printed on B3 exports and broker notes (dd/mm/yyyy).
+const BRDateLayout = "02/01/2006"
+
+// B3Location is the timezone of all market and billing dates (B3 runs on
+// Brasilia time, no DST since 2019).
+var B3Location = loadB3Location()
+
+func loadB3Location() *time.Location {
+	loc, err := time.LoadLocation("America/Sao_Paulo")
+
+	if err != nil {
+		log.Printf("utils.loadB3Location: WARNING: %s, using fixed -03:00", err.Error())
+		return time.FixedZone("BRT", -3*60*60)
+	}
+
+	return loc
+}
+
+// B3Date is a calendar date on the B3 timezone, always at midnight.
+type B3Date struct {
+	t time.Time
+}
+
+func NewB3Date(year int, month time.Month, day int) B3Date {
+	return B3Date{
+		t: time.Date(year, month, day, 0, 0, 0, 0, B3Location),
+	}
+}
+
+// B3DateOf keeps the calendar date of t as seen on its own location, dates
+// read from input files or the DB carry the market day whatever the offset.
+func B3DateOf(t time.Time) B3Date {
+	return NewB3Date(t.Year(), t.Month(), t.Day())
+}
+
+// B3Today returns the current date in Sao Paulo, regardless of host timezone.
+func B3Today() B3Date {
+	return B3DateOf(time.Now().In(B3Location))
+}
+
+// ParseB3Date accepts RFC3339 timestamps and plain yyyy-mm-dd dates.
+func ParseB3Date(value string) (B3Date, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return B3DateOf(t), nil
+	}
+
+	t, err := time.ParseInLocation(B3DateLayout, value, B3Location)
+
+	if err != nil {
+		return B3Date{}, fmt.Errorf("utils.ParseB3Date: invalid date %s", value)
+	}
+
+	return B3DateOf(t), nil
+}
+
+// ParseBRDate reads dd/mm/yyyy dates.
+func ParseBRDate(value string) (B3Date, error) {
+	t, err := time.ParseInLocation(BRDateLayout, strings.TrimSpace(value), B3Location)
+
+	if err != nil {
+		return B3Date{}, fmt.Errorf("utils.ParseBRDate: invalid date %s", value)
+	}
+
+	return B3DateOf(t), nil
+}
+
+func (d B3Date) Time() time.Time {
+	return d.t
+}
+
+func (d B3Date) IsZero() bool {
+	return d.t.IsZero()
+}
+
+func (d B3Date) Year() int {
+	return d.t.Year()
+}
+
+func (d B3Date) Month() time.Month {
+	return d.t.Month()
+}
+
+func (d B3Date) Day() int {
+	return d.t.Day()
+}
+
+func (d B3Date) Weekday() time.Weekday {
+	return d.t.Weekday()
+}
+
+func (d B3Date) AddDays(days int) B3Date {
+	return NewB3Date(d.Year(), d.Month(), d.Day()+days)
+}
+
+func (d B3Date) AddMonths(months int) B3Date {
+	return NewB3Date(d.Year(), d.Month()+time.Month(months), d.Day())
+}
+
+func (d B3Date) FirstDayOfMonth() B3Date {
+	return NewB3Date(d.Year(), d.Month(), 1)
+}
+
+func (d B3Date) LastDayOfMonth() B3Date {
+	return NewB3Date(d.Year(), d.Month()+1, 0)
+}
+
+func (d B3Date) Before(other B3Date) bool {
+	return d.t.Before(other.t)
+}
+
+func (d B3Date) After(other B3Date) bool {
+	return d.t.After(other.t)
+}
+
+func (d B3Date) Equal(other B3Date) bool {
+	return d.t.Equal(other.t)
+}
+
+func (d B3Date) Format(layout string) string {
+	return d.t.Format(layout)
+}
+
+func (d B3Date) String() string {
+	return d.t.Format(B3DateLayout)
+}
 
 func GetDateObject(dateTime string) (time.Time, error) {
-	return time.Parse(time.RFC3339, dateTime)
+	date, err := ParseB3Date(dateTime)
+	return date.Time(), err
 }
 
 func ToFirstDayOfMonth(d time.Time) time.Time {
-	return time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.Local)
+	return B3DateOf(d).FirstDayOfMonth().Time()
 }
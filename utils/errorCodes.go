@@ -7,6 +7,11 @@ import (
 var errorMessagesByCode = map[string]string{
 	"ERR_SYS_001": "input data validation error",
 	"ERR_DB_001":  "wrong number of affected rows",
+	"ERR_CAL_001": "market date is not a trading day",
+	"ERR_CAL_002": "billing date is not the settlement date",
+	"ERR_CMP_001": "security name matches more than one ticker",
+	"ERR_CMP_002": "security name does not match any ticker",
+	"ERR_CMP_003": "unit composition not registered",
 }
 
 func GetError(location string, code string, details string) error {
@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"regexp"
+)
+
+// futuresCode matches BM&F futures tickers, the contract, the maturity month
+// letter (F January to Z December) and the year (WINZ24, WDOF25).
+var futuresCode = regexp.MustCompile(`^([A-Z]{3})([FGHJKMNQUVXZ])([0-9]{2})$`)
+
+// ParseFuturesCode returns the contract of a futures ticker, ok is false for
+// other tickers.
+func ParseFuturesCode(code string) (contract string, ok bool) {
+	match := futuresCode.FindStringSubmatch(code)
+
+	if match == nil {
+		return "", false
+	}
+
+	return match[1], true
+}
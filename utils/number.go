@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseBRNumber reads numbers as printed by B3 and the brokers, "R$ 1.234,56",
+// "1.234,56" and "1234.56" are equal; a dash or an empty value is zero.
+func ParseBRNumber(value string) (float64, error) {
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "R$"))
+
+	if value == "" || value == "-" {
+		return 0, nil
+	}
+
+	if strings.Contains(value, ",") {
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.Replace(value, ",", ".", 1)
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("utils.ParseBRNumber: invalid number %s", value)
+	}
+
+	return number, nil
+}
+
+// ParseBRQty reads whole quantities, dots are thousand separators ("1.000").
+func ParseBRQty(value string) (int64, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), ".", "")
+
+	qty, err := strconv.ParseInt(value, 10, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("utils.ParseBRQty: invalid quantity %s", value)
+	}
+
+	return qty, nil
+}
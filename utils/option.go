@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// optionCode matches option series tickers, the underlying root, the series
+// letter (A to L calls, M to X puts, January to December), the strike code
+// and the weekly suffix (PETRA240, VALEM620W2).
+var optionCode = regexp.MustCompile(`^([A-Z0-9]{4})([A-X])([0-9]{1,4})(W[1-5])?$`)
+
+// strikeValue matches the strike printed on the security name ("24,00").
+var strikeValue = regexp.MustCompile(`^[0-9]+,[0-9]{2}$`)
+
+// IsOption guesses option series from the ticker, used only for tickers
+// missing from the ticker registry.
+func IsOption(code string) bool {
+	return optionCode.MatchString(code)
+}
+
+// ParseOptionCode splits an option ticker in underlying root, series letter,
+// strike code and week of the weekly series (zero for monthly ones), ok is
+// false for other tickers.
+func ParseOptionCode(code string) (root string, series byte, strikeCode string, week int, ok bool) {
+	match := optionCode.FindStringSubmatch(code)
+
+	if match == nil {
+		return "", 0, "", 0, false
+	}
+
+	if match[4] != "" {
+		week = int(match[4][1] - '0')
+	}
+
+	return match[1], match[2][0], match[3], week, true
+}
+
+// ParseStrike reads the strike from the last token of a security name
+// ("PETRA240 PN 24,00"), zero when the name carries none.
+func ParseStrike(name string) float64 {
+	tokens := strings.Fields(name)
+
+	for idx := len(tokens) - 1; idx >= 0; idx-- {
+		if strikeValue.MatchString(tokens[idx]) {
+			value, _ := strconv.ParseFloat(strings.Replace(tokens[idx], ",", ".", 1), 64)
+			return value
+		}
+	}
+
+	return 0
+}
+
+// B3 moved the option expiry from the third monday to the third friday of the
+// month in 2021
+var optionFridayExpiryStart = NewB3Date(2021, time.January, 1)
+
+// GetOptionExpiry returns the expiry of the series of the month, monthly
+// series (week zero) expire on the third monday before 2021 and on the third
+// friday from 2021, weekly series W1 to W5 on the same weekday of their week.
+// Holidays are left to the caller.
+func GetOptionExpiry(year int, month time.Month, week int) B3Date {
+	firstDay := NewB3Date(year, month, 1)
+	weekday := time.Friday
+
+	if firstDay.Before(optionFridayExpiryStart) {
+		weekday = time.Monday
+	}
+
+	if week == 0 {
+		week = 3
+	}
+
+	offset := (int(weekday) - int(firstDay.Weekday()) + 7) % 7
+
+	return NewB3Date(year, month, 1+offset+7*(week-1))
+}
+
+// GetClassSuffixes returns the ticker suffixes a share class trades with.
+func GetClassSuffixes(class string) []string {
+	return securityClasses[class]
+}
@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"strings"
+)
+
+// securityClasses maps the share class printed on SINACOR notes to the
+// ticker suffixes it may trade with.
+var securityClasses = map[string][]string{
+	"ON":  {"3"},
+	"PN":  {"4"},
+	"PNA": {"5"},
+	"PNB": {"6"},
+	"PNC": {"7"},
+	"PND": {"8"},
+	"UNT": {"11"},
+	"CI":  {"11"},
+	"DR1": {"31", "32", "33"},
+	"DR2": {"31", "32", "33"},
+	"DR3": {"31", "32", "33"},
+	"DRN": {"34", "35"},
+	"DRE": {"39"},
+}
+
+var accentReplacer = strings.NewReplacer(
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+	"É", "E", "Ê", "E", "È", "E",
+	"Í", "I", "Ì", "I",
+	"Ó", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ú", "U", "Ü", "U",
+	"Ç", "C",
+)
+
+// NormalizeSecurityName upper cases name, drops accents and punctuation and
+// collapses spaces, "Petrobras  PN N2" and "PETROBRAS PN N2" are equal.
+func NormalizeSecurityName(name string) string {
+	name = accentReplacer.Replace(strings.ToUpper(name))
+
+	fields := strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+
+	return strings.Join(fields, " ")
+}
+
+// ParseSecurityName splits a security specification ("PETROBRAS PN N2") in
+// issuer and share class, the governance tags after the class are dropped.
+// class is empty when the name carries none.
+func ParseSecurityName(name string) (issuer string, class string) {
+	tokens := strings.Fields(NormalizeSecurityName(name))
+
+	for idx, token := range tokens {
+		if _, ok := securityClasses[token]; ok && idx > 0 {
+			return strings.Join(tokens[:idx], " "), token
+		}
+	}
+
+	return strings.Join(tokens, " "), ""
+}
+
+// HasClassSuffix tells if code may belong to class, any code matches an
+// empty or unknown class.
+func HasClassSuffix(code string, class string) bool {
+	suffixes, ok := securityClasses[class]
+
+	if !ok {
+		return true
+	}
+
+	if len(code) <= 4 {
+		return false
+	}
+
+	for _, suffix := range suffixes {
+		if code[4:] == suffix {
+			return true
+		}
+	}
+
+	return false
+}
+
+// issuerStopWords are company type and linking words left out when comparing
+// issuer names.
+var issuerStopWords = map[string]bool{
+	"S":    true,
+	"A":    true,
+	"SA":   true,
+	"CIA":  true,
+	"COMP": true,
+	"DE":   true,
+	"DA":   true,
+	"DO":   true,
+	"DOS":  true,
+	"DAS":  true,
+	"E":    true,
+}
+
+func getIssuerTokens(issuer string) []string {
+	tokens := make([]string, 0)
+
+	for _, token := range strings.Fields(NormalizeSecurityName(issuer)) {
+		if !issuerStopWords[token] {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+// getEditDistance returns the Levenshtein distance between a and b.
+func getEditDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = prev[j-1] + cost
+
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+// isSameToken tells if two issuer tokens are equal, tokens of five letters or
+// more may differ by one edit (PETROBRAS, PETROBRAZ).
+func isSameToken(a string, b string) bool {
+	if a == b {
+		return true
+	}
+
+	return len(a) >= 5 && len(b) >= 5 && getEditDistance(a, b) <= 1
+}
+
+// GetIssuerSimilarity returns the share of the tokens of the shorter issuer
+// name found on the other one, from 0 to 1, company type words are ignored.
+// "PETROBRAS" and "PETROLEO BRASILEIRO S.A. PETROBRAS" score 1.
+func GetIssuerSimilarity(a string, b string) float64 {
+	tokensA := getIssuerTokens(a)
+	tokensB := getIssuerTokens(b)
+
+	if len(tokensA) == 0 || len(tokensB) == 0 {
+		return 0
+	}
+
+	if len(tokensA) > len(tokensB) {
+		tokensA, tokensB = tokensB, tokensA
+	}
+
+	matched := 0
+
+	for _, tokenA := range tokensA {
+		for _, tokenB := range tokensB {
+			if isSameToken(tokenA, tokenB) {
+				matched++
+				break
+			}
+		}
+	}
+
+	return float64(matched) / float64(len(tokensA))
+}
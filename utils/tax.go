@@ -3,27 +3,30 @@ package utils
 import (
 	"fmt"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/jarismar/b3c-invoice-reader-lambda/input"
 	"github.com/jarismar/b3c-service-entities/entity"
 )
 
+func GetTaxGroupIdFromDate(d B3Date, prefix string) (int64, error) {
+	res := prefix + d.Format("20060102")
+	resi, err := strconv.ParseInt(res, 10, 64)
+	return resi, err
+}
+
 func GetTaxGroupId(t string, prefix string) (int64, error) {
-	dt := strings.Split(t, "T")
-	ymd := strings.Split(dt[0], "-")
-	res := prefix
-	for _, ymdv := range ymd {
-		res = res + ymdv
+	d, err := ParseB3Date(t)
+
+	if err != nil {
+		return 0, err
 	}
-	resi, err := strconv.Atoi(res)
-	return int64(resi), err
+
+	return GetTaxGroupIdFromDate(d, prefix)
 }
 
 func GetTaxGroupIdFromTime(t time.Time, prefix string) (int64, error) {
-	ts := t.Format(time.RFC3339)
-	return GetTaxGroupId(ts, prefix)
+	return GetTaxGroupIdFromDate(B3DateOf(t), prefix)
 }
 
 func GetInputTaxByCode(taxes []input.Tax, taxCode string) (*input.Tax, error) {